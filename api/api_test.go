@@ -0,0 +1,127 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"pm5-emulator/clock"
+	"pm5-emulator/config"
+	"pm5-emulator/emulator"
+	"pm5-emulator/sim"
+	"pm5-emulator/transport"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServer(t *testing.T) (*Server, *emulator.Emulator, *clock.Manual) {
+	c := clock.NewManual(time.Date(2020, time.January, 1, 8, 0, 0, 0, time.UTC))
+	em, err := emulator.NewOfflineEmulator(emulator.Config{Clock: c, Seed: 1})
+	assert.NoError(t, err)
+	return NewServer(em), em, c
+}
+
+func do(s *Server, method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(method, PREFIX+path, strings.NewReader(body)))
+	return w
+}
+
+func TestServer_State(t *testing.T) {
+	s, em, c := newTestServer(t)
+	central := transport.NewLoopback().Connect("c0:ff:ee:00:00:01", 185)
+	em.CentralConnected(central)
+
+	w := do(s, http.MethodGet, "state", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var got State
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, State{
+		State:    config.PM5_STATE_READY,
+		Persona:  sim.DEFAULT_PERSONA,
+		Centrals: []emulator.Central{{ID: "c0:ff:ee:00:00:01", MTU: 185, Connected: c.Now()}},
+	}, got)
+
+	em.CentralDisconnected(central)
+	w = do(s, http.MethodGet, "centrals", "")
+	assert.JSONEq(t, "[]", w.Body.String())
+
+	w = do(s, http.MethodPost, "state", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, http.MethodGet, w.Header().Get("Allow"))
+}
+
+func TestServer_Control(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+		state  string
+	}{
+		{"Pause Not Rowing", "rowing/pause", "", http.StatusConflict, config.PM5_STATE_READY},
+		{"Start Just Row", "rowing/start", "", http.StatusOK, config.PM5_STATE_INUSE},
+		{"Pause", "rowing/pause", "", http.StatusOK, config.PM5_STATE_PAUSED},
+		{"Start Resumes", "rowing/start", "", http.StatusOK, config.PM5_STATE_INUSE},
+		{"Stop", "rowing/stop", "", http.StatusOK, config.PM5_STATE_FINISHED},
+		{"Unknown Action", "rowing/sprint", "", http.StatusBadRequest, config.PM5_STATE_FINISHED},
+		{"Command", "command", `{"command": "goidle"}`, http.StatusOK, config.PM5_STATE_IDLE},
+		{"Command Refused", "command", `{"command": "gofinished"}`, http.StatusConflict, config.PM5_STATE_IDLE},
+		{"Unknown Command", "command", `{"command": "launch"}`, http.StatusBadRequest, config.PM5_STATE_IDLE},
+		{"Start Programmed", "rowing/start", "", http.StatusOK, config.PM5_STATE_INUSE},
+		{"Menu Button", "button", `{"button": "menu"}`, http.StatusOK, config.PM5_STATE_READY},
+		{"Unknown Button", "button", `{"button": "power"}`, http.StatusBadRequest, config.PM5_STATE_READY},
+		{"Malformed Body", "command", `{"cmd": "goidle"}`, http.StatusBadRequest, config.PM5_STATE_READY},
+	}
+	s, em, _ := newTestServer(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := do(s, http.MethodPost, tt.path, tt.body)
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+			assert.Equal(t, tt.state, em.State())
+			if tt.status != http.StatusOK {
+				var e errorReply
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &e))
+				assert.NotEmpty(t, e.Error)
+			}
+		})
+	}
+}
+
+func TestServer_Target(t *testing.T) {
+	s, em, c := newTestServer(t)
+	em.Start()
+	do(s, http.MethodPost, "rowing/start", "")
+
+	w := do(s, http.MethodPost, "target", `{"stroke_rate": 30, "power": 300}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	c.Advance(10 * time.Second)
+	m := em.Metrics()
+	assert.Equal(t, 30, m.StrokeRate)
+	assert.Equal(t, 300, m.Power)
+
+	// the stroke rate is kept, the pace sets the power
+	w = do(s, http.MethodPost, "target", `{"pace": 120}`)
+	var got State
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, Target{StrokeRate: 30, Power: sim.PowerForPace(2 * time.Minute)}, got.Target)
+
+	w = do(s, http.MethodPost, "target", `{"pace": 120, "power": 200}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = do(s, http.MethodPost, "target", `{"stroke_rate": -1}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = do(s, http.MethodPost, "persona", `{"name": "elite"}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "elite", em.Persona())
+	w = do(s, http.MethodPost, "persona", `{"name": "cox"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = do(s, http.MethodGet, "metrics", "")
+	var metrics Metrics
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &metrics))
+	assert.Equal(t, newMetrics(em.Metrics()), metrics)
+}
@@ -0,0 +1,263 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"pm5-emulator/config"
+	"pm5-emulator/emulator"
+	"pm5-emulator/sim"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+//PREFIX is the path every endpoint of the API is served under
+const PREFIX = "/api/"
+
+//Actions of the rowing endpoint
+const (
+	ROWING_START  = "start"  // start a just row workout, or the programmed workout, or resume rowing
+	ROWING_PAUSE  = "pause"  // stop rowing, pausing the workout
+	ROWING_RESUME = "resume" // row again after pause
+	ROWING_STOP   = "stop"   // end the workout, as finished
+)
+
+//errBadRequest marks the errors of malformed requests
+var errBadRequest = errors.New("bad request")
+
+//badRequest returns an error for a malformed request
+func badRequest(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", errBadRequest, fmt.Sprintf(format, args...))
+}
+
+//handler serves an endpoint, returning the value to reply with. Errors are
+//replied with 400 when the request is malformed and 409 when the emulator
+//refuses it.
+type handler func(r *http.Request) (interface{}, error)
+
+//Server serves the HTTP API driving an emulator at runtime
+type Server struct {
+	em  *emulator.Emulator
+	mux *http.ServeMux
+}
+
+//NewServer returns the API of em
+func NewServer(em *emulator.Emulator) *Server {
+	s := &Server{em: em, mux: http.NewServeMux()}
+	s.handle("state", http.MethodGet, s.state)
+	s.handle("metrics", http.MethodGet, s.metrics)
+	s.handle("centrals", http.MethodGet, s.centrals)
+	s.handle("personas", http.MethodGet, s.personas)
+	s.handle("commands", http.MethodGet, s.commands)
+	s.handle("target", http.MethodPost, s.setTarget)
+	s.handle("persona", http.MethodPost, s.setPersona)
+	s.handle("rowing/", http.MethodPost, s.rowing)
+	s.handle("command", http.MethodPost, s.command)
+	s.handle("button", http.MethodPost, s.button)
+	s.mux.Handle(PREFIX+"events", em.Events())
+	return s
+}
+
+//Handle serves h for the pattern next to the API, such as the dashboard on /
+func (s *Server) Handle(pattern string, h http.Handler) {
+	s.mux.Handle(pattern, h)
+}
+
+//ServeHTTP serves a request to the API
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+//handle serves h at the path under PREFIX for the method
+func (s *Server) handle(path, method string, h handler) {
+	s.mux.HandleFunc(PREFIX+path, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			reply(w, http.StatusMethodNotAllowed, errorReply{fmt.Sprintf("%s is not allowed", r.Method)})
+			return
+		}
+		v, err := h(r)
+		switch {
+		case errors.Is(err, errBadRequest):
+			reply(w, http.StatusBadRequest, errorReply{err.Error()})
+		case err != nil:
+			reply(w, http.StatusConflict, errorReply{err.Error()})
+		default:
+			reply(w, http.StatusOK, v)
+		}
+	})
+}
+
+//errorReply is the body of the replies to failed requests
+type errorReply struct {
+	Error string `json:"error"`
+}
+
+//reply writes v as the JSON body of the reply
+func reply(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logrus.Error("[[API]] ", err)
+	}
+}
+
+//decode reads the JSON body of the request into v
+func decode(r *http.Request, v interface{}) error {
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	if err := d.Decode(v); err != nil {
+		return badRequest("%v", err)
+	}
+	return nil
+}
+
+func (s *Server) state(r *http.Request) (interface{}, error) {
+	return newState(s.em), nil
+}
+
+func (s *Server) metrics(r *http.Request) (interface{}, error) {
+	return newMetrics(s.em.Metrics()), nil
+}
+
+func (s *Server) centrals(r *http.Request) (interface{}, error) {
+	return s.em.Centrals(), nil
+}
+
+func (s *Server) personas(r *http.Request) (interface{}, error) {
+	return sim.Personas(), nil
+}
+
+func (s *Server) commands(r *http.Request) (interface{}, error) {
+	return emulator.Commands(), nil
+}
+
+//targetRequest changes what the athlete rows at, the values left out are
+//kept and 0 leaves them to the persona and the workout
+type targetRequest struct {
+	StrokeRate *int     `json:"stroke_rate"` // strokes per minute
+	Power      *int     `json:"power"`       // watts
+	Pace       *float64 `json:"pace"`        // seconds per 500m, sets the power
+}
+
+//setTarget sets the stroke rate and the power or pace the athlete rows at
+func (s *Server) setTarget(r *http.Request) (interface{}, error) {
+	var req targetRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	if req.Power != nil && req.Pace != nil {
+		return nil, badRequest("set either power or pace")
+	}
+	rate, power := s.em.Target()
+	if req.StrokeRate != nil {
+		rate = *req.StrokeRate
+	}
+	if req.Power != nil {
+		power = *req.Power
+	}
+	if req.Pace != nil {
+		power = sim.PowerForPace(time.Duration(*req.Pace * float64(time.Second)))
+	}
+	if rate < 0 || power < 0 {
+		return nil, badRequest("negative target")
+	}
+	if err := s.em.SetTarget(rate, power); err != nil {
+		return nil, err
+	}
+	return newState(s.em), nil
+}
+
+//personaRequest switches the persona the athlete rows as
+type personaRequest struct {
+	Name string `json:"name"`
+}
+
+func (s *Server) setPersona(r *http.Request) (interface{}, error) {
+	var req personaRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	if err := s.em.SetPersona(req.Name); err != nil {
+		return nil, badRequest("%v", err)
+	}
+	return newState(s.em), nil
+}
+
+//rowing starts, pauses, resumes or stops rowing, following the action at the end of the path
+func (s *Server) rowing(r *http.Request) (interface{}, error) {
+	var err error
+	switch action := r.URL.Path[len(PREFIX+"rowing/"):]; action {
+	case ROWING_START:
+		switch s.em.State() {
+		case config.PM5_STATE_READY:
+			err = s.em.PressButton(emulator.BUTTON_JUST_ROW)
+		case config.PM5_STATE_PAUSED:
+			err = s.em.ResumeRowing()
+		default:
+			err = s.send(config.CSAFE_GOINUSE_CMD, "start rowing")
+		}
+	case ROWING_PAUSE:
+		err = s.em.StopRowing()
+	case ROWING_RESUME:
+		err = s.em.ResumeRowing()
+	case ROWING_STOP:
+		err = s.send(config.CSAFE_GOFINISHED_CMD, "stop rowing")
+	default:
+		return nil, badRequest("unknown rowing action %q", action)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newState(s.em), nil
+}
+
+//send sends the state machine command cmd, explaining a refusal with what
+func (s *Server) send(cmd byte, what string) error {
+	state := s.em.State()
+	if err := s.em.Command(cmd); err != nil {
+		return fmt.Errorf("cannot %s in %s state: %v", what, state, err)
+	}
+	return nil
+}
+
+//commandRequest sends a state machine command
+type commandRequest struct {
+	Command string `json:"command"` // one of emulator.Commands
+}
+
+func (s *Server) command(r *http.Request) (interface{}, error) {
+	var req commandRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	cmd, err := emulator.LookupCommand(req.Command)
+	if err != nil {
+		return nil, badRequest("%v", err)
+	}
+	if err := s.send(cmd, req.Command); err != nil {
+		return nil, err
+	}
+	return newState(s.em), nil
+}
+
+//buttonRequest presses a button of the PM
+type buttonRequest struct {
+	Button string `json:"button"` // one of emulator.BUTTON_*
+}
+
+func (s *Server) button(r *http.Request) (interface{}, error) {
+	var req buttonRequest
+	if err := decode(r, &req); err != nil {
+		return nil, err
+	}
+	if req.Button != emulator.BUTTON_MENU && req.Button != emulator.BUTTON_JUST_ROW {
+		return nil, badRequest("unknown button %q", req.Button)
+	}
+	if err := s.em.PressButton(req.Button); err != nil {
+		return nil, err
+	}
+	return newState(s.em), nil
+}
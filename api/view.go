@@ -0,0 +1,85 @@
+package api
+
+import (
+	"pm5-emulator/emulator"
+	"pm5-emulator/workout"
+)
+
+//Metrics are the live values of the workout, durations in seconds
+type Metrics struct {
+	Elapsed      float64 `json:"elapsed"`
+	Distance     float64 `json:"distance"`    // meters
+	Speed        float64 `json:"speed"`       // meters per second
+	Pace         float64 `json:"pace"`        // seconds per 500m, 0 when not rowing
+	StrokeRate   int     `json:"stroke_rate"` // strokes per minute
+	StrokeCount  int     `json:"stroke_count"`
+	Power        int     `json:"power"`         // watts
+	Calories     int     `json:"calories"`      // kcal
+	HeartRate    int     `json:"heart_rate"`    // beats per minute
+	DriveTime    float64 `json:"drive_time"`    // of the last stroke
+	RecoveryTime float64 `json:"recovery_time"` // of the last stroke
+}
+
+//newMetrics returns the view of the metrics m
+func newMetrics(m workout.Metrics) Metrics {
+	return Metrics{
+		Elapsed:      m.ElapsedTime.Seconds(),
+		Distance:     m.Distance,
+		Speed:        m.Speed,
+		Pace:         m.Pace().Seconds(),
+		StrokeRate:   m.StrokeRate,
+		StrokeCount:  m.StrokeCount,
+		Power:        m.Power,
+		Calories:     m.Calories,
+		HeartRate:    m.HeartRate,
+		DriveTime:    m.DriveTime.Seconds(),
+		RecoveryTime: m.RecoveryTime.Seconds(),
+	}
+}
+
+//Goal is the goal of the programmed workout, 0 when not set
+type Goal struct {
+	Time     float64 `json:"time"`     // seconds
+	Distance float64 `json:"distance"` // meters
+	Calories int     `json:"calories"`
+	Power    int     `json:"power"` // watts
+}
+
+//Target is what the athlete is asked to row at, 0 when left to the persona and the workout
+type Target struct {
+	StrokeRate int `json:"stroke_rate"` // strokes per minute
+	Power      int `json:"power"`       // watts
+}
+
+//State is the state of the emulator
+type State struct {
+	State    string             `json:"state"` // slave state of the PM
+	Persona  string             `json:"persona"`
+	Target   Target             `json:"target"`
+	Goal     Goal               `json:"goal"`
+	Metrics  Metrics            `json:"metrics"`
+	Force    []float64          `json:"force_curve"` // of the last stroke, in pounds-force
+	Logged   int                `json:"logged"`      // workouts in the logbook
+	Centrals []emulator.Central `json:"centrals"`
+}
+
+//newState returns the view of the state of em
+func newState(em *emulator.Emulator) State {
+	rate, power := em.Target()
+	g := em.Goal()
+	return State{
+		State:   em.State(),
+		Persona: em.Persona(),
+		Target:  Target{StrokeRate: rate, Power: power},
+		Goal: Goal{
+			Time:     g.Time.Seconds(),
+			Distance: g.Distance,
+			Calories: g.Calories,
+			Power:    g.Power,
+		},
+		Metrics:  newMetrics(em.Metrics()),
+		Force:    em.ForceCurve(),
+		Logged:   em.Logged(),
+		Centrals: em.Centrals(),
+	}
+}
@@ -0,0 +1,271 @@
+package capture
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bettercap/gatt"
+)
+
+//btsnoop file format, with HCI UART (H4) packets
+const (
+	BTSNOOP_VERSION  = 1
+	BTSNOOP_LINK_H4  = 1002
+	BTSNOOP_EPOCH_US = 0x00dcddb30f2f8000 // microseconds from year 0 to 1970
+
+	BTSNOOP_FLAG_RECEIVED = 0x01 // sent by the controller, otherwise by the host
+	BTSNOOP_FLAG_EVENT    = 0x02 // command or event, otherwise data
+)
+
+//HCI and ATT values of the packets written
+const (
+	h4ACL   = 0x02
+	h4Event = 0x04
+
+	hciEventDisconnectionComplete = 0x05
+	hciEventLEMeta                = 0x3E
+	hciLEConnectionComplete       = 0x01
+	hciReasonRemoteUser           = 0x13
+	hciRolePeripheral             = 0x01
+	hciFirstConnHandle            = 0x0040
+
+	l2capCIDATT = 0x0004
+
+	attExchangeMTUReq = 0x02
+	attExchangeMTURsp = 0x03
+	attReadReq        = 0x0A
+	attReadRsp        = 0x0B
+	attWriteReq       = 0x12
+	attWriteRsp       = 0x13
+	attNotification   = 0x1B
+	attDefaultMTU     = 23
+)
+
+//handles are the ATT handles of a characteristic
+type handles struct {
+	value uint16
+	cccd  uint16 // 0 when the characteristic does not notify
+}
+
+//Btsnoop writes the GATT interactions of a session as a btsnoop trace of the
+//HCI packets they map to, that Wireshark opens with its Bluetooth dissectors
+type Btsnoop struct {
+	mu      sync.Mutex
+	w       *bufio.Writer
+	file    *os.File           // nil when not writing to a file
+	handles map[string]handles // by characteristic UUID
+	conns   map[string]uint16  // connection handles by central
+	next    uint16             // next connection handle
+}
+
+//NewBtsnoop writes the btsnoop header to w and returns a writer of the trace
+func NewBtsnoop(w io.Writer) (*Btsnoop, error) {
+	b := &Btsnoop{
+		w:       bufio.NewWriter(w),
+		handles: make(map[string]handles),
+		conns:   make(map[string]uint16),
+		next:    hciFirstConnHandle,
+	}
+	hdr := make([]byte, 16)
+	copy(hdr, "btsnoop\x00")
+	binary.BigEndian.PutUint32(hdr[8:], BTSNOOP_VERSION)
+	binary.BigEndian.PutUint32(hdr[12:], BTSNOOP_LINK_H4)
+	if _, err := b.w.Write(hdr); err != nil {
+		return nil, err
+	}
+	return b, b.w.Flush()
+}
+
+//CreateBtsnoop creates the btsnoop file at path and returns a writer of the trace
+func CreateBtsnoop(path string) (*Btsnoop, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	b, err := NewBtsnoop(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	b.file = f
+	return b, nil
+}
+
+//SetServices sets the services of the device, their characteristics get the
+//handles gatt assigns them when the services are added in this order
+func (b *Btsnoop) SetServices(services []*gatt.Service) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.handles = make(map[string]handles)
+	h := uint16(1) // ble attributes start at 1
+	for _, s := range services {
+		h++ // service declaration
+		for _, c := range s.Characteristics() {
+			hs := handles{value: h + 1}
+			h += 2 // characteristic declaration and value
+			for _, d := range c.Descriptors() {
+				if c.Descriptor() == d {
+					hs.cccd = h
+				}
+				h++
+			}
+			b.handles[c.UUID().String()] = hs
+		}
+	}
+}
+
+//Record writes the HCI packets of an entry to the trace
+func (b *Btsnoop) Record(e Entry) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if e.Event == EVENT_START {
+		return nil
+	}
+	if e.Event == EVENT_CONNECT {
+		return b.connect(e)
+	}
+	conn, ok := b.conns[e.Central]
+	if !ok {
+		return fmt.Errorf("btsnoop: central %s is not connected", e.Central)
+	}
+	if e.Event == EVENT_DISCONNECT {
+		delete(b.conns, e.Central)
+		evt := []byte{0x00, byte(conn), byte(conn >> 8), hciReasonRemoteUser}
+		return b.event(e.Time, hciEventDisconnectionComplete, evt)
+	}
+
+	hs, ok := b.handles[e.Char]
+	if !ok {
+		return fmt.Errorf("btsnoop: unknown characteristic %s", e.Char)
+	}
+	switch e.Event {
+	case EVENT_READ:
+		return b.att(e.Time, conn, true, []byte{attReadReq, byte(hs.value), byte(hs.value >> 8)},
+			append([]byte{attReadRsp}, e.Data...))
+	case EVENT_WRITE:
+		return b.att(e.Time, conn, true, append([]byte{attWriteReq, byte(hs.value), byte(hs.value >> 8)}, e.Data...),
+			[]byte{attWriteRsp})
+	case EVENT_SUBSCRIBE, EVENT_UNSUBSCRIBE:
+		value := byte(0x00)
+		if e.Event == EVENT_SUBSCRIBE {
+			value = 0x01 // notifications enabled
+		}
+		return b.att(e.Time, conn, true, []byte{attWriteReq, byte(hs.cccd), byte(hs.cccd >> 8), value, 0x00},
+			[]byte{attWriteRsp})
+	case EVENT_NOTIFY:
+		return b.att(e.Time, conn, false, append([]byte{attNotification, byte(hs.value), byte(hs.value >> 8)}, e.Data...))
+	}
+	return fmt.Errorf("btsnoop: unknown event %q", e.Event)
+}
+
+//Close flushes the trace and closes its file
+func (b *Btsnoop) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.w.Flush(); err != nil {
+		return err
+	}
+	if b.file != nil {
+		return b.file.Close()
+	}
+	return nil
+}
+
+//connect writes the connection of a central, and the MTU exchange when the
+//connection does not use the default MTU
+func (b *Btsnoop) connect(e Entry) error {
+	conn := b.next
+	b.next++
+	b.conns[e.Central] = conn
+
+	evt := []byte{hciLEConnectionComplete, 0x00, byte(conn), byte(conn >> 8), hciRolePeripheral, 0x00}
+	evt = append(evt, address(e.Central)...)
+	// 30ms interval, no latency, 720ms supervision timeout, master clock accuracy
+	evt = append(evt, 0x18, 0x00, 0x00, 0x00, 0x48, 0x00, 0x00)
+	if err := b.event(e.Time, hciEventLEMeta, evt); err != nil {
+		return err
+	}
+
+	if e.MTU == 0 || e.MTU == attDefaultMTU {
+		return nil
+	}
+	mtu := []byte{byte(e.MTU), byte(e.MTU >> 8)}
+	return b.att(e.Time, conn, true, append([]byte{attExchangeMTUReq}, mtu...), append([]byte{attExchangeMTURsp}, mtu...))
+}
+
+//att writes ATT PDUs exchanged on a connection, the first received from the
+//central when received is set, the next ones sent to it
+func (b *Btsnoop) att(t time.Time, conn uint16, received bool, pdus ...[]byte) error {
+	for i, pdu := range pdus {
+		rx := received && i == 0
+		l2cap := make([]byte, 4, 4+len(pdu))
+		binary.LittleEndian.PutUint16(l2cap, uint16(len(pdu)))
+		binary.LittleEndian.PutUint16(l2cap[2:], l2capCIDATT)
+		l2cap = append(l2cap, pdu...)
+
+		flags := uint16(0x0000) // first non-automatically-flushable packet, from the host
+		if rx {
+			flags = 0x2000 // first automatically flushable packet, from the controller
+		}
+		pkt := make([]byte, 5, 5+len(l2cap))
+		pkt[0] = h4ACL
+		binary.LittleEndian.PutUint16(pkt[1:], conn|flags)
+		binary.LittleEndian.PutUint16(pkt[3:], uint16(len(l2cap)))
+		pkt = append(pkt, l2cap...)
+
+		var dir uint32
+		if rx {
+			dir = BTSNOOP_FLAG_RECEIVED
+		}
+		if err := b.record(t, dir, pkt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//event writes an HCI event sent by the controller
+func (b *Btsnoop) event(t time.Time, code byte, params []byte) error {
+	pkt := append([]byte{h4Event, code, byte(len(params))}, params...)
+	return b.record(t, BTSNOOP_FLAG_RECEIVED|BTSNOOP_FLAG_EVENT, pkt)
+}
+
+//record writes a packet record to the trace
+func (b *Btsnoop) record(t time.Time, flags uint32, pkt []byte) error {
+	hdr := make([]byte, 24)
+	binary.BigEndian.PutUint32(hdr, uint32(len(pkt)))
+	binary.BigEndian.PutUint32(hdr[4:], uint32(len(pkt)))
+	binary.BigEndian.PutUint32(hdr[8:], flags)
+	binary.BigEndian.PutUint64(hdr[16:], uint64(t.UnixNano()/1000+BTSNOOP_EPOCH_US))
+	if _, err := b.w.Write(hdr); err != nil {
+		return err
+	}
+	if _, err := b.w.Write(pkt); err != nil {
+		return err
+	}
+	return b.w.Flush()
+}
+
+//address returns the Bluetooth address of a central identified by its
+//address, in the little endian order of HCI, zero when not an address
+func address(id string) []byte {
+	addr := make([]byte, 6)
+	b, err := hex.DecodeString(strings.Replace(id, ":", "", -1))
+	if err != nil || len(b) != 6 {
+		return addr
+	}
+	for i := range b {
+		addr[i] = b[5-i]
+	}
+	return addr
+}
@@ -0,0 +1,91 @@
+package capture
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/bettercap/gatt"
+	"github.com/stretchr/testify/assert"
+)
+
+type btsnoopRecord struct {
+	flags uint32
+	time  time.Time
+	pkt   []byte
+}
+
+func readBtsnoop(t *testing.T, b []byte) []btsnoopRecord {
+	assert.Equal(t, []byte("btsnoop\x00\x00\x00\x00\x01\x00\x00\x03\xea"), b[:16])
+	var records []btsnoopRecord
+	for b = b[16:]; len(b) > 0; {
+		l := binary.BigEndian.Uint32(b)
+		us := int64(binary.BigEndian.Uint64(b[16:])) - BTSNOOP_EPOCH_US
+		records = append(records, btsnoopRecord{
+			flags: binary.BigEndian.Uint32(b[8:]),
+			time:  time.Unix(0, us*1000).UTC(),
+			pkt:   b[24 : 24+l],
+		})
+		b = b[24+l:]
+	}
+	return records
+}
+
+func TestBtsnoop(t *testing.T) {
+	s1 := gatt.NewService(gatt.MustParseUUID("CE060010-43E5-11E4-916C-0800200C9A66"))
+	a := s1.AddCharacteristic(gatt.MustParseUUID("CE060011-43E5-11E4-916C-0800200C9A66"))
+	a.HandleReadFunc(func(rsp gatt.ResponseWriter, req *gatt.ReadRequest) {})
+	n := s1.AddCharacteristic(gatt.MustParseUUID("CE060031-43E5-11E4-916C-0800200C9A66"))
+	n.HandleNotifyFunc(func(r gatt.Request, n gatt.Notifier) {})
+	s2 := gatt.NewService(gatt.MustParseUUID("CE060020-43E5-11E4-916C-0800200C9A66"))
+	w := s2.AddCharacteristic(gatt.MustParseUUID("CE060021-43E5-11E4-916C-0800200C9A66"))
+	w.HandleWriteFunc(func(r gatt.Request, data []byte) byte { return gatt.StatusSuccess })
+
+	var buf bytes.Buffer
+	b, err := NewBtsnoop(&buf)
+	assert.NoError(t, err)
+	b.SetServices([]*gatt.Service{s1, s2})
+
+	at := time.Date(2020, time.May, 1, 12, 0, 0, 0, time.UTC)
+	central := "c0:ff:ee:00:00:01"
+	for _, e := range []Entry{
+		{Event: EVENT_CONNECT, Central: central, MTU: 185},
+		{Event: EVENT_READ, Central: central, Char: a.UUID().String(), Data: Bytes{0x50}},
+		{Event: EVENT_SUBSCRIBE, Central: central, Char: n.UUID().String()},
+		{Event: EVENT_NOTIFY, Central: central, Char: n.UUID().String(), Data: Bytes{0x01, 0x02}},
+		{Event: EVENT_WRITE, Central: central, Char: w.UUID().String(), Data: Bytes{0xF1}},
+		{Event: EVENT_UNSUBSCRIBE, Central: central, Char: n.UUID().String()},
+		{Event: EVENT_DISCONNECT, Central: central},
+	} {
+		e.Time = at
+		assert.NoError(t, b.Record(e))
+	}
+	assert.Error(t, b.Record(Entry{Event: EVENT_READ, Central: central, Char: a.UUID().String()}))
+	assert.NoError(t, b.Close())
+
+	// pb is the high byte of the connection handle, 0x20 for packets from the controller
+	acl := func(pb byte, pdu ...byte) []byte {
+		p := []byte{h4ACL, 0x40, pb, byte(len(pdu) + 4), 0x00, byte(len(pdu)), 0x00, 0x04, 0x00}
+		return append(p, pdu...)
+	}
+	rx, tx := uint32(BTSNOOP_FLAG_RECEIVED), uint32(0)
+	want := []btsnoopRecord{
+		{BTSNOOP_FLAG_RECEIVED | BTSNOOP_FLAG_EVENT, at, []byte{h4Event, 0x3E, 19, 0x01, 0x00, 0x40, 0x00, 0x01, 0x00,
+			0x01, 0x00, 0x00, 0xee, 0xff, 0xc0, 0x18, 0x00, 0x00, 0x00, 0x48, 0x00, 0x00}},
+		{rx, at, acl(0x20, 0x02, 185, 0x00)},
+		{tx, at, acl(0x00, 0x03, 185, 0x00)},
+		// value handles follow the service and characteristic declarations
+		{rx, at, acl(0x20, 0x0A, 0x03, 0x00)},
+		{tx, at, acl(0x00, 0x0B, 0x50)},
+		{rx, at, acl(0x20, 0x12, 0x06, 0x00, 0x01, 0x00)},
+		{tx, at, acl(0x00, 0x13)},
+		{tx, at, acl(0x00, 0x1B, 0x05, 0x00, 0x01, 0x02)},
+		{rx, at, acl(0x20, 0x12, 0x09, 0x00, 0xF1)},
+		{tx, at, acl(0x00, 0x13)},
+		{rx, at, acl(0x20, 0x12, 0x06, 0x00, 0x00, 0x00)},
+		{tx, at, acl(0x00, 0x13)},
+		{BTSNOOP_FLAG_RECEIVED | BTSNOOP_FLAG_EVENT, at, []byte{h4Event, 0x05, 4, 0x00, 0x40, 0x00, 0x13}},
+	}
+	assert.Equal(t, want, readBtsnoop(t, buf.Bytes()))
+}
@@ -0,0 +1,102 @@
+package capture
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+//Events of a capture entry
+const (
+	EVENT_START       = "start" // first entry, when the emulator started
+	EVENT_CONNECT     = "connect"
+	EVENT_DISCONNECT  = "disconnect"
+	EVENT_READ        = "read"
+	EVENT_WRITE       = "write"
+	EVENT_SUBSCRIBE   = "subscribe"
+	EVENT_UNSUBSCRIBE = "unsubscribe"
+	EVENT_NOTIFY      = "notify"
+)
+
+//Directions of the data of a capture entry
+const (
+	DIR_IN  = "in"  // from the central to the PM
+	DIR_OUT = "out" // from the PM to the central
+)
+
+//Entry is a single GATT interaction, written as one line of a capture
+type Entry struct {
+	Time      time.Time `json:"time"`
+	Event     string    `json:"event"`
+	Direction string    `json:"dir,omitempty"`
+	Central   string    `json:"central,omitempty"`
+	MTU       int       `json:"mtu,omitempty"` // of connections
+	Service   string    `json:"service,omitempty"`
+	Char      string    `json:"char,omitempty"`
+	Data      Bytes     `json:"data,omitempty"`
+	CSAFE     *Frame    `json:"csafe,omitempty"` // set on the entry completing a csafe frame
+
+	//Seed and Persona of the emulator, on the start entry
+	Seed    int64  `json:"seed,omitempty"`
+	Persona string `json:"persona,omitempty"`
+}
+
+//Frame is a csafe frame decoded from the data of one or more entries
+type Frame struct {
+	Status   *Byte     `json:"status,omitempty"` // of responses only
+	Commands []Command `json:"commands,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+//Command is a command, or the response to one, carried by a csafe frame
+type Command struct {
+	ID     Byte      `json:"id"`
+	Data   Bytes     `json:"data,omitempty"`
+	Nested []Command `json:"nested,omitempty"` // commands of a PM proprietary wrapper
+}
+
+//Bytes is raw data written as a hex string
+type Bytes []byte
+
+//MarshalJSON writes the bytes as a hex string
+func (b Bytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(hex.EncodeToString(b))
+}
+
+//UnmarshalJSON reads the bytes from a hex string
+func (b *Bytes) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	dec, err := hex.DecodeString(s)
+	if err != nil {
+		return fmt.Errorf("capture: invalid data %q", s)
+	}
+	*b = dec
+	return nil
+}
+
+//Byte is a single byte written as a 0x prefixed hex string
+type Byte byte
+
+//MarshalJSON writes the byte as a 0x prefixed hex string
+func (b Byte) MarshalJSON() ([]byte, error) {
+	return json.Marshal(fmt.Sprintf("0x%02x", byte(b)))
+}
+
+//UnmarshalJSON reads the byte from a 0x prefixed hex string
+func (b *Byte) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	v, err := strconv.ParseUint(s, 0, 8)
+	if err != nil {
+		return fmt.Errorf("capture: invalid byte %q", s)
+	}
+	*b = Byte(v)
+	return nil
+}
@@ -0,0 +1,89 @@
+package capture
+
+import (
+	"pm5-emulator/protocol/csafe"
+	"strings"
+
+	"github.com/bettercap/gatt"
+)
+
+//FrameDecoder reassembles the csafe frames written to the rx characteristic
+//and notified on the tx characteristic, by central, and decodes them
+type FrameDecoder struct {
+	rx, tx string            // characteristics carrying csafe requests and responses
+	frames map[string][]byte // csafe frames received in part, by central and characteristic
+	dec    csafe.Decoder
+}
+
+//NewFrameDecoder returns a decoder of the csafe frames written to rx and notified on tx
+func NewFrameDecoder(rx, tx gatt.UUID) *FrameDecoder {
+	return &FrameDecoder{rx: rx.String(), tx: tx.String(), frames: make(map[string][]byte)}
+}
+
+//Decode returns the csafe frame completed by e, nil when e completes none.
+//The frames received in part from a central are dropped when it disconnects.
+func (d *FrameDecoder) Decode(e Entry) *Frame {
+	switch {
+	case e.Event == EVENT_WRITE && e.Char == d.rx:
+		return d.reassemble(e, d.request)
+	case e.Event == EVENT_NOTIFY && e.Char == d.tx:
+		return d.reassemble(e, d.response)
+	case e.Event == EVENT_DISCONNECT:
+		for key := range d.frames {
+			if strings.HasPrefix(key, e.Central+"/") {
+				delete(d.frames, key)
+			}
+		}
+	}
+	return nil
+}
+
+//reassemble appends the data of e to the frame being received from its central
+//on its characteristic and decodes the frame once complete, nil until then
+func (d *FrameDecoder) reassemble(e Entry, decode func([]byte) *Frame) *Frame {
+	key := e.Central + "/" + e.Char
+	buf := append(d.frames[key], e.Data...)
+	if len(buf) == 0 {
+		return nil
+	}
+	if buf[0] == csafe.FRAME_START_BYTE && buf[len(buf)-1] != csafe.FRAME_END_BYTE {
+		d.frames[key] = buf
+		return nil
+	}
+	delete(d.frames, key)
+	return decode(buf)
+}
+
+//request decodes a csafe frame sent by a central
+func (d *FrameDecoder) request(raw []byte) *Frame {
+	cmds, err := d.dec.DecodeCommands(raw)
+	if err != nil {
+		return &Frame{Error: err.Error()}
+	}
+	return &Frame{Commands: commands(cmds, csafe.ParseCommands)}
+}
+
+//response decodes a csafe frame sent by the PM
+func (d *FrameDecoder) response(raw []byte) *Frame {
+	status, rsps, err := d.dec.DecodeResponse(raw)
+	if err != nil {
+		return &Frame{Error: err.Error()}
+	}
+	s := Byte(status)
+	return &Frame{Status: &s, Commands: commands(rsps, csafe.ParseResponses)}
+}
+
+//commands converts csafe commands, parsing the data of the wrappers with parse
+func commands(cmds []csafe.Command, parse func([]byte) ([]csafe.Command, error)) []Command {
+	var out []Command
+	for _, cmd := range cmds {
+		c := Command{ID: Byte(cmd.ID), Data: cmd.Data}
+		if cmd.IsWrapper() && parse != nil {
+			if nested, err := parse(cmd.Data); err == nil {
+				c.Nested = commands(nested, nil)
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
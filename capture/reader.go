@@ -0,0 +1,32 @@
+package capture
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+//Read reads the entries of a capture
+func Read(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	dec := json.NewDecoder(r)
+	for line := 1; dec.More(); line++ {
+		var e Entry
+		if err := dec.Decode(&e); err != nil {
+			return nil, fmt.Errorf("capture: entry %d: %v", line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+//Load reads the entries of the capture file at path
+func Load(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
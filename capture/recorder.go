@@ -0,0 +1,84 @@
+package capture
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"pm5-emulator/clock"
+	"sync"
+
+	"github.com/bettercap/gatt"
+)
+
+//Recorder writes the GATT interactions of a session to a JSON Lines capture,
+//one entry per line
+type Recorder struct {
+	mu     sync.Mutex
+	clock  clock.Clock
+	w      *bufio.Writer
+	enc    *json.Encoder
+	file   *os.File      // nil when not writing to a file
+	frames *FrameDecoder // nil when not decoding csafe frames
+}
+
+//NewRecorder returns a recorder writing to w, timestamping the entries with c
+func NewRecorder(w io.Writer, c clock.Clock) *Recorder {
+	bw := bufio.NewWriter(w)
+	return &Recorder{
+		clock: c,
+		w:     bw,
+		enc:   json.NewEncoder(bw),
+	}
+}
+
+//Create creates the capture file at path and returns a recorder writing to it
+func Create(path string, c clock.Clock) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	r := NewRecorder(f, c)
+	r.file = f
+	return r, nil
+}
+
+//DecodeCSAFE decodes the csafe frames written to rx and notified on tx
+func (r *Recorder) DecodeCSAFE(rx, tx gatt.UUID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.frames = NewFrameDecoder(rx, tx)
+}
+
+//Record writes e to the capture, along with the csafe frame it completes.
+//Entries without a time are timestamped with the clock of the recorder.
+func (r *Recorder) Record(e Entry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if e.Time.IsZero() {
+		e.Time = r.clock.Now()
+	}
+	if r.frames != nil {
+		e.CSAFE = r.frames.Decode(e)
+	}
+
+	if err := r.enc.Encode(e); err != nil {
+		return err
+	}
+	return r.w.Flush()
+}
+
+//Close flushes the capture and closes its file
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.w.Flush(); err != nil {
+		return err
+	}
+	if r.file != nil {
+		return r.file.Close()
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package capture
+
+import (
+	"bytes"
+	"pm5-emulator/clock"
+	"pm5-emulator/protocol/csafe"
+	"testing"
+	"time"
+
+	"github.com/bettercap/gatt"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	rx = gatt.MustParseUUID("CE060021-43E5-11E4-916C-0800200C9A66")
+	tx = gatt.MustParseUUID("CE060022-43E5-11E4-916C-0800200C9A66")
+)
+
+func readEntries(t *testing.T, b *bytes.Buffer) []Entry {
+	entries, err := Read(b)
+	assert.NoError(t, err)
+	return entries
+}
+
+func TestRecorder_Record(t *testing.T) {
+	start := time.Date(2020, time.May, 1, 12, 0, 0, 0, time.UTC)
+	c := clock.NewManual(start)
+	var b bytes.Buffer
+	r := NewRecorder(&b, c)
+	r.DecodeCSAFE(rx, tx)
+
+	enc := csafe.Encoder{}
+	// a wrapped request for the work time, written in two parts
+	req, _ := enc.Encode(csafe.Packet{Cmds: []byte{0x7F, 0x01, 0xA0}, JustCmd: true})
+	rsp, _ := enc.EncodeCommandResponses(0x81, []csafe.Command{{ID: 0x7F, Data: []byte{0xA0, 0x02, 0x10, 0x27}}})
+
+	assert.NoError(t, r.Record(Entry{Event: EVENT_CONNECT, Central: "c1"}))
+	c.Advance(time.Second)
+	assert.NoError(t, r.Record(Entry{Event: EVENT_WRITE, Direction: DIR_IN, Central: "c1", Char: rx.String(), Data: req[:3]}))
+	assert.NoError(t, r.Record(Entry{Event: EVENT_WRITE, Direction: DIR_IN, Central: "c1", Char: rx.String(), Data: req[3:]}))
+	assert.NoError(t, r.Record(Entry{Event: EVENT_NOTIFY, Direction: DIR_OUT, Central: "c1", Char: tx.String(), Data: rsp}))
+	// not a csafe characteristic
+	assert.NoError(t, r.Record(Entry{Event: EVENT_NOTIFY, Direction: DIR_OUT, Central: "c1", Char: "ce060031", Data: []byte{0xF1}}))
+	assert.NoError(t, r.Record(Entry{Event: EVENT_WRITE, Direction: DIR_IN, Central: "c1", Char: rx.String(), Data: []byte{0xF1, 0x80, 0x00, 0xF2}}))
+	assert.NoError(t, r.Close())
+
+	entries := readEntries(t, &b)
+	assert.Len(t, entries, 6)
+	assert.Equal(t, Entry{Time: start, Event: EVENT_CONNECT, Central: "c1"}, entries[0])
+	assert.Equal(t, start.Add(time.Second), entries[1].Time)
+	assert.Equal(t, Bytes(req[:3]), entries[1].Data)
+
+	// the frame is decoded once complete
+	assert.Nil(t, entries[1].CSAFE)
+	assert.Equal(t, &Frame{Commands: []Command{
+		{ID: 0x7F, Data: Bytes{0xA0}, Nested: []Command{{ID: 0xA0}}},
+	}}, entries[2].CSAFE)
+
+	status := Byte(0x81)
+	assert.Equal(t, &Frame{Status: &status, Commands: []Command{
+		{ID: 0x7F, Data: Bytes{0xA0, 0x02, 0x10, 0x27}, Nested: []Command{{ID: 0xA0, Data: Bytes{0x10, 0x27}}}},
+	}}, entries[3].CSAFE)
+
+	assert.Nil(t, entries[4].CSAFE)
+	assert.Equal(t, &Frame{Error: csafe.ErrChecksum.Error()}, entries[5].CSAFE)
+}
+
+func TestRecorder_Disconnect(t *testing.T) {
+	var b bytes.Buffer
+	r := NewRecorder(&b, clock.NewManual(time.Time{}))
+	r.DecodeCSAFE(rx, tx)
+
+	enc := csafe.Encoder{}
+	req, _ := enc.Encode(csafe.Packet{Cmds: []byte{0x80}, JustCmd: true})
+	assert.NoError(t, r.Record(Entry{Event: EVENT_WRITE, Central: "c1", Char: rx.String(), Data: req[:2]}))
+	assert.NoError(t, r.Record(Entry{Event: EVENT_DISCONNECT, Central: "c1"}))
+	// the partial frame of the previous connection is dropped
+	assert.NoError(t, r.Record(Entry{Event: EVENT_WRITE, Central: "c1", Char: rx.String(), Data: req}))
+
+	entries := readEntries(t, &b)
+	assert.Equal(t, &Frame{Commands: []Command{{ID: 0x80}}}, entries[2].CSAFE)
+}
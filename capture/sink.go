@@ -0,0 +1,20 @@
+package capture
+
+//Sink receives the entries of a session
+type Sink interface {
+	Record(e Entry) error
+}
+
+//Tee passes the entries to every sink in turn
+type Tee []Sink
+
+//Record passes e to every sink, returning the first error
+func (t Tee) Record(e Entry) error {
+	var first error
+	for _, s := range t {
+		if err := s.Record(e); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
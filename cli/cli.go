@@ -0,0 +1,192 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"pm5-emulator/emulator"
+	"strings"
+)
+
+//PROGRAM is the name of the emulator command
+const PROGRAM = "pm5-emulator"
+
+//Exit codes of the emulator
+const (
+	EXIT_OK      = 0 // the command succeeded
+	EXIT_FAILURE = 1 // the command failed, or a scenario or self test did not pass
+	EXIT_USAGE   = 2 // the command line or the settings are invalid
+	EXIT_ADAPTER = 3 // the Bluetooth adapter can not be opened, or is unsupported or unauthorized
+)
+
+//Names of the subcommands
+const (
+	COMMAND_SERVE    = "serve"
+	COMMAND_REPLAY   = "replay"
+	COMMAND_RECORD   = "record"
+	COMMAND_SCENARIO = "scenario"
+	COMMAND_SELFTEST = "selftest"
+	COMMAND_HELP     = "help"
+)
+
+//errFailed is returned by the commands that printed why they failed
+var errFailed = errors.New("failed")
+
+//errUsage is wrapped by the errors in the command line or the settings
+var errUsage = errors.New("invalid usage")
+
+//command is a subcommand of the emulator
+type command struct {
+	name     string
+	args     string // positional arguments, for the usage
+	summary  string
+	min, max int   // number of positional arguments, no maximum when max is -1
+	flags    group // groups of flags the command accepts
+	run      func(c *context, args []string) error
+}
+
+//commands are the subcommands of the emulator, serve runs when none is given
+var commands = []command{
+	{
+		name:    COMMAND_SERVE,
+		summary: "emulate the PM5 over Bluetooth, steered by the athlete simulation",
+		flags:   FLAGS_COMMON | FLAGS_SESSION | FLAGS_DEVICE | FLAGS_SERVE,
+		run:     serve,
+	},
+	{
+		name:    COMMAND_REPLAY,
+		args:    "FILE",
+		summary: "emulate the PM5 playing the workout recorded in FILE, a FIT, TCX or CSV file",
+		min:     1, max: 1,
+		flags: FLAGS_COMMON | FLAGS_SESSION | FLAGS_DEVICE | FLAGS_SERVE,
+		run:   replayFile,
+	},
+	{
+		name:    COMMAND_RECORD,
+		args:    "FILE",
+		summary: "emulate the PM5 recording every GATT interaction to FILE",
+		min:     1, max: 1,
+		flags: FLAGS_COMMON | FLAGS_SESSION | FLAGS_DEVICE | FLAGS_SERVE,
+		run:   record,
+	},
+	{
+		name:    COMMAND_SCENARIO,
+		args:    "FILE...",
+		summary: "run the scenarios of the JSON files against an offline emulator",
+		min:     1, max: -1,
+		flags: FLAGS_COMMON | FLAGS_SESSION,
+		run:   runScenarios,
+	},
+	{
+		name:    COMMAND_SELFTEST,
+		args:    "[CAPTURE...]",
+		summary: "check the emulator answers a central, then replay the captures against it",
+		max:     -1,
+		flags:   FLAGS_COMMON | FLAGS_SESSION | FLAGS_DEVICE,
+		run:     selftest,
+	},
+}
+
+//context is what a command runs with
+type context struct {
+	*Settings
+	stdout, stderr io.Writer
+}
+
+//Main runs the emulator command line args, without the program name, and
+//returns the exit code
+func Main(args []string, stdout, stderr io.Writer) int {
+	return run(args, os.LookupEnv, stdout, stderr)
+}
+
+//run runs the command line args, looking the environment variables up with lookup
+func run(args []string, lookup func(string) (string, bool), stdout, stderr io.Writer) int {
+	name := COMMAND_SERVE
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if name == COMMAND_HELP {
+		usage(stdout)
+		return EXIT_OK
+	}
+	cmd, ok := lookupCommand(name)
+	if !ok {
+		fmt.Fprintf(stderr, "%s: unknown command %q\n\n", PROGRAM, name)
+		usage(stderr)
+		return EXIT_USAGE
+	}
+
+	s := &Settings{}
+	fs := flag.NewFlagSet(PROGRAM+" "+cmd.name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: %s %s [flags] %s\n\n%s\n\nflags:\n", PROGRAM, cmd.name, cmd.args, cmd.summary)
+		fs.PrintDefaults()
+	}
+	s.register(fs, cmd.flags)
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return EXIT_OK
+		}
+		return EXIT_USAGE
+	}
+
+	err := resolve(fs, lookup)
+	if err == nil {
+		err = cmd.check(fs.Args())
+	}
+	if err == nil {
+		err = s.validate()
+	}
+	if err == nil {
+		err = s.setupLogging(stderr)
+	}
+	if err == nil {
+		err = cmd.run(&context{Settings: s, stdout: stdout, stderr: stderr}, fs.Args())
+	}
+	if err == nil {
+		return EXIT_OK
+	}
+	if errors.Is(err, errFailed) {
+		return EXIT_FAILURE
+	}
+	fmt.Fprintf(stderr, "%s %s: %v\n", PROGRAM, cmd.name, err)
+	switch {
+	case errors.Is(err, errUsage):
+		return EXIT_USAGE
+	case errors.Is(err, emulator.ErrAdapter):
+		return EXIT_ADAPTER
+	}
+	return EXIT_FAILURE
+}
+
+//check checks the number of positional arguments of the command
+func (cmd command) check(args []string) error {
+	if len(args) < cmd.min || (cmd.max >= 0 && len(args) > cmd.max) {
+		return fmt.Errorf("%w: expected arguments %q, got %d", errUsage, cmd.args, len(args))
+	}
+	return nil
+}
+
+//lookupCommand returns the subcommand called name
+func lookupCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+//usage prints the subcommands of the emulator to w
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "usage: %s [command] [flags] [args]\n\ncommands:\n", PROGRAM)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-9s %-13s %s\n", cmd.name, cmd.args, cmd.summary)
+	}
+	fmt.Fprintf(w, "\nserve runs when no command is given. Run %s COMMAND -h for the flags of a command.\n", PROGRAM)
+	fmt.Fprintf(w, "Flags can also be set by the %s environment variables, such as %s for -log-level,\n", ENV_PREFIX+"*", envName("log-level"))
+	fmt.Fprintf(w, "or in the JSON config file named by -config or %s, keyed by flag name.\n", envName(FLAG_CONFIG))
+}
@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolve(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cli")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "pm5.json")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{"seed": 7, "persona": "club", "serial": "123", "adv-interval": "100ms", "dashboard": true, "api": "localhost:0"}`), 0644))
+
+	tests := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		want    Settings
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			want: Settings{LogLevel: "info", LogFormat: LOG_TEXT},
+		},
+		{
+			name: "config file",
+			args: []string{"-config", path},
+			want: Settings{LogLevel: "info", LogFormat: LOG_TEXT, Seed: 7, Persona: "club", Serial: "123"},
+		},
+		{
+			name: "environment over config file",
+			args: []string{"-config", path},
+			env:  map[string]string{"PM5_SEED": "8", "PM5_LOG_LEVEL": "debug"},
+			want: Settings{LogLevel: "debug", LogFormat: LOG_TEXT, Seed: 8, Persona: "club", Serial: "123"},
+		},
+		{
+			name: "flags over environment",
+			args: []string{"-seed", "9", "-persona", "steady"},
+			env:  map[string]string{"PM5_CONFIG": path, "PM5_SEED": "8"},
+			want: Settings{LogLevel: "info", LogFormat: LOG_TEXT, Seed: 9, Persona: "steady", Serial: "123"},
+		},
+		{
+			name:    "invalid environment",
+			env:     map[string]string{"PM5_SEED": "seven"},
+			wantErr: true,
+		},
+		{
+			name:    "missing config file",
+			args:    []string{"-config", filepath.Join(dir, "missing.json")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Settings{}
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			s.register(fs, FLAGS_COMMON|FLAGS_SESSION|FLAGS_DEVICE)
+			assert.NoError(t, fs.Parse(tt.args))
+			err := resolve(fs, func(name string) (string, bool) {
+				v, ok := tt.env[name]
+				return v, ok
+			})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			s.Config = ""
+			assert.Equal(t, tt.want, *s)
+		})
+	}
+}
+
+func TestReadConfig_Unknown(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cli")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "pm5.json")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{"sead": 7}`), 0644))
+
+	_, err = readConfig(path)
+	assert.Error(t, err)
+}
+
+func TestMain_Usage(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"help", []string{"help"}, EXIT_OK},
+		{"unknown command", []string{"jump"}, EXIT_USAGE},
+		{"unknown flag", []string{"selftest", "-jump"}, EXIT_USAGE},
+		{"missing argument", []string{"scenario"}, EXIT_USAGE},
+		{"too many arguments", []string{"replay", "a.fit", "b.fit"}, EXIT_USAGE},
+		{"unknown persona", []string{"selftest", "-persona", "nobody"}, EXIT_USAGE},
+		{"unknown machine type", []string{"selftest", "-machine-type", "canoe"}, EXIT_USAGE},
+		{"dashboard without api", []string{"serve", "-dashboard"}, EXIT_USAGE},
+		{"missing scenario", []string{"scenario", "missing.json"}, EXIT_FAILURE},
+		{"capture not created", []string{"serve", "-transport", "none", "-logbook", "", "-capture", "missing/capture.jsonl"}, EXIT_FAILURE},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			assert.Equal(t, tt.want, run(tt.args, noEnv, &stdout, &stderr), stderr.String())
+		})
+	}
+}
+
+func TestMain_Scenario(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"scenario", "-log-level", "error", "../scenario/testdata/pause-resume.json"}, noEnv, &stdout, &stderr)
+	assert.Equal(t, EXIT_OK, code, stdout.String())
+	assert.Contains(t, stdout.String(), "PASSED")
+}
+
+func TestMain_Selftest(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	args := []string{"selftest", "-log-level", "error", "-serial", "123456789", "-machine-type", "bike", "../regression/testdata/session.jsonl"}
+	code := run(args, noEnv, &stdout, &stderr)
+	assert.Equal(t, EXIT_OK, code, stdout.String())
+	assert.Contains(t, stdout.String(), "PASS device information\n")
+	assert.Contains(t, stdout.String(), "PASS csafe status\n")
+	assert.Contains(t, stdout.String(), "PASS general status notifications\n")
+	assert.Contains(t, stdout.String(), "PASS ../regression/testdata/session.jsonl\n")
+}
+
+func noEnv(string) (string, bool) {
+	return "", false
+}
@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"fmt"
+	"pm5-emulator/scenario"
+)
+
+//runScenarios runs the scenario files args, the seed and the persona of the
+//settings override those of the scenarios when set
+func runScenarios(c *context, args []string) error {
+	passed := true
+	for _, path := range args {
+		s, err := scenario.Load(path)
+		if err != nil {
+			return err
+		}
+		if c.Seed != 0 {
+			s.Seed = c.Seed
+		}
+		if c.Persona != "" {
+			s.Persona = c.Persona
+		}
+		report := scenario.Run(s)
+		fmt.Fprint(c.stdout, report.String())
+		passed = passed && report.Passed()
+	}
+	if !passed {
+		return errFailed
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"pm5-emulator/capture"
+	"pm5-emulator/clock"
+	"pm5-emulator/config"
+	"pm5-emulator/emulator"
+	"pm5-emulator/protocol/csafe"
+	"pm5-emulator/random"
+	"pm5-emulator/regression"
+	"pm5-emulator/scenario"
+	"pm5-emulator/service"
+	"pm5-emulator/service/mux"
+	"pm5-emulator/transport"
+	"time"
+
+	"github.com/bettercap/gatt"
+)
+
+//SELFTEST_CENTRAL is the central the self test connects as
+const SELFTEST_CENTRAL = "5e:1f:7e:57:00:01"
+
+//SELFTEST_ROWING is how long the self test rows for
+const SELFTEST_ROWING = 5 * time.Second
+
+//tester runs the checks of the self test against an offline emulator
+type tester struct {
+	em      *emulator.Emulator
+	clock   *clock.Manual
+	central *transport.Central
+	device  config.Device
+}
+
+//checks are the checks of the self test, run in order
+var checks = []struct {
+	name string
+	run  func(t *tester) error
+}{
+	{"device information", (*tester).deviceInfo},
+	{"csafe status", (*tester).csafeStatus},
+	{"general status notifications", (*tester).generalStatus},
+}
+
+//selftest checks that an offline emulator answers a central as a PM5 does,
+//then replays the captures args against emulators and compares their answers
+//with the recorded ones
+func selftest(c *context, args []string) error {
+	profile, err := c.profile()
+	if err != nil {
+		return err
+	}
+	cfg := emulator.Config{
+		Clock:   clock.NewManual(scenario.START),
+		Seed:    c.Seed,
+		Persona: c.Persona,
+		Device:  c.device(),
+		Profile: profile,
+	}
+	if cfg.Seed == 0 {
+		cfg.Seed = random.NewSeed()
+	}
+	em, err := emulator.NewOfflineEmulator(cfg)
+	if err != nil {
+		return err
+	}
+	em.Start()
+	t := &tester{
+		em:      em,
+		clock:   cfg.Clock.(*clock.Manual),
+		central: transport.NewLoopback(em.Services()...).Connect(SELFTEST_CENTRAL, 0),
+		device:  cfg.Device,
+	}
+
+	passed := true
+	result := func(name string, err error) {
+		if err != nil {
+			passed = false
+			fmt.Fprintf(c.stdout, "FAIL %s: %v\n", name, err)
+			return
+		}
+		fmt.Fprintf(c.stdout, "PASS %s\n", name)
+	}
+	for _, check := range checks {
+		result(check.name, check.run(t))
+	}
+	for _, path := range args {
+		entries, err := capture.Load(path)
+		if err != nil {
+			result(path, err)
+			continue
+		}
+		report := regression.Run(entries, regression.Options{Seed: c.Seed, Persona: c.Persona})
+		fmt.Fprint(c.stdout, report.String())
+		if !report.Passed() {
+			result(path, errors.New("the emulator did not answer as recorded"))
+			continue
+		}
+		result(path, nil)
+	}
+	if !passed {
+		return errFailed
+	}
+	return nil
+}
+
+//deviceInfo reads the device information and checks it is the identity of the PM
+func (t *tester) deviceInfo() error {
+	for short, want := range map[string]string{
+		"0011": t.device.ModelNo,
+		"0012": t.device.SerialNo,
+		"0013": t.device.HardwareVersion,
+		"0014": t.device.FirmwareVersion,
+		"0015": t.device.Manufacturer,
+		"0016": t.device.MachineType,
+	} {
+		got, err := t.central.Read(uuid(short))
+		if err != nil {
+			return err
+		}
+		if string(got) != want {
+			return fmt.Errorf("characteristic %s reads %q, want %q", short, got, want)
+		}
+	}
+	return nil
+}
+
+//csafeStatus sends a csafe GETSTATUS command and checks the response reports
+//the state of the PM
+func (t *tester) csafeStatus() error {
+	rx, tx := service.ControlCharacteristics()
+	var responses [][]byte
+	if err := t.central.Subscribe(tx, func(data []byte) {
+		responses = append(responses, append([]byte(nil), data...))
+	}); err != nil {
+		return err
+	}
+	defer t.central.Unsubscribe(tx)
+
+	var enc csafe.Encoder
+	frame, err := enc.Encode(csafe.Packet{Cmds: []byte{config.CSAFE_GETSTATUS_CMD}, JustCmd: true})
+	if err != nil {
+		return err
+	}
+	if err := t.central.Write(rx, frame); err != nil {
+		return err
+	}
+
+	dec := capture.NewFrameDecoder(rx, tx)
+	var f *capture.Frame
+	for _, data := range responses {
+		f = dec.Decode(capture.Entry{Event: capture.EVENT_NOTIFY, Char: tx.String(), Data: data})
+	}
+	switch {
+	case f == nil:
+		return errors.New("no response")
+	case f.Error != "":
+		return fmt.Errorf("invalid response: %s", f.Error)
+	case f.Status == nil:
+		return errors.New("response without status")
+	}
+	if state := byte(*f.Status) & csafe.SLAVESTATE_MSK; state != csafe.SlaveState(t.em.State()) {
+		return fmt.Errorf("response reports state 0x%x in %s state", state, t.em.State())
+	}
+	return nil
+}
+
+//generalStatus rows and checks the general status notifications report the
+//elapsed time of the workout
+func (t *tester) generalStatus() error {
+	u := uuid("0031")
+	var elapsed []int
+	if err := t.central.Subscribe(u, func(data []byte) {
+		if p := mux.ParseNotification(0x31, data); p != nil {
+			elapsed = append(elapsed, p.Fields()["Elapsed_Time"])
+		}
+	}); err != nil {
+		return err
+	}
+	defer t.central.Unsubscribe(u)
+
+	if err := t.em.PressButton(emulator.BUTTON_JUST_ROW); err != nil {
+		return err
+	}
+	t.clock.Advance(SELFTEST_ROWING)
+	if state := t.em.State(); state != config.PM5_STATE_INUSE {
+		return fmt.Errorf("state is %s after just row", state)
+	}
+	if len(elapsed) < 2 {
+		return fmt.Errorf("%d notifications in %s", len(elapsed), SELFTEST_ROWING)
+	}
+	if first, last := elapsed[0], elapsed[len(elapsed)-1]; last <= first {
+		return fmt.Errorf("elapsed time went from %d to %d", first, last)
+	}
+	return nil
+}
+
+//uuid returns the UUID of the PM5 characteristic with the short identifier short
+func uuid(short string) gatt.UUID {
+	return gatt.MustParseUUID(config.UUID_PREFIX + short + config.UUID_SUFFIX)
+}
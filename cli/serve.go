@@ -0,0 +1,133 @@
+package cli
+
+import (
+	ctx "context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/signal"
+	"pm5-emulator/api"
+	"pm5-emulator/console"
+	"pm5-emulator/dashboard"
+	"pm5-emulator/emulator"
+	"pm5-emulator/replay"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+//SHUTDOWN_TIMEOUT is how long the control API is given to finish the
+//requests in flight when the emulator stops
+const SHUTDOWN_TIMEOUT = 2 * time.Second
+
+//serve emulates the PM5 until it is interrupted or the console is quit
+func serve(c *context, args []string) error {
+	cfg, err := c.emulatorConfig()
+	if err != nil {
+		return err
+	}
+	return c.serve(cfg)
+}
+
+//replayFile emulates the PM5 playing the recorded workout of the file args[0]
+func replayFile(c *context, args []string) error {
+	cfg, err := c.emulatorConfig()
+	if err != nil {
+		return err
+	}
+	cfg.Replay, err = replay.Load(args[0])
+	if err != nil {
+		return err
+	}
+	logrus.Infof("Replaying %s, %s long", args[0], cfg.Replay.Duration())
+	return c.serve(cfg)
+}
+
+//record emulates the PM5 recording its GATT interactions to the file args[0]
+func record(c *context, args []string) error {
+	c.Capture = args[0]
+	return serve(c, nil)
+}
+
+//serve runs an emulator with the settings cfg on the transport and the front
+//ends of the settings, until SIGINT or SIGTERM is received, the console is
+//quit or the adapter fails, then stops it
+func (c *context) serve(cfg emulator.Config) error {
+	var em *emulator.Emulator
+	var err error
+	if c.Transport == TRANSPORT_NONE {
+		if em, err = emulator.NewOfflineEmulator(cfg); err != nil {
+			return err
+		}
+		em.Start()
+	} else {
+		if em, err = emulator.NewEmulatorWithConfig(cfg); err != nil {
+			return err
+		}
+		if err := em.RunEmulator(); err != nil {
+			_ = em.Stop()
+			return err
+		}
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	done := make(chan error, 2) // the control API failing, the console quitting
+
+	var srv *http.Server
+	if c.API != "" {
+		s := api.NewServer(em)
+		if c.Dashboard {
+			s.Handle("/", dashboard.Handler())
+			logrus.Infof("Serving the dashboard on http://%s/", c.API)
+		}
+		srv = &http.Server{Addr: c.API, Handler: s}
+		logrus.Infof("Serving the control API on http://%s%s", c.API, api.PREFIX)
+		go func() {
+			if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+				done <- err
+			}
+		}()
+	}
+	var tui *console.Console
+	quit := make(chan struct{})
+	if c.Console {
+		tui = console.New(em, os.Stdin, c.stdout)
+		logrus.SetOutput(ioutil.Discard)
+		go func() {
+			defer close(quit)
+			err := tui.Run()
+			logrus.SetOutput(c.stderr)
+			done <- err
+		}()
+	}
+
+	select {
+	case sig := <-signals:
+		logrus.Infof("Received %s, shutting down", sig)
+	case err = <-done:
+	case err = <-em.Failed():
+	}
+
+	if tui != nil {
+		tui.Stop()
+		<-quit
+	}
+	if srv != nil {
+		timeout, cancel := ctx.WithTimeout(ctx.Background(), SHUTDOWN_TIMEOUT)
+		if srv.Shutdown(timeout) != nil {
+			// the event streams stay open until closed
+			_ = srv.Close()
+		}
+		cancel()
+	}
+	if stopErr := em.Stop(); err == nil {
+		err = stopErr
+	}
+	if err != nil {
+		logrus.Error("[[Emulator]] ", err)
+	}
+	return err
+}
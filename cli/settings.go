@@ -0,0 +1,311 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"pm5-emulator/clock"
+	"pm5-emulator/config"
+	"pm5-emulator/config/option"
+	"pm5-emulator/emulator"
+	"pm5-emulator/logbook"
+	"pm5-emulator/sim"
+	"pm5-emulator/user"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+//ENV_PREFIX prefixes the environment variables setting the flags, the flag
+//-log-level is set by PM5_LOG_LEVEL
+const ENV_PREFIX = "PM5_"
+
+//FLAG_CONFIG is the flag naming the config file
+const FLAG_CONFIG = "config"
+
+//Log formats
+const (
+	LOG_TEXT = "text"
+	LOG_JSON = "json"
+)
+
+//Transports the PM is emulated over
+const (
+	TRANSPORT_HCI  = "hci"  // a Bluetooth HCI device
+	TRANSPORT_NONE = "none" // none, the emulator is only driven by the control API or the console
+)
+
+//Bounds of the advertising interval allowed by Bluetooth
+const (
+	MIN_ADVERTISING_INTERVAL = 20 * time.Millisecond
+	MAX_ADVERTISING_INTERVAL = 10240 * time.Millisecond
+)
+
+//group is a set of groups of flags
+type group int
+
+//Groups of flags
+const (
+	FLAGS_COMMON  group = 1 << iota // config file and logs
+	FLAGS_SESSION                   // seed and persona of the simulation
+	FLAGS_DEVICE                    // identity of the PM and profile of the athlete
+	FLAGS_SERVE                     // transport, clock, files and front ends of a live emulator
+)
+
+//Settings are the settings of a command, from its flags, the environment
+//variables and the config file, in that order of precedence
+type Settings struct {
+	Config    string
+	LogLevel  string
+	LogFormat string
+
+	Seed    int64
+	Persona string
+
+	Profile     string
+	MachineType string
+	Serial      string
+
+	Transport           string
+	HCI                 int
+	AdvertisingInterval time.Duration
+	MaxCentrals         int
+	ClockSpeed          float64
+	Logbook             string
+	Capture             string
+	Btsnoop             string
+	API                 string
+	Dashboard           bool
+	Console             bool
+}
+
+//register defines the flags of the groups on fs
+func (s *Settings) register(fs *flag.FlagSet, groups group) {
+	if groups&FLAGS_COMMON != 0 {
+		fs.StringVar(&s.Config, FLAG_CONFIG, "", "JSON config file of the settings, keyed by flag name")
+		fs.StringVar(&s.LogLevel, "log-level", logrus.InfoLevel.String(), "level of the logs: trace, debug, info, warning, error, fatal or panic")
+		fs.StringVar(&s.LogFormat, "log-format", LOG_TEXT, "format of the logs: text or json")
+	}
+	if groups&FLAGS_SESSION != 0 {
+		fs.Int64Var(&s.Seed, "seed", 0, "seed of the random data, a random seed when 0")
+		fs.StringVar(&s.Persona, "persona", "", "persona the simulated athlete rows as: "+strings.Join(sim.Personas(), ", "))
+	}
+	if groups&FLAGS_DEVICE != 0 {
+		fs.StringVar(&s.Profile, "profile", "", "JSON file of the profile of the athlete, the default profile when empty")
+		fs.StringVar(&s.MachineType, "machine-type", "", "erg machine type the PM reports: "+strings.Join(machineTypes(), ", "))
+		fs.StringVar(&s.Serial, "serial", "", "serial number the PM reports and advertises its name with, "+config.SERIAL_NO+" when empty")
+	}
+	if groups&FLAGS_SERVE != 0 {
+		fs.StringVar(&s.Transport, "transport", TRANSPORT_HCI, "transport the PM is emulated over: hci, or none to only drive it with -api or -console")
+		fs.IntVar(&s.HCI, "hci", option.DEFAULT_HCI, "index of the HCI device, -1 for the first available")
+		fs.DurationVar(&s.AdvertisingInterval, "adv-interval", option.DEFAULT_ADVERTISING_INTERVAL, "interval the PM advertises at")
+		fs.IntVar(&s.MaxCentrals, "max-centrals", option.DEFAULT_MAX_CENTRALS, "number of centrals that can be connected at once, each with its own session")
+		fs.Float64Var(&s.ClockSpeed, "clock-speed", 1, "speed of the clock of the emulator, 2 runs the workouts twice as fast")
+		fs.StringVar(&s.Logbook, "logbook", logbook.DefaultPath(), "logbook file, the logbook is kept in memory when empty")
+		fs.StringVar(&s.Capture, "capture", "", "file every GATT interaction is recorded to, nothing is recorded when empty")
+		fs.StringVar(&s.Btsnoop, "btsnoop", "", "btsnoop file the GATT interactions are traced to for Wireshark, nothing is traced when empty")
+		fs.StringVar(&s.API, "api", "", "address to serve the HTTP control API on, such as localhost:8080, none when empty")
+		fs.BoolVar(&s.Dashboard, "dashboard", false, "serve the web dashboard of the emulated PM5 next to the control API")
+		fs.BoolVar(&s.Console, "console", false, "steer the emulator from an interactive terminal console, the logs are discarded while it runs")
+	}
+}
+
+//resolve sets the flags of fs not set on the command line from the
+//environment variables looked up with lookup, then from the config file
+func resolve(fs *flag.FlagSet, lookup func(string) (string, bool)) error {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	path := ""
+	if f := fs.Lookup(FLAG_CONFIG); f != nil {
+		path = f.Value.String()
+		if v, ok := lookup(envName(FLAG_CONFIG)); ok && !set[FLAG_CONFIG] {
+			path = v
+		}
+	}
+	file, err := readConfig(path)
+	if err != nil {
+		return err
+	}
+
+	var errs []string
+	fs.VisitAll(func(f *flag.Flag) {
+		if set[f.Name] || f.Name == FLAG_CONFIG {
+			return
+		}
+		if v, ok := lookup(envName(f.Name)); ok {
+			if err := fs.Set(f.Name, v); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", envName(f.Name), err))
+			}
+			return
+		}
+		if v, ok := file[f.Name]; ok {
+			if err := fs.Set(f.Name, v); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %s: %v", path, f.Name, err))
+			}
+		}
+	})
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %s", errUsage, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+//readConfig reads the settings of the JSON config file at path, by flag
+//name, none when path is empty. The settings of the flags of the other
+//commands are accepted, the unknown ones are not.
+func readConfig(path string) (map[string]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errUsage, err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var values map[string]interface{}
+	if err := dec.Decode(&values); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", errUsage, path, err)
+	}
+
+	known := flag.NewFlagSet("", flag.ContinueOnError)
+	(&Settings{}).register(known, FLAGS_COMMON|FLAGS_SESSION|FLAGS_DEVICE|FLAGS_SERVE)
+	settings := make(map[string]string, len(values))
+	for name, v := range values {
+		if known.Lookup(name) == nil || name == FLAG_CONFIG {
+			return nil, fmt.Errorf("%w: %s: unknown setting %q", errUsage, path, name)
+		}
+		switch v := v.(type) {
+		case string:
+			settings[name] = v
+		case json.Number:
+			settings[name] = v.String()
+		case bool:
+			settings[name] = fmt.Sprint(v)
+		default:
+			return nil, fmt.Errorf("%w: %s: setting %q is not a string, a number or a boolean", errUsage, path, name)
+		}
+	}
+	return settings, nil
+}
+
+//envName returns the environment variable setting the flag name
+func envName(name string) string {
+	return ENV_PREFIX + strings.ToUpper(strings.Replace(name, "-", "_", -1))
+}
+
+//machineTypes returns the names of the erg machine types, sorted
+func machineTypes() []string {
+	var names []string
+	for name := range config.MACHINE_TYPES {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//validate checks the settings
+func (s *Settings) validate() error {
+	if s.LogFormat != LOG_TEXT && s.LogFormat != LOG_JSON {
+		return fmt.Errorf("%w: unknown log format %q", errUsage, s.LogFormat)
+	}
+	if _, err := logrus.ParseLevel(s.LogLevel); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if s.Persona != "" {
+		if _, err := sim.LookupPersona(s.Persona); err != nil {
+			return fmt.Errorf("%w: %v", errUsage, err)
+		}
+	}
+	if _, ok := config.MACHINE_TYPES[s.MachineType]; s.MachineType != "" && !ok {
+		return fmt.Errorf("%w: unknown machine type %q", errUsage, s.MachineType)
+	}
+	if s.Transport != "" && s.Transport != TRANSPORT_HCI && s.Transport != TRANSPORT_NONE {
+		return fmt.Errorf("%w: unknown transport %q", errUsage, s.Transport)
+	}
+	if s.HCI < -1 {
+		return fmt.Errorf("%w: invalid HCI device %d", errUsage, s.HCI)
+	}
+	if s.MaxCentrals < 0 {
+		return fmt.Errorf("%w: invalid number of centrals %d", errUsage, s.MaxCentrals)
+	}
+	if s.AdvertisingInterval != 0 && (s.AdvertisingInterval < MIN_ADVERTISING_INTERVAL || s.AdvertisingInterval > MAX_ADVERTISING_INTERVAL) {
+		return fmt.Errorf("%w: advertising interval %s is not between %s and %s", errUsage,
+			s.AdvertisingInterval, MIN_ADVERTISING_INTERVAL, MAX_ADVERTISING_INTERVAL)
+	}
+	if s.ClockSpeed < 0 {
+		return fmt.Errorf("%w: invalid clock speed %g", errUsage, s.ClockSpeed)
+	}
+	if s.Dashboard && s.API == "" {
+		return fmt.Errorf("%w: -dashboard needs -api", errUsage)
+	}
+	return nil
+}
+
+//setupLogging sets the level and the format of the logs, written to w
+func (s *Settings) setupLogging(w io.Writer) error {
+	level, err := logrus.ParseLevel(s.LogLevel)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	logrus.SetLevel(level)
+	logrus.SetOutput(w)
+	if s.LogFormat == LOG_JSON {
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	}
+	return nil
+}
+
+//emulatorConfig returns the settings of the emulator session
+func (s *Settings) emulatorConfig() (emulator.Config, error) {
+	cfg := emulator.DefaultConfig()
+	if s.Seed != 0 {
+		cfg.Seed = s.Seed
+	}
+	cfg.Persona = s.Persona
+	if s.ClockSpeed != 0 && s.ClockSpeed != 1 {
+		cfg.Clock = clock.NewScaled(s.ClockSpeed)
+	}
+	cfg.Logbook = s.Logbook
+	cfg.Capture = s.Capture
+	cfg.Btsnoop = s.Btsnoop
+	cfg.Device = s.device()
+	cfg.HCI = s.HCI
+	cfg.AdvertisingInterval = s.AdvertisingInterval
+	cfg.MaxCentrals = s.MaxCentrals
+	p, err := s.profile()
+	cfg.Profile = p
+	return cfg, err
+}
+
+//profile returns the profile of the athlete, nil for the default profile
+func (s *Settings) profile() (*user.Profile, error) {
+	if s.Profile == "" {
+		return nil, nil
+	}
+	p, err := user.LoadProfile(s.Profile)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+//device returns the identity the PM reports
+func (s *Settings) device() config.Device {
+	dev := config.DefaultDevice()
+	if s.Serial != "" {
+		dev = config.DeviceWithSerial(s.Serial)
+	}
+	if s.MachineType != "" {
+		dev.MachineType = config.MACHINE_TYPES[s.MachineType]
+	}
+	return dev
+}
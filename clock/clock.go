@@ -0,0 +1,45 @@
+package clock
+
+import "time"
+
+//Source tells the current time to the parts of the emulator that follow time
+type Source interface {
+	Now() time.Time
+}
+
+//Timer is a function scheduled on a clock
+type Timer interface {
+	//Stop cancels the function, it returns false if it already ran or was stopped
+	Stop() bool
+}
+
+//Clock is the time the emulator runs on. The simulation, the state machine
+//timeouts and the notifications all follow the same clock, so that they
+//scale together when the clock runs faster than the host.
+type Clock interface {
+	Source
+
+	//Sleep pauses the calling goroutine for d
+	Sleep(d time.Duration)
+
+	//AfterFunc runs f once d has elapsed
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+//Real is the clock of the host
+type Real struct{}
+
+//Now returns the current time of the host
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+//Sleep pauses the calling goroutine for d
+func (Real) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+//AfterFunc runs f in its own goroutine once d has elapsed
+func (Real) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
@@ -0,0 +1,62 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+//every runs a function periodically on a clock
+type every struct {
+	mu      sync.Mutex
+	c       Clock
+	d       func() time.Duration
+	f       func()
+	t       Timer
+	stopped bool
+}
+
+//Every runs f every d of the clock c until the returned timer is stopped
+func Every(c Clock, d time.Duration, f func()) Timer {
+	return EveryFunc(c, func() time.Duration { return d }, f)
+}
+
+//EveryFunc runs f every d() of the clock c until the returned timer is
+//stopped, d is called before each run so the interval can change
+func EveryFunc(c Clock, d func() time.Duration, f func()) Timer {
+	e := &every{c: c, d: d, f: f}
+	e.mu.Lock()
+	e.t = c.AfterFunc(d(), e.fire)
+	e.mu.Unlock()
+	return e
+}
+
+//fire runs the function and schedules the next run
+func (e *every) fire() {
+	e.mu.Lock()
+	if e.stopped {
+		e.mu.Unlock()
+		return
+	}
+	e.mu.Unlock()
+
+	e.f()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.stopped {
+		e.t = e.c.AfterFunc(e.d(), e.fire)
+	}
+}
+
+//Stop cancels the next runs
+func (e *every) Stop() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.stopped {
+		return false
+	}
+	e.stopped = true
+	e.t.Stop()
+	return true
+}
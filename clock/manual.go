@@ -0,0 +1,112 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+//Manual is a clock that only moves when advanced, for tests and stepped
+//simulations. The functions scheduled on it run in the goroutine advancing
+//the clock, in the order of their deadlines, which makes runs deterministic.
+type Manual struct {
+	mu     sync.Mutex
+	now    time.Time
+	seq    int // creation order of the timers, breaking deadline ties
+	timers []*manualTimer
+}
+
+//manualTimer is a function scheduled on a manual clock
+type manualTimer struct {
+	c   *Manual
+	at  time.Time
+	seq int
+	f   func()
+}
+
+//NewManual returns a manual clock stopped at start
+func NewManual(start time.Time) *Manual {
+	return &Manual{now: start}
+}
+
+//Now returns the current time of the clock
+func (c *Manual) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+//Sleep pauses the calling goroutine until the clock is advanced by d
+func (c *Manual) Sleep(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	done := make(chan struct{})
+	c.AfterFunc(d, func() { close(done) })
+	<-done
+}
+
+//AfterFunc runs f when the clock is advanced by d
+func (c *Manual) AfterFunc(d time.Duration, f func()) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.seq++
+	t := &manualTimer{c: c, at: c.now.Add(d), seq: c.seq, f: f}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+//Advance moves the clock forward by d, running the functions that fall due
+//on the way at their deadline
+func (c *Manual) Advance(d time.Duration) {
+	c.mu.Lock()
+	target := c.now.Add(d)
+	c.mu.Unlock()
+
+	for {
+		c.mu.Lock()
+		t := c.next(target)
+		if t == nil {
+			c.now = target
+			c.mu.Unlock()
+			return
+		}
+		c.remove(t)
+		c.now = t.at
+		c.mu.Unlock()
+
+		t.f()
+	}
+}
+
+//next returns the first timer due by target, the lock must be held
+func (c *Manual) next(target time.Time) *manualTimer {
+	var next *manualTimer
+	for _, t := range c.timers {
+		if t.at.After(target) {
+			continue
+		}
+		if next == nil || t.at.Before(next.at) || (t.at.Equal(next.at) && t.seq < next.seq) {
+			next = t
+		}
+	}
+	return next
+}
+
+//remove removes a timer, it returns false if it was not scheduled, the lock must be held
+func (c *Manual) remove(t *manualTimer) bool {
+	for i, s := range c.timers {
+		if s == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+//Stop cancels the timer
+func (t *manualTimer) Stop() bool {
+	t.c.mu.Lock()
+	defer t.c.mu.Unlock()
+	return t.c.remove(t)
+}
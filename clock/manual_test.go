@@ -0,0 +1,86 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManual_Advance(t *testing.T) {
+	start := time.Date(2020, time.May, 1, 12, 0, 0, 0, time.UTC)
+	c := NewManual(start)
+
+	var fired []string
+	var at []time.Time
+	record := func(name string) func() {
+		return func() {
+			fired = append(fired, name)
+			at = append(at, c.Now())
+		}
+	}
+	c.AfterFunc(2*time.Second, record("b"))
+	c.AfterFunc(time.Second, record("a"))
+	c.AfterFunc(2*time.Second, record("c"))
+	stopped := c.AfterFunc(time.Second, record("stopped"))
+	assert.True(t, stopped.Stop())
+	assert.False(t, stopped.Stop())
+
+	c.Advance(1500 * time.Millisecond)
+	assert.Equal(t, []string{"a"}, fired)
+	assert.Equal(t, start.Add(1500*time.Millisecond), c.Now())
+
+	c.Advance(time.Second)
+	assert.Equal(t, []string{"a", "b", "c"}, fired)
+	assert.Equal(t, []time.Time{start.Add(time.Second), start.Add(2 * time.Second), start.Add(2 * time.Second)}, at)
+}
+
+func TestManual_Sleep(t *testing.T) {
+	c := NewManual(time.Time{})
+	done := make(chan time.Time)
+	go func() {
+		c.Sleep(time.Minute)
+		done <- c.Now()
+	}()
+
+	// wait for the goroutine to schedule its wake up
+	for {
+		c.mu.Lock()
+		n := len(c.timers)
+		c.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	c.Advance(time.Minute)
+	assert.Equal(t, time.Time{}.Add(time.Minute), <-done)
+}
+
+func TestEvery(t *testing.T) {
+	c := NewManual(time.Time{})
+	n := 0
+	e := Every(c, 100*time.Millisecond, func() { n++ })
+
+	c.Advance(time.Second)
+	assert.Equal(t, 10, n)
+
+	assert.True(t, e.Stop())
+	c.Advance(time.Second)
+	assert.Equal(t, 10, n)
+}
+
+func TestScaled(t *testing.T) {
+	c := NewScaled(100)
+	start := c.Now()
+
+	fired := make(chan time.Time)
+	c.AfterFunc(time.Second, func() { fired <- c.Now() })
+
+	select {
+	case now := <-fired:
+		assert.True(t, now.Sub(start) >= time.Second)
+	case <-time.After(time.Second):
+		t.Fatal("timer did not run faster than the host")
+	}
+}
@@ -0,0 +1,66 @@
+package clock
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+//PM is the date and time of the emulated PM. Once set it moves forward
+//with its source.
+type PM struct {
+	mu  sync.Mutex
+	src Source
+	set time.Time // PM time when last set
+	at  time.Time // source time when last set
+}
+
+//NewPM returns a PM clock following src, starting at start or at the time
+//of src when start is zero
+func NewPM(src Source, start time.Time) *PM {
+	c := &PM{src: src}
+	if start.IsZero() {
+		start = src.Now()
+	}
+	c.Set(start)
+	return c
+}
+
+//Now returns the current date and time of the PM
+func (c *PM) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.set.Add(c.src.Now().Sub(c.at))
+}
+
+//Set sets the date and time of the PM
+func (c *PM) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set = t
+	c.at = c.src.Now()
+}
+
+//SetTime sets the time of the PM keeping its date
+func (c *PM) SetTime(hour, min, sec int) error {
+	if hour > 23 || min > 59 || sec > 59 || hour < 0 || min < 0 || sec < 0 {
+		return fmt.Errorf("invalid time %02d:%02d:%02d", hour, min, sec)
+	}
+
+	now := c.Now()
+	c.Set(time.Date(now.Year(), now.Month(), now.Day(), hour, min, sec, 0, now.Location()))
+	return nil
+}
+
+//SetDate sets the date of the PM keeping its time
+func (c *PM) SetDate(year int, month time.Month, day int) error {
+	now := c.Now()
+	t := time.Date(year, month, day, now.Hour(), now.Minute(), now.Second(), now.Nanosecond(), now.Location())
+	// time.Date normalizes out of range values, e.g. February 30th
+	if t.Year() != year || t.Month() != month || t.Day() != day {
+		return fmt.Errorf("invalid date %04d-%02d-%02d", year, month, day)
+	}
+
+	c.Set(t)
+	return nil
+}
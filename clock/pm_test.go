@@ -0,0 +1,52 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//stepSource is a source that only moves when told to
+type stepSource struct {
+	now time.Time
+}
+
+func (s *stepSource) Now() time.Time {
+	return s.now
+}
+
+func TestPM_Now(t *testing.T) {
+	src := &stepSource{now: time.Date(2021, time.March, 14, 9, 26, 53, 0, time.UTC)}
+
+	// starts from the source time
+	c := NewPM(src, time.Time{})
+	assert.Equal(t, src.now, c.Now())
+
+	// moves forward with the source once set
+	c.Set(time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC))
+	src.now = src.now.Add(90 * time.Second)
+	assert.Equal(t, time.Date(2020, time.January, 1, 12, 1, 30, 0, time.UTC), c.Now())
+
+	// starts from the configured time
+	c = NewPM(src, time.Date(1999, time.December, 31, 23, 59, 59, 0, time.UTC))
+	src.now = src.now.Add(time.Second)
+	assert.Equal(t, time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC), c.Now())
+}
+
+func TestPM_SetTimeAndDate(t *testing.T) {
+	src := &stepSource{now: time.Date(2021, time.March, 14, 9, 26, 53, 0, time.UTC)}
+	c := NewPM(src, time.Time{})
+
+	assert.NoError(t, c.SetTime(18, 5, 0))
+	assert.Equal(t, time.Date(2021, time.March, 14, 18, 5, 0, 0, time.UTC), c.Now())
+
+	assert.NoError(t, c.SetDate(2022, time.February, 28))
+	assert.Equal(t, time.Date(2022, time.February, 28, 18, 5, 0, 0, time.UTC), c.Now())
+
+	assert.Error(t, c.SetTime(24, 0, 0))
+	assert.Error(t, c.SetTime(12, 60, 0))
+	assert.Error(t, c.SetDate(2022, time.February, 29))
+	assert.Error(t, c.SetDate(2022, 13, 1))
+	assert.Equal(t, time.Date(2022, time.February, 28, 18, 5, 0, 0, time.UTC), c.Now())
+}
@@ -0,0 +1,38 @@
+package clock
+
+import "time"
+
+//Scaled is a clock running factor times faster than the host
+type Scaled struct {
+	factor float64
+	start  time.Time // host time the clock started at
+}
+
+//NewScaled returns a clock starting at the host time and running factor
+//times faster
+func NewScaled(factor float64) *Scaled {
+	if factor <= 0 {
+		factor = 1
+	}
+	return &Scaled{factor: factor, start: time.Now()}
+}
+
+//Now returns the current time of the clock
+func (c *Scaled) Now() time.Time {
+	return c.start.Add(time.Duration(float64(time.Since(c.start)) * c.factor))
+}
+
+//Sleep pauses the calling goroutine for d of the clock
+func (c *Scaled) Sleep(d time.Duration) {
+	time.Sleep(c.host(d))
+}
+
+//AfterFunc runs f in its own goroutine once d of the clock has elapsed
+func (c *Scaled) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(c.host(d), f)
+}
+
+//host returns the host duration of d
+func (c *Scaled) host(d time.Duration) time.Duration {
+	return time.Duration(float64(d) / c.factor)
+}
@@ -1,12 +1,11 @@
 package main
 
 import (
-	"pm5-emulator/emulator"
+	"os"
+	"pm5-emulator/cli"
 	_ "pm5-emulator/log"
 )
 
 func main() {
-	em := emulator.NewEmulator() //factory method
-	em.RunEmulator()
-	select {}
+	os.Exit(cli.Main(os.Args[1:], os.Stdout, os.Stderr))
 }
@@ -0,0 +1,82 @@
+package command
+
+import (
+	"fmt"
+	"pm5-emulator/protocol"
+	"pm5-emulator/protocol/csafe"
+	"time"
+)
+
+//Meridiem of the hour sent with PM_SET_DATETIME
+const (
+	MERIDIEM_AM = 0
+	MERIDIEM_PM = 1
+)
+
+//registerClockCommands registers the standard csafe and PM proprietary
+//commands setting and querying the date and time of the PM
+func (h *Handler) registerClockCommands() {
+	h.HandleFunc(byte(csafe.SETTIME_CMD), h.setTime)
+	h.HandleFunc(byte(csafe.SETDATE_CMD), h.setDate)
+	h.HandleWrappedFunc(byte(csafe.SETPMCFG_CMD), byte(csafe.PM_SET_DATETIME), h.pmSetDateTime)
+	h.HandleWrappedFunc(byte(csafe.GETPMCFG_CMD), byte(csafe.PM_GET_DATETIME), h.pmGetDateTime)
+}
+
+//setTime sets the time of the PM from hours, minutes and seconds
+func (h *Handler) setTime(req protocol.Request) ([]byte, error) {
+	if err := checkLen(req, csafe.HMS_FORMAT_CNT); err != nil {
+		return nil, err
+	}
+	return nil, h.clock.SetTime(int(req.Data[0]), int(req.Data[1]), int(req.Data[2]))
+}
+
+//setDate sets the date of the PM from the year since BASE_YEAR, the month and the day
+func (h *Handler) setDate(req protocol.Request) ([]byte, error) {
+	if err := checkLen(req, csafe.YMD_FORMAT_CNT); err != nil {
+		return nil, err
+	}
+	year := csafe.BASE_YEAR + int(req.Data[0])
+	return nil, h.clock.SetDate(year, time.Month(req.Data[1]), int(req.Data[2]))
+}
+
+//pmSetDateTime sets the date and time of the PM from the hour (1-12), the
+//minutes, the meridiem, the month, the day and the year, MSB first
+func (h *Handler) pmSetDateTime(req protocol.Request) ([]byte, error) {
+	if err := checkLen(req, 7); err != nil {
+		return nil, err
+	}
+	hour, min, meridiem := int(req.Data[0]), int(req.Data[1]), req.Data[2]
+	month, day := time.Month(req.Data[3]), int(req.Data[4])
+	year := int(req.Data[5])<<8 | int(req.Data[6])
+
+	if hour < 1 || hour > 12 || meridiem > MERIDIEM_PM {
+		return nil, fmt.Errorf("invalid time %d:%02d meridiem %d", hour, min, meridiem)
+	}
+	hour %= 12
+	if meridiem == MERIDIEM_PM {
+		hour += 12
+	}
+
+	// check the whole date and time before setting any of them
+	t := time.Date(year, month, day, hour, min, 0, 0, time.Local)
+	if t.Year() != year || t.Month() != month || t.Day() != day || t.Minute() != min {
+		return nil, fmt.Errorf("invalid date and time %04d-%02d-%02d %02d:%02d", year, month, day, hour, min)
+	}
+	h.clock.Set(t)
+	return nil, nil
+}
+
+//pmGetDateTime answers the date and time of the PM as set by PM_SET_DATETIME:
+//the hour (1-12), the minutes, the meridiem, the month, the day and the
+//year, MSB first
+func (h *Handler) pmGetDateTime(req protocol.Request) ([]byte, error) {
+	t := h.clock.Now()
+	hour, meridiem := t.Hour()%12, byte(MERIDIEM_AM)
+	if t.Hour() >= 12 {
+		meridiem = MERIDIEM_PM
+	}
+	if hour == 0 {
+		hour = 12
+	}
+	return []byte{byte(hour), byte(t.Minute()), meridiem, byte(t.Month()), byte(t.Day()), byte(t.Year() >> 8), byte(t.Year())}, nil
+}
@@ -0,0 +1,92 @@
+package command
+
+import (
+	"pm5-emulator/clock"
+	"pm5-emulator/logbook"
+	"pm5-emulator/protocol"
+	"pm5-emulator/protocol/csafe"
+	"pm5-emulator/sm"
+	"pm5-emulator/user"
+	"pm5-emulator/workout"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_ClockCommands(t *testing.T) {
+	h := newTestHandler()
+
+	_, err := h.Handle(protocol.Request{Command: byte(csafe.SETDATE_CMD), Data: []byte{121, 3, 14}})
+	assert.NoError(t, err)
+	_, err = h.Handle(protocol.Request{Command: byte(csafe.SETTIME_CMD), Data: []byte{9, 26, 53}})
+	assert.NoError(t, err)
+
+	now := h.clock.Now()
+	assert.Equal(t, time.Date(2021, time.March, 14, 9, 26, 53, 0, now.Location()), now.Truncate(time.Second))
+
+	tests := []struct {
+		name string
+		data []byte
+		want time.Time
+	}{
+		{"Morning", []byte{9, 30, MERIDIEM_AM, 6, 1, 0x07, 0xE6}, time.Date(2022, time.June, 1, 9, 30, 0, 0, time.Local)},
+		{"Noon", []byte{12, 0, MERIDIEM_PM, 6, 1, 0x07, 0xE6}, time.Date(2022, time.June, 1, 12, 0, 0, 0, time.Local)},
+		{"Midnight", []byte{12, 15, MERIDIEM_AM, 6, 1, 0x07, 0xE6}, time.Date(2022, time.June, 1, 0, 15, 0, 0, time.Local)},
+		{"Evening", []byte{7, 45, MERIDIEM_PM, 12, 31, 0x07, 0xE6}, time.Date(2022, time.December, 31, 19, 45, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := h.Handle(protocol.Request{Wrapper: byte(csafe.SETPMCFG_CMD), Command: byte(csafe.PM_SET_DATETIME), Data: tt.data})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, h.clock.Now().Truncate(time.Minute))
+		})
+	}
+}
+
+func TestHandler_GetDateTime(t *testing.T) {
+	stm := sm.NewStateMachine()
+	stm.Reset()
+	c := clock.NewManual(time.Time{})
+	h := NewHandler(stm, workout.New(), user.New(stm), logbook.New(), clock.NewPM(c, time.Time{}))
+	get := func() []byte {
+		data, err := h.Handle(protocol.Request{Wrapper: byte(csafe.GETPMCFG_CMD), Command: byte(csafe.PM_GET_DATETIME)})
+		assert.NoError(t, err)
+		return data
+	}
+
+	_, err := h.Handle(protocol.Request{Wrapper: byte(csafe.SETPMCFG_CMD), Command: byte(csafe.PM_SET_DATETIME), Data: []byte{11, 50, MERIDIEM_AM, 12, 31, 0x07, 0xE6}})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{11, 50, MERIDIEM_AM, 12, 31, 0x07, 0xE6}, get())
+
+	// the PM clock moves forward with the clock it runs on
+	c.Advance(15 * time.Minute)
+	assert.Equal(t, []byte{12, 5, MERIDIEM_PM, 12, 31, 0x07, 0xE6}, get())
+	c.Advance(12 * time.Hour)
+	assert.Equal(t, []byte{12, 5, MERIDIEM_AM, 1, 1, 0x07, 0xE7}, get())
+}
+
+func TestHandler_ClockCommandsRejected(t *testing.T) {
+	tests := []struct {
+		name string
+		req  protocol.Request
+	}{
+		{"Invalid Time", protocol.Request{Command: byte(csafe.SETTIME_CMD), Data: []byte{24, 0, 0}}},
+		{"Short Time", protocol.Request{Command: byte(csafe.SETTIME_CMD), Data: []byte{12, 0}}},
+		{"Invalid Date", protocol.Request{Command: byte(csafe.SETDATE_CMD), Data: []byte{121, 2, 29}}},
+		{"Invalid Hour", protocol.Request{Wrapper: byte(csafe.SETPMCFG_CMD), Command: byte(csafe.PM_SET_DATETIME), Data: []byte{13, 0, MERIDIEM_AM, 1, 1, 0x07, 0xE6}}},
+		{"Invalid Meridiem", protocol.Request{Wrapper: byte(csafe.SETPMCFG_CMD), Command: byte(csafe.PM_SET_DATETIME), Data: []byte{1, 0, 2, 1, 1, 0x07, 0xE6}}},
+		{"Invalid Minutes", protocol.Request{Wrapper: byte(csafe.SETPMCFG_CMD), Command: byte(csafe.PM_SET_DATETIME), Data: []byte{1, 60, MERIDIEM_AM, 1, 1, 0x07, 0xE6}}},
+		{"Invalid Day", protocol.Request{Wrapper: byte(csafe.SETPMCFG_CMD), Command: byte(csafe.PM_SET_DATETIME), Data: []byte{1, 0, MERIDIEM_AM, 4, 31, 0x07, 0xE6}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler()
+			before := h.clock.Now()
+
+			_, err := h.Handle(tt.req)
+			assert.Error(t, err)
+			assert.WithinDuration(t, before, h.clock.Now(), time.Second)
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package command
+
+import (
+	"math"
+	"pm5-emulator/protocol"
+	"pm5-emulator/protocol/csafe"
+	"time"
+)
+
+//registerDataCommands registers the standard csafe data query commands,
+//answered from the live values of the workout
+func (h *Handler) registerDataCommands() {
+	h.HandleFunc(byte(csafe.GETTWORK_CMD), h.getTWork)
+	h.HandleFunc(byte(csafe.GETHORIZONTAL_CMD), h.getHorizontal)
+	h.HandleFunc(byte(csafe.GETCALORIES_CMD), h.getCalories)
+	h.HandleFunc(byte(csafe.GETSPEED_CMD), h.getSpeed)
+	h.HandleFunc(byte(csafe.GETPACE_CMD), h.getPace)
+	h.HandleFunc(byte(csafe.GETCADENCE_CMD), h.getCadence)
+	h.HandleFunc(byte(csafe.GETHRCUR_CMD), h.getHRCur)
+	h.HandleFunc(byte(csafe.GETPOWER_CMD), h.getPower)
+}
+
+//getTWork answers the elapsed workout time as hours, minutes and seconds
+func (h *Handler) getTWork(req protocol.Request) ([]byte, error) {
+	return hms(h.workout.Metrics().ElapsedTime), nil
+}
+
+//getHorizontal answers the distance rowed in meters
+func (h *Handler) getHorizontal(req protocol.Request) ([]byte, error) {
+	m := h.workout.Metrics()
+	return withUnits(m.Distance, csafe.DISTANCE_METER_0_0), nil
+}
+
+//getCalories answers the total calories burned
+func (h *Handler) getCalories(req protocol.Request) ([]byte, error) {
+	return uint16LE(float64(h.workout.Metrics().Calories)), nil
+}
+
+//getSpeed answers the current speed in tenths of km/h
+func (h *Handler) getSpeed(req protocol.Request) ([]byte, error) {
+	kmh := h.workout.Metrics().Speed * 3.6
+	return withUnits(kmh*10, csafe.SPEED_KMPERHOUR_0_1), nil
+}
+
+//getPace answers the current pace in seconds per km
+func (h *Handler) getPace(req protocol.Request) ([]byte, error) {
+	pace := h.workout.Metrics().Pace() * 2 // per 500m to per km
+	return withUnits(pace.Seconds(), csafe.PACE_SECONDSPERKM_0_0), nil
+}
+
+//getCadence answers the current stroke rate
+func (h *Handler) getCadence(req protocol.Request) ([]byte, error) {
+	m := h.workout.Metrics()
+	return withUnits(float64(m.StrokeRate), csafe.CADENCE_STROKESPERMINUTE_0_0), nil
+}
+
+//getHRCur answers the current heart rate in beats per minute
+func (h *Handler) getHRCur(req protocol.Request) ([]byte, error) {
+	return []byte{clampByte(h.workout.Metrics().HeartRate)}, nil
+}
+
+//getPower answers the current power in watts
+func (h *Handler) getPower(req protocol.Request) ([]byte, error) {
+	m := h.workout.Metrics()
+	return withUnits(float64(m.Power), csafe.POWER_WATTS_0_0), nil
+}
+
+//hms returns a duration as hours, minutes and seconds bytes
+func hms(d time.Duration) []byte {
+	secs := int(d / time.Second)
+	return []byte{clampByte(secs / 3600), byte(secs / 60 % 60), byte(secs % 60)}
+}
+
+//withUnits returns a value as two bytes, LSB first, followed by its units specifier
+func withUnits(v float64, units byte) []byte {
+	return append(uint16LE(v), units)
+}
+
+//uint16LE returns a value rounded and clamped to two bytes, LSB first
+func uint16LE(v float64) []byte {
+	n := math.Round(math.Max(0, math.Min(v, math.MaxUint16)))
+	return []byte{byte(uint16(n)), byte(uint16(n) >> 8)}
+}
+
+//clampByte returns a value clamped to a single byte
+func clampByte(v int) byte {
+	if v < 0 {
+		return 0
+	}
+	if v > math.MaxUint8 {
+		return math.MaxUint8
+	}
+	return byte(v)
+}
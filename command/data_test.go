@@ -0,0 +1,62 @@
+package command
+
+import (
+	"pm5-emulator/protocol"
+	"pm5-emulator/protocol/csafe"
+	"pm5-emulator/workout"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_DataCommands(t *testing.T) {
+	h := newTestHandler()
+	h.workout.Update(func(m *workout.Metrics) {
+		m.ElapsedTime = time.Hour + 2*time.Minute + 3*time.Second + 400*time.Millisecond
+		m.Distance = 2000.4
+		m.Speed = 5
+		m.StrokeRate = 28
+		m.Power = 350
+		m.Calories = 123
+		m.HeartRate = 150
+	})
+
+	tests := []struct {
+		name string
+		cmd  byte
+		want []byte
+	}{
+		{"GETTWORK", byte(csafe.GETTWORK_CMD), []byte{0x01, 0x02, 0x03}},
+		{"GETHORIZONTAL", byte(csafe.GETHORIZONTAL_CMD), []byte{0xD0, 0x07, csafe.DISTANCE_METER_0_0}},
+		{"GETCALORIES", byte(csafe.GETCALORIES_CMD), []byte{0x7B, 0x00}},
+		{"GETSPEED", byte(csafe.GETSPEED_CMD), []byte{0xB4, 0x00, csafe.SPEED_KMPERHOUR_0_1}},
+		{"GETPACE", byte(csafe.GETPACE_CMD), []byte{0xC8, 0x00, csafe.PACE_SECONDSPERKM_0_0}},
+		{"GETCADENCE", byte(csafe.GETCADENCE_CMD), []byte{0x1C, 0x00, csafe.CADENCE_STROKESPERMINUTE_0_0}},
+		{"GETHRCUR", byte(csafe.GETHRCUR_CMD), []byte{0x96}},
+		{"GETPOWER", byte(csafe.GETPOWER_CMD), []byte{0x5E, 0x01, csafe.POWER_WATTS_0_0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := h.Handle(protocol.Request{Command: tt.cmd})
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestHandler_DataCommandsNotRowing(t *testing.T) {
+	h := newTestHandler()
+
+	got, err := h.Handle(protocol.Request{Command: byte(csafe.GETPACE_CMD)})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []byte{0x00, 0x00, csafe.PACE_SECONDSPERKM_0_0}, got)
+	}
+}
+
+func Test_uint16LE(t *testing.T) {
+	assert.Equal(t, []byte{0x00, 0x00}, uint16LE(-5))
+	assert.Equal(t, []byte{0x01, 0x00}, uint16LE(0.6))
+	assert.Equal(t, []byte{0xFF, 0xFF}, uint16LE(100000))
+}
@@ -0,0 +1,42 @@
+package command
+
+import (
+	"fmt"
+	"math"
+	"pm5-emulator/protocol"
+	"pm5-emulator/protocol/csafe"
+)
+
+//registerForceCommands registers the PM proprietary commands reading the
+//force curve of the last stroke
+func (h *Handler) registerForceCommands() {
+	h.HandleWrappedFunc(byte(csafe.GETPMDATA_CMD), byte(csafe.PM_GET_FORCEPLOTDATA), h.pmGetForcePlotData)
+}
+
+//pmGetForcePlotData answers the number of bytes read followed by the next
+//points of the force curve of the last stroke, in pounds-force on 2 bytes,
+//LSB first. The length requested is up to FORCEPLOT_BLOCKSIZE bytes. The
+//curve is read from its start again once a new stroke is finished.
+func (h *Handler) pmGetForcePlotData(req protocol.Request) ([]byte, error) {
+	if err := checkLen(req, 1); err != nil {
+		return nil, err
+	}
+	n := int(req.Data[0])
+	if n > csafe.FORCEPLOT_BLOCKSIZE {
+		return nil, fmt.Errorf("force plot block of %d bytes exceeds %d bytes", n, csafe.FORCEPLOT_BLOCKSIZE)
+	}
+
+	curve, stroke := h.workout.ForceCurve()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if stroke != h.forceStroke {
+		h.forceStroke, h.forceRead = stroke, 0
+	}
+	rsp := []byte{0}
+	for ; len(rsp) < n && h.forceRead < len(curve); h.forceRead++ {
+		v := int(math.Round(math.Max(0, curve[h.forceRead])))
+		rsp = append(rsp, byte(v), byte(v>>8))
+	}
+	rsp[0] = byte(len(rsp) - 1)
+	return rsp, nil
+}
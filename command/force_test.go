@@ -0,0 +1,51 @@
+package command
+
+import (
+	"pm5-emulator/protocol"
+	"pm5-emulator/protocol/csafe"
+	"pm5-emulator/workout"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_ForcePlotData(t *testing.T) {
+	h := newTestHandler()
+	read := func(n byte) []byte {
+		data, err := h.Handle(protocol.Request{Wrapper: byte(csafe.GETPMDATA_CMD), Command: byte(csafe.PM_GET_FORCEPLOTDATA), Data: []byte{n}})
+		assert.NoError(t, err)
+		return data
+	}
+
+	// nothing to read before the first stroke
+	assert.Equal(t, []byte{0}, read(csafe.FORCEPLOT_BLOCKSIZE))
+
+	curve := make([]float64, 20)
+	for i := range curve {
+		curve[i] = float64(100 + i)
+	}
+	h.workout.Update(func(m *workout.Metrics) { m.StrokeCount = 1 })
+	h.workout.SetForceCurve(curve)
+
+	data := read(csafe.FORCEPLOT_BLOCKSIZE)
+	assert.Len(t, data, 1+csafe.FORCEPLOT_BLOCKSIZE)
+	assert.Equal(t, []byte{csafe.FORCEPLOT_BLOCKSIZE, 100, 0, 101, 0}, data[:5])
+	// the rest of the curve, then nothing until the next stroke
+	assert.Equal(t, []byte{8, 116, 0, 117, 0, 118, 0, 119, 0}, read(csafe.FORCEPLOT_BLOCKSIZE))
+	assert.Equal(t, []byte{0}, read(csafe.FORCEPLOT_BLOCKSIZE))
+
+	curve[0] = 300
+	h.workout.Update(func(m *workout.Metrics) { m.StrokeCount = 2 })
+	h.workout.SetForceCurve(curve)
+	assert.Equal(t, []byte{2, 0x2C, 0x01}, read(3))
+}
+
+func TestHandler_ForcePlotDataRejected(t *testing.T) {
+	h := newTestHandler()
+	getData := byte(csafe.GETPMDATA_CMD)
+
+	_, err := h.Handle(protocol.Request{Wrapper: getData, Command: byte(csafe.PM_GET_FORCEPLOTDATA), Data: []byte{csafe.FORCEPLOT_BLOCKSIZE + 1}})
+	assert.Error(t, err)
+	_, err = h.Handle(protocol.Request{Wrapper: getData, Command: byte(csafe.PM_GET_FORCEPLOTDATA)})
+	assert.Error(t, err)
+}
@@ -0,0 +1,151 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"pm5-emulator/protocol"
+	"pm5-emulator/protocol/csafe"
+	"pm5-emulator/workout"
+	"time"
+)
+
+//errNotConfigurable is returned when a goal is set outside of the IDLE and HAVEID states
+var errNotConfigurable = errors.New("workout goals can only be set in IDLE or HAVEID state")
+
+//standardPrograms are the workouts of the PM standard list selected with SETPROGRAM
+var standardPrograms = map[byte]workout.Goal{
+	1: {Distance: 2000},
+	2: {Distance: 5000},
+	3: {Distance: 10000},
+	4: {Time: 30 * time.Minute},
+}
+
+//distanceUnits are the meters in one unit of the supported distance units specifiers
+var distanceUnits = map[byte]float64{
+	csafe.DISTANCE_METER_0_0: 1,
+	csafe.DISTANCE_METER_0_1: 0.1,
+	csafe.DISTANCE_KM_0_0:    1000,
+	csafe.DISTANCE_KM_0_1:    100,
+	csafe.DISTANCE_KM_0_2:    10,
+	csafe.DISTANCE_MILE_0_0:  1609.344,
+	csafe.DISTANCE_MILE_0_1:  160.9344,
+	csafe.DISTANCE_MILE_0_2:  16.09344,
+	csafe.DISTANCE_MILE_0_3:  1.609344,
+	csafe.DISTANCE_FEET_0_0:  0.3048,
+}
+
+//registerGoalCommands registers the standard csafe workout goal commands
+func (h *Handler) registerGoalCommands() {
+	h.HandleFunc(byte(csafe.SETTWORK_CMD), h.configuring(h.setTWork))
+	h.HandleFunc(byte(csafe.SETHORIZONTAL_CMD), h.configuring(h.setHorizontal))
+	h.HandleFunc(byte(csafe.SETCALORIES_CMD), h.configuring(h.setCalories))
+	h.HandleFunc(byte(csafe.SETPOWER_CMD), h.configuring(h.setPower))
+	h.HandleFunc(byte(csafe.SETPROGRAM_CMD), h.configuring(h.setProgram))
+}
+
+//configuring wraps f to reject the command unless the workout can be configured
+func (h *Handler) configuring(f HandlerFunc) HandlerFunc {
+	return func(req protocol.Request) ([]byte, error) {
+		if !h.stm.IsIdle() && !h.stm.HaveID() {
+			return nil, errNotConfigurable
+		}
+		return f(req)
+	}
+}
+
+//setTWork sets a time goal from hours, minutes and seconds
+func (h *Handler) setTWork(req protocol.Request) ([]byte, error) {
+	if err := checkLen(req, csafe.HMS_FORMAT_CNT); err != nil {
+		return nil, err
+	}
+	hours, minutes, seconds := req.Data[0], req.Data[1], req.Data[2]
+	if minutes > 59 || seconds > 59 {
+		return nil, fmt.Errorf("invalid time %d:%d:%d", hours, minutes, seconds)
+	}
+
+	d := time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second
+	h.workout.SetGoal(func(g *workout.Goal) {
+		*g = workout.Goal{Time: d, Power: g.Power}
+	})
+	return nil, nil
+}
+
+//setHorizontal sets a distance goal from a value and its units specifier
+func (h *Handler) setHorizontal(req protocol.Request) ([]byte, error) {
+	if err := checkLen(req, 3); err != nil {
+		return nil, err
+	}
+	meters, ok := distanceUnits[req.Data[2]]
+	if !ok {
+		return nil, fmt.Errorf("unsupported distance units 0x%x", req.Data[2])
+	}
+
+	d := float64(le16(req.Data)) * meters
+	h.workout.SetGoal(func(g *workout.Goal) {
+		*g = workout.Goal{Distance: d, Power: g.Power}
+	})
+	return nil, nil
+}
+
+//setCalories sets a calories goal
+func (h *Handler) setCalories(req protocol.Request) ([]byte, error) {
+	if err := checkLen(req, 2); err != nil {
+		return nil, err
+	}
+
+	c := int(le16(req.Data))
+	h.workout.SetGoal(func(g *workout.Goal) {
+		*g = workout.Goal{Calories: c, Power: g.Power}
+	})
+	return nil, nil
+}
+
+//setPower sets the target power of the workout in watts
+func (h *Handler) setPower(req protocol.Request) ([]byte, error) {
+	if err := checkLen(req, 3); err != nil {
+		return nil, err
+	}
+	if req.Data[2] != csafe.POWER_WATTS_0_0 {
+		return nil, fmt.Errorf("unsupported power units 0x%x", req.Data[2])
+	}
+
+	p := int(le16(req.Data))
+	h.workout.SetGoal(func(g *workout.Goal) {
+		g.Power = p
+	})
+	return nil, nil
+}
+
+//setProgram selects a workout of the standard list, program 0 keeps the
+//goal programmed with the other goal commands
+func (h *Handler) setProgram(req protocol.Request) ([]byte, error) {
+	if err := checkLen(req, 2); err != nil {
+		return nil, err
+	}
+	if req.Data[0] == 0 {
+		return nil, nil
+	}
+
+	program, ok := standardPrograms[req.Data[0]]
+	if !ok {
+		return nil, fmt.Errorf("unsupported program %d", req.Data[0])
+	}
+	h.workout.SetGoal(func(g *workout.Goal) {
+		program.Power = g.Power
+		*g = program
+	})
+	return nil, nil
+}
+
+//checkLen returns an error unless the request carries n data bytes
+func checkLen(req protocol.Request, n int) error {
+	if len(req.Data) != n {
+		return fmt.Errorf("command 0x%x expects %d data bytes, got %d", req.Command, n, len(req.Data))
+	}
+	return nil
+}
+
+//le16 reads two bytes, LSB first
+func le16(b []byte) uint16 {
+	return uint16(b[0]) | uint16(b[1])<<8
+}
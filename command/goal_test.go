@@ -0,0 +1,93 @@
+package command
+
+import (
+	"pm5-emulator/config"
+	"pm5-emulator/protocol"
+	"pm5-emulator/protocol/csafe"
+	"pm5-emulator/workout"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_GoalCommands(t *testing.T) {
+	tests := []struct {
+		name string
+		reqs []protocol.Request
+		want workout.Goal
+	}{
+		{"SETTWORK", []protocol.Request{
+			{Command: byte(csafe.SETTWORK_CMD), Data: []byte{0x00, 0x14, 0x1E}},
+		}, workout.Goal{Time: 20*time.Minute + 30*time.Second}},
+		{"SETHORIZONTAL Meters", []protocol.Request{
+			{Command: byte(csafe.SETHORIZONTAL_CMD), Data: []byte{0xD0, 0x07, csafe.DISTANCE_METER_0_0}},
+		}, workout.Goal{Distance: 2000}},
+		{"SETHORIZONTAL Kilometers", []protocol.Request{
+			{Command: byte(csafe.SETHORIZONTAL_CMD), Data: []byte{0x05, 0x00, csafe.DISTANCE_KM_0_0}},
+		}, workout.Goal{Distance: 5000}},
+		{"SETCALORIES", []protocol.Request{
+			{Command: byte(csafe.SETCALORIES_CMD), Data: []byte{0x64, 0x00}},
+		}, workout.Goal{Calories: 100}},
+		{"SETPOWER Keeps Goal", []protocol.Request{
+			{Command: byte(csafe.SETHORIZONTAL_CMD), Data: []byte{0xF4, 0x01, csafe.DISTANCE_METER_0_0}},
+			{Command: byte(csafe.SETPOWER_CMD), Data: []byte{0xC8, 0x00, csafe.POWER_WATTS_0_0}},
+		}, workout.Goal{Distance: 500, Power: 200}},
+		{"Last Goal Wins", []protocol.Request{
+			{Command: byte(csafe.SETHORIZONTAL_CMD), Data: []byte{0xF4, 0x01, csafe.DISTANCE_METER_0_0}},
+			{Command: byte(csafe.SETCALORIES_CMD), Data: []byte{0x0A, 0x00}},
+		}, workout.Goal{Calories: 10}},
+		{"SETPROGRAM Standard List", []protocol.Request{
+			{Command: byte(csafe.SETPROGRAM_CMD), Data: []byte{0x04, 0x00}},
+		}, workout.Goal{Time: 30 * time.Minute}},
+		{"SETPROGRAM Programmed", []protocol.Request{
+			{Command: byte(csafe.SETCALORIES_CMD), Data: []byte{0x0A, 0x00}},
+			{Command: byte(csafe.SETPROGRAM_CMD), Data: []byte{0x00, 0x00}},
+		}, workout.Goal{Calories: 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler()
+			h.stm.Update(config.CSAFE_GOIDLE_CMD)
+
+			for _, req := range tt.reqs {
+				_, err := h.Handle(req)
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, h.workout.Goal())
+		})
+	}
+}
+
+func TestHandler_GoalCommandsRejected(t *testing.T) {
+	tests := []struct {
+		name  string
+		state string
+		req   protocol.Request
+	}{
+		{"Ready State", config.PM5_STATE_READY,
+			protocol.Request{Command: byte(csafe.SETCALORIES_CMD), Data: []byte{0x0A, 0x00}}},
+		{"InUse State", config.PM5_STATE_INUSE,
+			protocol.Request{Command: byte(csafe.SETCALORIES_CMD), Data: []byte{0x0A, 0x00}}},
+		{"Short Data", config.PM5_STATE_IDLE,
+			protocol.Request{Command: byte(csafe.SETTWORK_CMD), Data: []byte{0x00, 0x14}}},
+		{"Invalid Time", config.PM5_STATE_IDLE,
+			protocol.Request{Command: byte(csafe.SETTWORK_CMD), Data: []byte{0x00, 0x3C, 0x00}}},
+		{"Unsupported Distance Units", config.PM5_STATE_IDLE,
+			protocol.Request{Command: byte(csafe.SETHORIZONTAL_CMD), Data: []byte{0x0A, 0x00, csafe.DISTANCE_STEPS_0_0}}},
+		{"Unsupported Power Units", config.PM5_STATE_HAVEID,
+			protocol.Request{Command: byte(csafe.SETPOWER_CMD), Data: []byte{0x0A, 0x00, csafe.ENERGY_CALORIES_0_0}}},
+		{"Unsupported Program", config.PM5_STATE_HAVEID,
+			protocol.Request{Command: byte(csafe.SETPROGRAM_CMD), Data: []byte{0x10, 0x00}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler()
+			h.stm.SetState(tt.state)
+
+			_, err := h.Handle(tt.req)
+			assert.Error(t, err)
+			assert.Equal(t, workout.Goal{}, h.workout.Goal())
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package command
+
+import (
+	"fmt"
+	"pm5-emulator/clock"
+	"pm5-emulator/config"
+	"pm5-emulator/logbook"
+	"pm5-emulator/protocol"
+	"pm5-emulator/sm"
+	"pm5-emulator/user"
+	"pm5-emulator/workout"
+	"sync"
+)
+
+//HandlerFunc answers a single command
+type HandlerFunc func(req protocol.Request) ([]byte, error)
+
+//handlerKey identifies a command along with the wrapper it is nested in
+type handlerKey struct {
+	wrapper byte
+	command byte
+}
+
+//Handler answers the commands received over the control service from the
+//state of the emulated PM
+type Handler struct {
+	mu       sync.Mutex
+	stm      *sm.StateMachine
+	workout  *workout.Workout
+	user     *user.User
+	logbook  *logbook.Logbook
+	clock    *clock.PM
+	handlers map[handlerKey]HandlerFunc
+
+	forceStroke int // stroke of the force curve being read
+	forceRead   int // points of the force curve read
+}
+
+//NewHandler returns a handler for the emulated PM driven by stm, rowing w
+//for the user u, keeping its workouts in lb and its date and time in c
+func NewHandler(stm *sm.StateMachine, w *workout.Workout, u *user.User, lb *logbook.Logbook, c *clock.PM) *Handler {
+	h := &Handler{
+		stm:      stm,
+		workout:  w,
+		user:     u,
+		logbook:  lb,
+		clock:    c,
+		handlers: make(map[handlerKey]HandlerFunc),
+	}
+
+	// Status requests are answered by the status byte alone
+	h.HandleFunc(config.CSAFE_GETSTATUS_CMD, func(req protocol.Request) ([]byte, error) {
+		return nil, nil
+	})
+
+	// State machine commands
+	for _, cmd := range []byte{
+		config.CSAFE_RESET_CMD,
+		config.CSAFE_GOIDLE_CMD,
+		config.CSAFE_GOHAVEID_CMD,
+		config.CSAFE_GOINUSE_CMD,
+		config.CSAFE_GOFINISHED_CMD,
+		config.CSAFE_GOREADY_CMD,
+		config.CSAFE_BADID_CMD,
+	} {
+		h.HandleFunc(cmd, h.updateState)
+	}
+
+	h.registerUserCommands()
+	h.registerProfileCommands()
+	h.registerDataCommands()
+	h.registerGoalCommands()
+	h.registerLogbookCommands()
+	h.registerClockCommands()
+	h.registerForceCommands()
+
+	return h
+}
+
+//HandleFunc registers the function answering a command
+func (h *Handler) HandleFunc(cmd byte, f HandlerFunc) {
+	h.HandleWrappedFunc(0, cmd, f)
+}
+
+//HandleWrappedFunc registers the function answering a command nested in wrapper
+func (h *Handler) HandleWrappedFunc(wrapper, cmd byte, f HandlerFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.handlers[handlerKey{wrapper: wrapper, command: cmd}] = f
+}
+
+//Handle answers a request, commands without a registered function are rejected
+func (h *Handler) Handle(req protocol.Request) ([]byte, error) {
+	h.mu.Lock()
+	f, ok := h.handlers[handlerKey{wrapper: req.Wrapper, command: req.Command}]
+	h.mu.Unlock()
+
+	if !ok {
+		if req.Wrapper != 0 {
+			return nil, fmt.Errorf("unsupported command 0x%x wrapped in 0x%x", req.Command, req.Wrapper)
+		}
+		return nil, fmt.Errorf("unsupported command 0x%x", req.Command)
+	}
+	return f(req)
+}
+
+//State returns the current state name of the state machine
+func (h *Handler) State() string {
+	return h.stm.GetStateName()
+}
+
+//updateState passes a state machine command to the state machine
+func (h *Handler) updateState(req protocol.Request) ([]byte, error) {
+	return nil, h.stm.Update(req.Command)
+}
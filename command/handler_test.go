@@ -0,0 +1,58 @@
+package command
+
+import (
+	"pm5-emulator/clock"
+	"pm5-emulator/config"
+	"pm5-emulator/logbook"
+	"pm5-emulator/protocol"
+	"pm5-emulator/sm"
+	"pm5-emulator/user"
+	"pm5-emulator/workout"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestHandler() *Handler {
+	stm := sm.NewStateMachine()
+	stm.Reset()
+	return NewHandler(stm, workout.New(), user.New(stm), logbook.New(), clock.NewPM(clock.Real{}, time.Time{}))
+}
+
+func TestHandler_Handle(t *testing.T) {
+	h := newTestHandler()
+
+	// walk through the states with state machine commands
+	steps := []struct {
+		path    string
+		command byte
+		state   string
+	}{
+		{"ready2idle", config.CSAFE_GOIDLE_CMD, config.PM5_STATE_IDLE},
+		{"idle2haveID", config.CSAFE_GOHAVEID_CMD, config.PM5_STATE_HAVEID},
+		{"haveID2InUse", config.CSAFE_GOINUSE_CMD, config.PM5_STATE_INUSE},
+		{"inUse2Finished", config.CSAFE_GOFINISHED_CMD, config.PM5_STATE_FINISHED},
+		{"finished2Ready", config.CSAFE_RESET_CMD, config.PM5_STATE_READY},
+	}
+	for _, step := range steps {
+		t.Run(step.path, func(t *testing.T) {
+			data, err := h.Handle(protocol.Request{Command: step.command})
+			assert.NoError(t, err)
+			assert.Nil(t, data)
+			assert.Equal(t, step.state, h.State())
+		})
+	}
+
+	// commands the state machine does not accept in its current state are rejected
+	_, err := h.Handle(protocol.Request{Command: config.CSAFE_GOFINISHED_CMD})
+	assert.Error(t, err)
+
+	// status requests are always accepted
+	_, err = h.Handle(protocol.Request{Command: config.CSAFE_GETSTATUS_CMD})
+	assert.NoError(t, err)
+
+	// unknown commands are rejected
+	_, err = h.Handle(protocol.Request{Command: 0x7A})
+	assert.Error(t, err)
+}
@@ -0,0 +1,77 @@
+package command
+
+import (
+	"encoding/binary"
+	"fmt"
+	"pm5-emulator/protocol"
+	"pm5-emulator/protocol/csafe"
+)
+
+//LOGCARD_PRESENT is the log card state answered while the logbook is available
+const LOGCARD_PRESENT = 1
+
+//registerLogbookCommands registers the PM proprietary commands reading the logbook
+func (h *Handler) registerLogbookCommands() {
+	getCfg, getData := byte(csafe.GETPMCFG_CMD), byte(csafe.GETPMDATA_CMD)
+
+	h.HandleWrappedFunc(getCfg, byte(csafe.PM_GET_LOGCARDSTATE), h.pmGetLogCardState)
+	h.HandleWrappedFunc(getCfg, byte(csafe.PM_GET_LOGCARDCENSUS), h.pmGetLogCardCensus)
+	h.HandleWrappedFunc(getCfg, byte(csafe.PM_GET_INTERNALLOGPARAMS), h.pmGetInternalLogParams)
+	h.HandleWrappedFunc(getData, byte(csafe.PM_GET_LOGCARDMEMORY), h.pmGetLogCardMemory)
+	h.HandleWrappedFunc(getData, byte(csafe.PM_GET_INTERNALLOGMEMORY), h.pmGetInternalLogMemory)
+}
+
+//pmGetLogCardState answers whether the logbook is available
+func (h *Handler) pmGetLogCardState(req protocol.Request) ([]byte, error) {
+	return []byte{LOGCARD_PRESENT}, nil
+}
+
+//pmGetLogCardCensus answers the number of users and of workouts in the logbook
+func (h *Handler) pmGetLogCardCensus(req protocol.Request) ([]byte, error) {
+	rsp := []byte{clampByte(len(h.logbook.Users())), 0, 0}
+	binary.BigEndian.PutUint16(rsp[1:], uint16(h.logbook.Count()))
+	return rsp, nil
+}
+
+//pmGetInternalLogParams answers the size in bytes of the internal log memory
+//followed by the number of workouts it holds
+func (h *Handler) pmGetInternalLogParams(req protocol.Request) ([]byte, error) {
+	rsp := make([]byte, 6)
+	binary.BigEndian.PutUint32(rsp, uint32(len(h.logbook.Memory())))
+	binary.BigEndian.PutUint16(rsp[4:], uint16(h.logbook.Count()))
+	return rsp, nil
+}
+
+//pmGetLogCardMemory answers a block of the logbook memory of the current user
+func (h *Handler) pmGetLogCardMemory(req protocol.Request) ([]byte, error) {
+	return readMemory(req, h.logbook.UserMemory(h.user.ID()))
+}
+
+//pmGetInternalLogMemory answers a block of the logbook memory of all the users
+func (h *Handler) pmGetInternalLogMemory(req protocol.Request) ([]byte, error) {
+	return readMemory(req, h.logbook.Memory())
+}
+
+//readMemory answers the number of bytes read followed by the block of mem at
+//the address and of the length requested. The address is sent on 4 bytes,
+//MSB first, followed by the length, up to MEMORY_BLOCKSIZE.
+func readMemory(req protocol.Request, mem []byte) ([]byte, error) {
+	if err := checkLen(req, 5); err != nil {
+		return nil, err
+	}
+	n := int(req.Data[4])
+	if n > csafe.MEMORY_BLOCKSIZE {
+		return nil, fmt.Errorf("memory block of %d bytes exceeds %d bytes", n, csafe.MEMORY_BLOCKSIZE)
+	}
+
+	// nothing is read past the end of the memory, the address is checked
+	// before it is converted for it not to wrap on 32-bit builds
+	addr := len(mem)
+	if a := binary.BigEndian.Uint32(req.Data); uint64(a) < uint64(len(mem)) {
+		addr = int(a)
+	}
+	if addr+n > len(mem) {
+		n = len(mem) - addr
+	}
+	return append([]byte{byte(n)}, mem[addr:addr+n]...), nil
+}
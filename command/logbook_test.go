@@ -0,0 +1,77 @@
+package command
+
+import (
+	"encoding/binary"
+	"math"
+	"pm5-emulator/logbook"
+	"pm5-emulator/protocol"
+	"pm5-emulator/protocol/csafe"
+	"pm5-emulator/workout"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_LogbookCommands(t *testing.T) {
+	h := newTestHandler()
+	getCfg, getData := byte(csafe.GETPMCFG_CMD), byte(csafe.GETPMDATA_CMD)
+
+	e := logbook.NewEntry(time.Now(), workout.Goal{},
+		workout.Metrics{ElapsedTime: 10 * time.Minute, Distance: 2500, Calories: 120, StrokeCount: 240},
+		[]workout.Split{{Time: 2 * time.Minute, Distance: 500, Calories: 24, StrokeCount: 48}})
+	for i := 0; i < 20; i++ {
+		h.logbook.Add(i%3, e)
+	}
+
+	data, err := h.Handle(protocol.Request{Wrapper: getCfg, Command: byte(csafe.PM_GET_LOGCARDSTATE)})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{LOGCARD_PRESENT}, data)
+
+	data, err = h.Handle(protocol.Request{Wrapper: getCfg, Command: byte(csafe.PM_GET_LOGCARDCENSUS)})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{3, 0, 20}, data)
+
+	data, err = h.Handle(protocol.Request{Wrapper: getCfg, Command: byte(csafe.PM_GET_INTERNALLOGPARAMS)})
+	assert.NoError(t, err)
+	size := int(binary.BigEndian.Uint32(data))
+	assert.Equal(t, len(h.logbook.Memory()), size)
+	assert.Equal(t, uint16(20), binary.BigEndian.Uint16(data[4:]))
+
+	read := func(cmd byte) []byte {
+		var mem []byte
+		for addr := 0; ; addr += csafe.MEMORY_BLOCKSIZE {
+			req := []byte{0, 0, 0, 0, csafe.MEMORY_BLOCKSIZE}
+			binary.BigEndian.PutUint32(req, uint32(addr))
+			data, err := h.Handle(protocol.Request{Wrapper: getData, Command: cmd, Data: req})
+			if !assert.NoError(t, err) || data[0] == 0 {
+				return mem
+			}
+			assert.Len(t, data, int(data[0])+1)
+			mem = append(mem, data[1:]...)
+		}
+	}
+	assert.Equal(t, h.logbook.Memory(), read(byte(csafe.PM_GET_INTERNALLOGMEMORY)))
+	// the log card holds the workouts of the current user
+	assert.Equal(t, h.logbook.UserMemory(0), read(byte(csafe.PM_GET_LOGCARDMEMORY)))
+	assert.Len(t, read(byte(csafe.PM_GET_LOGCARDMEMORY)), 7*(logbook.RECORD_SIZE+2*logbook.SPLIT_RECORD_SIZE))
+
+	// nothing is read past the end of the memory
+	for _, addr := range []uint32{uint32(size), 1 << 31, math.MaxUint32} {
+		req := []byte{0, 0, 0, 0, csafe.MEMORY_BLOCKSIZE}
+		binary.BigEndian.PutUint32(req, addr)
+		data, err := h.Handle(protocol.Request{Wrapper: getData, Command: byte(csafe.PM_GET_INTERNALLOGMEMORY), Data: req})
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{0}, data)
+	}
+}
+
+func TestHandler_LogMemoryRejected(t *testing.T) {
+	h := newTestHandler()
+	getData := byte(csafe.GETPMDATA_CMD)
+
+	_, err := h.Handle(protocol.Request{Wrapper: getData, Command: byte(csafe.PM_GET_INTERNALLOGMEMORY), Data: []byte{0, 0, 0, 0, csafe.MEMORY_BLOCKSIZE + 1}})
+	assert.Error(t, err)
+	_, err = h.Handle(protocol.Request{Wrapper: getData, Command: byte(csafe.PM_GET_INTERNALLOGMEMORY), Data: []byte{0, 0, 0, 0}})
+	assert.Error(t, err)
+}
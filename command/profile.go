@@ -0,0 +1,136 @@
+package command
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"pm5-emulator/protocol"
+	"pm5-emulator/protocol/csafe"
+	"pm5-emulator/user"
+)
+
+//weightUnits are the kilograms in one unit of the supported weight units specifiers
+var weightUnits = map[byte]float64{
+	csafe.WEIGHT_KG_0_0:  1,
+	csafe.WEIGHT_KG_0_1:  0.1,
+	csafe.WEIGHT_LBS_0_0: csafe.LBS_TO_KG,
+	csafe.WEIGHT_LBS_0_1: csafe.LBS_TO_KG / 10,
+}
+
+//registerProfileCommands registers the standard csafe user information
+//commands and the PM proprietary user ID and profile commands. PM
+//proprietary commands send multi byte values MSB first.
+func (h *Handler) registerProfileCommands() {
+	h.HandleFunc(byte(csafe.SETUSERINFO_CMD), h.setUserInfo)
+	h.HandleFunc(byte(csafe.GETUSERINFO_CMD), h.getUserInfo)
+
+	h.HandleWrappedFunc(byte(csafe.SETPMCFG_CMD), byte(csafe.PM_SET_USER_ID), h.pmSetUserID)
+	h.HandleWrappedFunc(byte(csafe.SETPMCFG_CMD), byte(csafe.PM_SET_USER_PROFILE), h.pmSetUserProfile)
+	h.HandleWrappedFunc(byte(csafe.GETPMCFG_CMD), byte(csafe.PM_GET_USER_ID), h.pmGetUserID)
+	h.HandleWrappedFunc(byte(csafe.GETPMCFG_CMD), byte(csafe.PM_GET_USER_PROFILE), h.pmGetUserProfile)
+}
+
+//setUserInfo sets the profile of the current user from the weight, LSB
+//first, its units specifier, the age and the gender
+func (h *Handler) setUserInfo(req protocol.Request) ([]byte, error) {
+	if err := checkLen(req, 5); err != nil {
+		return nil, err
+	}
+	p, err := profile(le16(req.Data), req.Data[2:])
+	if err != nil {
+		return nil, err
+	}
+	h.user.SetProfile(p)
+	return nil, nil
+}
+
+//getUserInfo answers the profile of the current user as sent with SETUSERINFO
+func (h *Handler) getUserInfo(req protocol.Request) ([]byte, error) {
+	p := h.user.Profile()
+	return append(uint16LE(weight(p)), p.Units, clampByte(p.Age), p.Gender), nil
+}
+
+//pmSetUserID sets the ID of a user number
+func (h *Handler) pmSetUserID(req protocol.Request) ([]byte, error) {
+	if err := checkLen(req, 5); err != nil {
+		return nil, err
+	}
+	id := binary.BigEndian.Uint32(req.Data[1:])
+	return nil, h.user.SetUserID(int(req.Data[0]), int(id))
+}
+
+//pmGetUserID answers the user number followed by its ID
+func (h *Handler) pmGetUserID(req protocol.Request) ([]byte, error) {
+	if err := checkLen(req, 1); err != nil {
+		return nil, err
+	}
+	id, err := h.user.UserID(int(req.Data[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	rsp := make([]byte, 5)
+	rsp[0] = req.Data[0]
+	binary.BigEndian.PutUint32(rsp[1:], uint32(id))
+	return rsp, nil
+}
+
+//pmSetUserProfile sets the profile of a user number from the weight, its
+//units specifier, the age and the gender
+func (h *Handler) pmSetUserProfile(req protocol.Request) ([]byte, error) {
+	if err := checkLen(req, 6); err != nil {
+		return nil, err
+	}
+	p, err := profile(binary.BigEndian.Uint16(req.Data[1:]), req.Data[3:])
+	if err != nil {
+		return nil, err
+	}
+	return nil, h.user.SetUserProfile(int(req.Data[0]), p)
+}
+
+//pmGetUserProfile answers the user number followed by its profile as sent
+//with PM_SET_USER_PROFILE
+func (h *Handler) pmGetUserProfile(req protocol.Request) ([]byte, error) {
+	if err := checkLen(req, 1); err != nil {
+		return nil, err
+	}
+	p, err := h.user.UserProfile(int(req.Data[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	w := uint16LE(weight(p))
+	return []byte{req.Data[0], w[1], w[0], p.Units, clampByte(p.Age), p.Gender}, nil
+}
+
+//profile returns a profile from a weight value and the units specifier, age
+//and gender bytes following it
+func profile(value uint16, b []byte) (user.Profile, error) {
+	units, age, gender := b[0], b[1], b[2]
+	kg, ok := weightUnits[units]
+	if !ok {
+		return user.Profile{}, fmt.Errorf("unsupported weight units 0x%x", units)
+	}
+	if gender > user.GENDER_FEMALE {
+		return user.Profile{}, fmt.Errorf("unsupported gender %d", gender)
+	}
+	if value == 0 {
+		return user.Profile{}, fmt.Errorf("invalid weight %d", value)
+	}
+
+	return user.Profile{
+		Weight: float64(value) * kg,
+		Age:    int(age),
+		Gender: gender,
+		Units:  units,
+	}, nil
+}
+
+//weight returns the weight of a profile in its units
+func weight(p user.Profile) float64 {
+	kg, ok := weightUnits[p.Units]
+	if !ok {
+		return 0
+	}
+	return math.Round(p.Weight / kg)
+}
@@ -0,0 +1,88 @@
+package command
+
+import (
+	"pm5-emulator/protocol"
+	"pm5-emulator/protocol/csafe"
+	"pm5-emulator/user"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_UserInfo(t *testing.T) {
+	h := newTestHandler()
+
+	// default profile of a 175 lb user
+	data, err := h.Handle(protocol.Request{Command: byte(csafe.GETUSERINFO_CMD)})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xAF, 0x00, csafe.WEIGHT_LBS_0_0, user.DEFAULT_AGE, user.GENDER_NONE}, data)
+
+	// 72.5 kg, 41 years old female
+	_, err = h.Handle(protocol.Request{
+		Command: byte(csafe.SETUSERINFO_CMD),
+		Data:    []byte{0xD5, 0x02, csafe.WEIGHT_KG_0_1, 41, user.GENDER_FEMALE},
+	})
+	assert.NoError(t, err)
+	assert.InDelta(t, 72.5, h.user.Profile().Weight, 0.001)
+
+	data, err = h.Handle(protocol.Request{Command: byte(csafe.GETUSERINFO_CMD)})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xD5, 0x02, csafe.WEIGHT_KG_0_1, 41, user.GENDER_FEMALE}, data)
+}
+
+func TestHandler_UserInfoRejected(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"Short Data", []byte{0x50, 0x00, csafe.WEIGHT_KG_0_0, 30}},
+		{"Unsupported Units", []byte{0x50, 0x00, csafe.DISTANCE_KM_0_0, 30, user.GENDER_MALE}},
+		{"Unsupported Gender", []byte{0x50, 0x00, csafe.WEIGHT_KG_0_0, 30, 3}},
+		{"No Weight", []byte{0x00, 0x00, csafe.WEIGHT_KG_0_0, 30, user.GENDER_MALE}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler()
+			_, err := h.Handle(protocol.Request{Command: byte(csafe.SETUSERINFO_CMD), Data: tt.data})
+			assert.Error(t, err)
+			assert.Equal(t, user.DefaultProfile(), h.user.Profile())
+		})
+	}
+}
+
+func TestHandler_PMUserProfile(t *testing.T) {
+	h := newTestHandler()
+	setCfg, getCfg := byte(csafe.SETPMCFG_CMD), byte(csafe.GETPMCFG_CMD)
+
+	// user number 2 is 0x00012345 weighing 200 lbs
+	_, err := h.Handle(protocol.Request{Wrapper: setCfg, Command: byte(csafe.PM_SET_USER_ID), Data: []byte{2, 0x00, 0x01, 0x23, 0x45}})
+	assert.NoError(t, err)
+	_, err = h.Handle(protocol.Request{
+		Wrapper: setCfg,
+		Command: byte(csafe.PM_SET_USER_PROFILE),
+		Data:    []byte{2, 0x00, 0xC8, csafe.WEIGHT_LBS_0_0, 25, user.GENDER_MALE},
+	})
+	assert.NoError(t, err)
+
+	data, err := h.Handle(protocol.Request{Wrapper: getCfg, Command: byte(csafe.PM_GET_USER_ID), Data: []byte{2}})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{2, 0x00, 0x01, 0x23, 0x45}, data)
+
+	data, err = h.Handle(protocol.Request{Wrapper: getCfg, Command: byte(csafe.PM_GET_USER_PROFILE), Data: []byte{2}})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{2, 0x00, 0xC8, csafe.WEIGHT_LBS_0_0, 25, user.GENDER_MALE}, data)
+
+	// the current user is not affected until logged in as 0x12345
+	assert.Equal(t, user.DefaultProfile(), h.user.Profile())
+	_, err = h.Handle(protocol.Request{Wrapper: setCfg, Command: byte(csafe.PM_SET_USER_ID), Data: []byte{0, 0x00, 0x01, 0x23, 0x45}})
+	assert.NoError(t, err)
+	assert.InDelta(t, 200*csafe.LBS_TO_KG, h.user.Profile().Weight, 0.001)
+
+	// out of range user number
+	_, err = h.Handle(protocol.Request{Wrapper: getCfg, Command: byte(csafe.PM_GET_USER_PROFILE), Data: []byte{user.MAX_USERS}})
+	assert.Equal(t, user.ErrUserNumber, err)
+
+	// PM commands are only answered inside their wrapper
+	_, err = h.Handle(protocol.Request{Command: byte(csafe.PM_GET_USER_ID), Data: []byte{0}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,32 @@
+package command
+
+import (
+	"errors"
+	"pm5-emulator/protocol"
+	"pm5-emulator/protocol/csafe"
+)
+
+//errIDDigitsNotConfigurable is returned when the ID digits are set once a user may have entered an ID
+var errIDDigitsNotConfigurable = errors.New("id digits can only be set in READY or IDLE state")
+
+//registerUserCommands registers the standard csafe user identification commands
+func (h *Handler) registerUserCommands() {
+	h.HandleFunc(byte(csafe.IDDIGITS_CMD), h.idDigits)
+	h.HandleFunc(byte(csafe.GETID_CMD), h.getID)
+}
+
+//idDigits sets the number of digits of the user ID
+func (h *Handler) idDigits(req protocol.Request) ([]byte, error) {
+	if !h.stm.IsReady() && !h.stm.IsIdle() {
+		return nil, errIDDigitsNotConfigurable
+	}
+	if err := checkLen(req, 1); err != nil {
+		return nil, err
+	}
+	return nil, h.user.SetDigits(int(req.Data[0]))
+}
+
+//getID answers the user ID as ASCII digits
+func (h *Handler) getID(req protocol.Request) ([]byte, error) {
+	return []byte(h.user.IDString()), nil
+}
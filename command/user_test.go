@@ -0,0 +1,64 @@
+package command
+
+import (
+	"pm5-emulator/config"
+	"pm5-emulator/protocol"
+	"pm5-emulator/protocol/csafe"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_UserCommands(t *testing.T) {
+	h := newTestHandler()
+
+	// the host asks for three digits IDs
+	_, err := h.Handle(protocol.Request{Command: byte(csafe.IDDIGITS_CMD), Data: []byte{3}})
+	assert.NoError(t, err)
+
+	data, err := h.Handle(protocol.Request{Command: byte(csafe.GETID_CMD)})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("000"), data)
+
+	_, err = h.Handle(protocol.Request{Command: config.CSAFE_GOIDLE_CMD})
+	assert.NoError(t, err)
+	assert.NoError(t, h.user.EnterID("042"))
+	assert.Equal(t, config.PM5_STATE_HAVEID, h.State())
+
+	data, err = h.Handle(protocol.Request{Command: byte(csafe.GETID_CMD)})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("042"), data)
+
+	// the ID is not known to the host
+	_, err = h.Handle(protocol.Request{Command: config.CSAFE_BADID_CMD})
+	assert.NoError(t, err)
+	assert.Equal(t, config.PM5_STATE_IDLE, h.State())
+
+	data, err = h.Handle(protocol.Request{Command: byte(csafe.GETID_CMD)})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("000"), data)
+}
+
+func TestHandler_IDDigitsRejected(t *testing.T) {
+	tests := []struct {
+		name  string
+		state string
+		data  []byte
+	}{
+		{"HaveID State", config.PM5_STATE_HAVEID, []byte{3}},
+		{"InUse State", config.PM5_STATE_INUSE, []byte{3}},
+		{"Too Few Digits", config.PM5_STATE_IDLE, []byte{csafe.IDDIGITS_MIN - 1}},
+		{"Too Many Digits", config.PM5_STATE_READY, []byte{csafe.IDDIGITS_MAX + 1}},
+		{"Missing Data", config.PM5_STATE_READY, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler()
+			h.stm.SetState(tt.state)
+
+			_, err := h.Handle(protocol.Request{Command: byte(csafe.IDDIGITS_CMD), Data: tt.data})
+			assert.Error(t, err)
+			assert.Equal(t, csafe.DEFAULT_IDDIGITS, h.user.Digits())
+		})
+	}
+}
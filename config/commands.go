@@ -1,6 +1,7 @@
 package config
 
 const (
+	CSAFE_GETSTATUS_CMD  = 0x80
 	CSAFE_RESET_CMD      = 0x81
 	CSAFE_GOIDLE_CMD     = 0x82
 	CSAFE_GOHAVEID_CMD   = 0x83
@@ -15,3 +15,55 @@ const (
 	UUID_SUFFIX       = "-43E5-11E4-916C-0800200C9A66"
 	UUID_PREFIX       = "CE06"
 )
+
+//MACHINE_TYPES are the erg machine types the PM can report, by the name they
+//are picked with
+var MACHINE_TYPES = map[string]string{
+	"static-a":  "Static A",
+	"static-b":  "Static B",
+	"static-c":  "Static C",
+	"static-d":  "Static D",
+	"static-e":  "Static E",
+	"dynamic":   "Static Dynamic",
+	"slides-a":  "Slides A",
+	"slides-b":  "Slides B",
+	"slides-c":  "Slides C",
+	"slides-d":  "Slides D",
+	"slides-e":  "Slides E",
+	"simulator": "Static Simulator",
+	"ski":       "Static Ski",
+	"bike":      "Bike",
+}
+
+//Device is the identity the PM reports
+type Device struct {
+	Name            string // advertised name
+	SerialNo        string
+	Manufacturer    string
+	ModelNo         string
+	HardwareVersion string
+	FirmwareVersion string
+	MachineType     string // one of the MACHINE_TYPES
+}
+
+//DefaultDevice returns the identity of the emulated PM
+func DefaultDevice() Device {
+	return Device{
+		Name:            NAME,
+		SerialNo:        SERIAL_NO,
+		Manufacturer:    MANUFACTURER_NAME,
+		ModelNo:         MODEL_NO,
+		HardwareVersion: HARDWARE_VERSION,
+		FirmwareVersion: FIRMWARE_VERSION,
+		MachineType:     ERG_MACHINE_TYPE,
+	}
+}
+
+//DeviceWithSerial returns the identity of the emulated PM with the serial
+//number serial, which the PM advertises its name with
+func DeviceWithSerial(serial string) Device {
+	d := DefaultDevice()
+	d.SerialNo = serial
+	d.Name = "PM5 " + serial
+	return d
+}
@@ -1,11 +1,28 @@
 package option
 
-import "github.com/bettercap/gatt"
+import (
+	"time"
+
+	"github.com/bettercap/gatt"
+)
+
+//Defaults of the HCI device, which macOS does not expose
+const (
+	DEFAULT_HCI                  = -1
+	DEFAULT_ADVERTISING_INTERVAL = 152500 * time.Microsecond
+	DEFAULT_MAX_CENTRALS         = 4
+)
 
 var DefaultClientOptions = []gatt.Option{
 	gatt.MacDeviceRole(gatt.CentralManager),
 }
 
-var DefaultServerOptions = []gatt.Option{
-	gatt.MacDeviceRole(gatt.PeripheralManager),
+var DefaultServerOptions = ServerOptions(DEFAULT_HCI, DEFAULT_ADVERTISING_INTERVAL, DEFAULT_MAX_CENTRALS)
+
+//ServerOptions returns the options of a peripheral, macOS picks the device,
+//the advertising interval and the number of centrals itself
+func ServerOptions(hci int, interval time.Duration, maxCentrals int) []gatt.Option {
+	return []gatt.Option{
+		gatt.MacDeviceRole(gatt.PeripheralManager),
+	}
 }
@@ -1,21 +1,38 @@
 package option
 
 import (
+	"time"
+
 	"github.com/bettercap/gatt"
 	"github.com/bettercap/gatt/linux/cmd"
 )
 
+//Defaults of the HCI device
+const (
+	DEFAULT_HCI                  = -1                        // first available HCI device
+	DEFAULT_ADVERTISING_INTERVAL = 152500 * time.Microsecond // 0x00f4 units of 0.625ms
+	DEFAULT_MAX_CENTRALS         = 4                         // centrals connected at once, such as an app and a heart rate tool
+)
+
 var DefaultClientOptions = []gatt.Option{
 	gatt.LnxMaxConnections(1),
-	gatt.LnxDeviceID(-1, true),
+	gatt.LnxDeviceID(DEFAULT_HCI, true),
 }
 
-var DefaultServerOptions = []gatt.Option{
-	gatt.LnxMaxConnections(1),
-	gatt.LnxDeviceID(-1, true),
-	gatt.LnxSetAdvertisingParameters(&cmd.LESetAdvertisingParameters{
-		AdvertisingIntervalMin: 0x00f4,
-		AdvertisingIntervalMax: 0x00f4,
-		AdvertisingChannelMap:  0x7,
-	}),
+var DefaultServerOptions = ServerOptions(DEFAULT_HCI, DEFAULT_ADVERTISING_INTERVAL, DEFAULT_MAX_CENTRALS)
+
+//ServerOptions returns the options of a peripheral on the HCI device hci,
+//-1 for the first available, advertising every interval until maxCentrals
+//centrals are connected
+func ServerOptions(hci int, interval time.Duration, maxCentrals int) []gatt.Option {
+	units := uint16(interval / (625 * time.Microsecond))
+	return []gatt.Option{
+		gatt.LnxMaxConnections(maxCentrals),
+		gatt.LnxDeviceID(hci, true),
+		gatt.LnxSetAdvertisingParameters(&cmd.LESetAdvertisingParameters{
+			AdvertisingIntervalMin: units,
+			AdvertisingIntervalMax: units,
+			AdvertisingChannelMap:  0x7,
+		}),
+	}
 }
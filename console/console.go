@@ -0,0 +1,312 @@
+package console
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"pm5-emulator/capture"
+	"pm5-emulator/config"
+	"pm5-emulator/emulator"
+	"pm5-emulator/events"
+	"pm5-emulator/sim"
+	"sync"
+	"time"
+)
+
+//REFRESH is the interval at which the console is redrawn
+const REFRESH = 250 * time.Millisecond
+
+//READ_TIMEOUT is the time after which a read of the terminal returns when no
+//key is pressed, for the keys to stop being read once the console stopped
+const READ_TIMEOUT = 100 * time.Millisecond
+
+//RECENT is the number of csafe exchanges the console shows
+const RECENT = 8
+
+//Steps of the keys raising and lowering the target
+const (
+	RATE_STEP  = 1  // strokes per minute
+	POWER_STEP = 10 // watts
+)
+
+//Console is an interactive terminal console showing what the emulator does
+//and steering it with the keyboard
+type Console struct {
+	em   *emulator.Emulator
+	in   io.Reader
+	out  io.Writer
+	stop chan struct{} // closed by Stop
+	once sync.Once
+
+	mu      sync.Mutex
+	chars   map[string]events.Event // last data read or notified, by characteristic
+	csafe   []events.Event          // last csafe exchanges, oldest first
+	command int                     // index of the state machine command selected in emulator.Commands
+	central string                  // ID of the central selected, the first connected when not
+	message string                  // outcome of the last key
+}
+
+//New returns a console for em reading keys from in and drawing on out
+func New(em *emulator.Emulator, in io.Reader, out io.Writer) *Console {
+	return &Console{
+		em:    em,
+		in:    in,
+		out:   out,
+		chars: make(map[string]events.Event),
+		stop:  make(chan struct{}),
+	}
+}
+
+//Stop makes Run return, restoring the terminal
+func (c *Console) Stop() {
+	c.once.Do(func() { close(c.stop) })
+}
+
+//Run runs the console until q or Ctrl-C is pressed, the input ends or Stop
+//is called. The input is put in raw mode when it is a terminal.
+func (c *Console) Run() error {
+	done := make(chan struct{})
+	in := c.in
+	if f, ok := c.in.(*os.File); ok {
+		restore, err := makeRaw(int(f.Fd()))
+		if err != nil {
+			return fmt.Errorf("console: %v", err)
+		}
+		if restore != nil {
+			defer restore()
+			in = terminalReader{f: f, stop: done}
+		}
+	}
+	// draw on the alternate screen, without cursor
+	fmt.Fprint(c.out, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(c.out, "\x1b[?25h\x1b[?1049l")
+
+	sub := c.em.Events().Subscribe()
+	defer sub.Close()
+	keys := make(chan Key)
+	go readKeys(in, keys, done)
+	defer func() {
+		close(done)
+		if _, ok := in.(terminalReader); ok {
+			// the terminal is restored once it is no longer read
+			for range keys {
+			}
+		}
+	}()
+	ticker := time.NewTicker(REFRESH)
+	defer ticker.Stop()
+
+	c.draw()
+	for {
+		select {
+		case e := <-sub.Events():
+			c.observe(e)
+		case k, ok := <-keys:
+			if !ok || k == 'q' || k == KEY_INTERRUPT {
+				return nil
+			}
+			c.press(k)
+			c.draw()
+		case <-ticker.C:
+			c.draw()
+		case <-c.stop:
+			return nil
+		}
+	}
+}
+
+//terminalReader reads a terminal in raw mode, whose reads return nothing
+//after READ_TIMEOUT, until stop is closed
+type terminalReader struct {
+	f    *os.File
+	stop <-chan struct{}
+}
+
+func (r terminalReader) Read(p []byte) (int, error) {
+	for {
+		// nothing read is reported as the end of the file
+		n, err := r.f.Read(p)
+		if n > 0 || err != io.EOF {
+			return n, err
+		}
+		select {
+		case <-r.stop:
+			return 0, io.EOF
+		default:
+		}
+	}
+}
+
+//observe keeps track of the decoded data and the csafe exchanges of e
+func (c *Console) observe(e events.Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch e.Type {
+	case capture.EVENT_READ, capture.EVENT_NOTIFY:
+		c.chars[e.Char] = e
+	case events.EVENT_CSAFE:
+		c.csafe = append(c.csafe, e)
+		if len(c.csafe) > RECENT {
+			c.csafe = c.csafe[len(c.csafe)-RECENT:]
+		}
+	}
+}
+
+//press does what the key k stands for
+func (c *Console) press(k Key) {
+	var err error
+	msg := ""
+	switch k {
+	case KEY_UP, '+':
+		rate, power := c.target()
+		err = c.em.SetTarget(rate+RATE_STEP, power)
+		msg = fmt.Sprintf("stroke rate %d s/m", rate+RATE_STEP)
+	case KEY_DOWN, '-':
+		rate, power := c.target()
+		rate = max(rate-RATE_STEP, 1)
+		err = c.em.SetTarget(rate, power)
+		msg = fmt.Sprintf("stroke rate %d s/m", rate)
+	case KEY_RIGHT, ']':
+		rate, power := c.target()
+		err = c.em.SetTarget(rate, power+POWER_STEP)
+		msg = fmt.Sprintf("power %d W", power+POWER_STEP)
+	case KEY_LEFT, '[':
+		rate, power := c.target()
+		power = max(power-POWER_STEP, 1)
+		err = c.em.SetTarget(rate, power)
+		msg = fmt.Sprintf("power %d W", power)
+	case '0':
+		err = c.em.SetTarget(0, 0)
+		msg = "target left to the persona and the workout"
+	case 'p':
+		if c.em.State() == config.PM5_STATE_PAUSED {
+			err, msg = c.em.ResumeRowing(), "resumed"
+		} else {
+			err, msg = c.em.StopRowing(), "paused"
+		}
+	case 's':
+		if c.em.State() == config.PM5_STATE_READY {
+			err = c.em.PressButton(emulator.BUTTON_JUST_ROW)
+		} else {
+			err = c.em.Command(config.CSAFE_GOINUSE_CMD)
+		}
+		msg = "rowing"
+	case 'e':
+		err, msg = c.em.Command(config.CSAFE_GOFINISHED_CMD), "workout ended"
+	case 'm':
+		err, msg = c.em.PressButton(emulator.BUTTON_MENU), "back to the main menu"
+	case 'n':
+		msg = "no central to select"
+		if id := c.nextCentral(); id != "" {
+			msg = fmt.Sprintf("central %s selected, d to disconnect", id)
+		}
+	case 'd':
+		msg = "no central to disconnect"
+		if id := c.selectedCentral(); id != "" {
+			err, msg = c.em.Disconnect(id), "disconnected "+id
+		}
+	case 'c':
+		c.mu.Lock()
+		c.command = (c.command + 1) % len(emulator.Commands())
+		c.mu.Unlock()
+		msg = fmt.Sprintf("command %s selected, enter to send", c.selected())
+	case KEY_ENTER:
+		name := c.selected()
+		cmd, _ := emulator.LookupCommand(name)
+		state := c.em.State()
+		if err = c.em.Command(cmd); err != nil {
+			err = fmt.Errorf("%s refused in %s state: %v", name, state, err)
+		}
+		msg = name + " sent"
+	default:
+		return
+	}
+	if err != nil {
+		msg = "error: " + err.Error()
+	}
+	c.mu.Lock()
+	c.message = msg
+	c.mu.Unlock()
+}
+
+//target returns the stroke rate and power the keys change, the target when
+//set or else what the athlete rows at
+func (c *Console) target() (rate, power int) {
+	rate, power = c.em.Target()
+	m := c.em.Metrics()
+	if rate == 0 {
+		rate = m.StrokeRate
+	}
+	if rate == 0 {
+		rate = sim.DEFAULT_STROKE_RATE
+	}
+	if power == 0 {
+		power = m.Power
+	}
+	if power == 0 {
+		power = sim.DEFAULT_POWER
+	}
+	return rate, power
+}
+
+//selected returns the name of the state machine command selected
+func (c *Console) selected() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return emulator.Commands()[c.command]
+}
+
+//selectedCentral returns the ID of the central selected, "" when none is connected
+func (c *Console) selectedCentral() string {
+	centrals := c.em.Centrals()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case connected(centrals, c.central):
+		return c.central
+	case len(centrals) > 0:
+		return centrals[0].ID
+	}
+	return ""
+}
+
+//nextCentral selects the central connected after the one selected and
+//returns its ID, "" when none is connected
+func (c *Console) nextCentral() string {
+	centrals := c.em.Centrals()
+	selected := c.selectedCentral()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.central = ""
+	for i, central := range centrals {
+		if central.ID == selected {
+			c.central = centrals[(i+1)%len(centrals)].ID
+		}
+	}
+	return c.central
+}
+
+//connected returns whether the central id is one of centrals
+func connected(centrals []emulator.Central, id string) bool {
+	for _, central := range centrals {
+		if central.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+//draw redraws the console
+func (c *Console) draw() {
+	c.mu.Lock()
+	screen := c.render()
+	c.mu.Unlock()
+	fmt.Fprint(c.out, "\x1b[H\x1b[2J"+screen)
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,206 @@
+package console
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"pm5-emulator/capture"
+	"pm5-emulator/clock"
+	"pm5-emulator/config"
+	"pm5-emulator/emulator"
+	"pm5-emulator/events"
+	"pm5-emulator/sim"
+	"pm5-emulator/transport"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestConsole(t *testing.T, in string) (*Console, *emulator.Emulator, *bytes.Buffer) {
+	c := clock.NewManual(time.Date(2020, time.January, 1, 8, 0, 0, 0, time.UTC))
+	em, err := emulator.NewOfflineEmulator(emulator.Config{Clock: c, Seed: 1})
+	assert.NoError(t, err)
+	var out bytes.Buffer
+	return New(em, strings.NewReader(in), &out), em, &out
+}
+
+func TestReadKeys(t *testing.T) {
+	keys := make(chan Key)
+	go readKeys(strings.NewReader("p\x1b[A\x1b[D\r\x03\x1bx\x1b"), keys, nil)
+	var got []Key
+	for k := range keys {
+		got = append(got, k)
+	}
+	// a lone escape is the escape key
+	assert.Equal(t, []Key{'p', KEY_UP, KEY_LEFT, KEY_ENTER, KEY_INTERRUPT, KEY_ESCAPE, 'x', KEY_ESCAPE}, got)
+
+	// the keys stop being read once stopped
+	stop := make(chan struct{})
+	close(stop)
+	keys = make(chan Key)
+	readKeys(strings.NewReader("abc"), keys, stop)
+	_, ok := <-keys
+	assert.False(t, ok)
+}
+
+func TestTerminalReader(t *testing.T) {
+	r, w, err := os.Pipe()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer r.Close()
+	stop := make(chan struct{})
+	tr := terminalReader{f: r, stop: stop}
+
+	_, _ = w.Write([]byte("s"))
+	w.Close()
+	p := make([]byte, 4)
+	n, err := tr.Read(p)
+	assert.NoError(t, err)
+	assert.Equal(t, "s", string(p[:n]))
+
+	// nothing read is waited for until stopped
+	time.AfterFunc(10*time.Millisecond, func() { close(stop) })
+	n, err = tr.Read(p)
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestConsole_Press(t *testing.T) {
+	c, em, _ := newTestConsole(t, "")
+	tests := []struct {
+		name  string
+		key   Key
+		state string
+		rate  int
+		power int
+	}{
+		{"Pause Not Rowing", 'p', config.PM5_STATE_READY, 0, 0},
+		{"Start", 's', config.PM5_STATE_INUSE, 0, 0},
+		{"Raise Rate", KEY_UP, config.PM5_STATE_INUSE, sim.DEFAULT_STROKE_RATE + 1, sim.DEFAULT_POWER},
+		{"Lower Rate", '-', config.PM5_STATE_INUSE, sim.DEFAULT_STROKE_RATE, sim.DEFAULT_POWER},
+		{"Raise Power", KEY_RIGHT, config.PM5_STATE_INUSE, sim.DEFAULT_STROKE_RATE, sim.DEFAULT_POWER + POWER_STEP},
+		{"Lower Power", '[', config.PM5_STATE_INUSE, sim.DEFAULT_STROKE_RATE, sim.DEFAULT_POWER},
+		{"Pause", 'p', config.PM5_STATE_PAUSED, sim.DEFAULT_STROKE_RATE, sim.DEFAULT_POWER},
+		{"Resume", 'p', config.PM5_STATE_INUSE, sim.DEFAULT_STROKE_RATE, sim.DEFAULT_POWER},
+		{"Auto", '0', config.PM5_STATE_INUSE, 0, 0},
+		{"End", 'e', config.PM5_STATE_FINISHED, 0, 0},
+		// badid is the first command, goidle comes after gohaveid and gofinished
+		{"Select", 'c', config.PM5_STATE_FINISHED, 0, 0},
+		{"Select Again", 'c', config.PM5_STATE_FINISHED, 0, 0},
+		{"Select Goidle", 'c', config.PM5_STATE_FINISHED, 0, 0},
+		{"Send", KEY_ENTER, config.PM5_STATE_IDLE, 0, 0},
+		{"Menu", 'm', config.PM5_STATE_READY, 0, 0},
+		{"Unknown Key", 'z', config.PM5_STATE_READY, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c.press(tt.key)
+			assert.Equal(t, tt.state, em.State(), c.message)
+			rate, power := em.Target()
+			assert.Equal(t, tt.rate, rate)
+			assert.Equal(t, tt.power, power)
+		})
+	}
+	assert.Equal(t, "back to the main menu", c.message)
+
+	c.press('p')
+	assert.True(t, strings.HasPrefix(c.message, "error: "), c.message)
+	c.press('d')
+	assert.Equal(t, "no central to disconnect", c.message)
+}
+
+func TestConsole_Render(t *testing.T) {
+	c, em, _ := newTestConsole(t, "")
+	central := transport.NewLoopback().Connect("c0:ff:ee:00:00:01", 185)
+	em.CentralConnected(central)
+	status := capture.Byte(0x81)
+	for i := 0; i < RECENT+2; i++ {
+		c.observe(events.Event{Type: events.EVENT_CSAFE, Central: "c0:ff:ee:00:00:01",
+			Request:  &capture.Frame{Commands: []capture.Command{{ID: 0x7F, Data: capture.Bytes{0xA0}, Nested: []capture.Command{{ID: 0xA0}}}}},
+			Response: &capture.Frame{Status: &status, Commands: []capture.Command{{ID: 0x7F}}}})
+	}
+	c.observe(events.Event{Type: capture.EVENT_NOTIFY, Char: "ce06003143e511e4916c0800200c9a66",
+		Data: capture.Bytes{0x01}, Fields: map[string]int{"Distance": 1234, "Drag_Factor": 120}})
+	c.observe(events.Event{Type: capture.EVENT_NOTIFY, Char: "ce06003d43e511e4916c0800200c9a66", Data: capture.Bytes{0x29, 0x01}})
+	assert.Len(t, c.csafe, RECENT)
+
+	screen := c.render()
+	assert.Contains(t, screen, "state READY")
+	assert.Contains(t, screen, "CENTRALS (1)\r\n> c0:ff:ee:00:00:01  MTU 185")
+	assert.Contains(t, screen, "0031 general status")
+	assert.Contains(t, screen, "Distance=1234 Drag_Factor=120")
+	assert.Contains(t, screen, "003d force curve           2901")
+	assert.Contains(t, screen, "0x7f[0xa0] -> status 0x81 0x7f")
+	assert.NotContains(t, strings.Replace(screen, "\r\n", "", -1), "\n")
+
+	assert.NoError(t, em.Disconnect("c0:ff:ee:00:00:01"))
+	assert.Error(t, em.Disconnect("c0:ff:ee:00:00:02"))
+}
+
+func TestConsole_Disconnect(t *testing.T) {
+	c, em, _ := newTestConsole(t, "")
+	loopback := transport.NewLoopback()
+	centrals := map[string]*transport.Central{}
+	for _, id := range []string{"c0:ff:ee:00:00:01", "c0:ff:ee:00:00:02", "c0:ff:ee:00:00:03"} {
+		centrals[id] = loopback.Connect(id, 185)
+		em.CentralConnected(centrals[id])
+	}
+	// the device reports the central disconnected
+	disconnect := func(msg string) {
+		c.press('d')
+		assert.Equal(t, "disconnected "+msg, c.message)
+		em.CentralDisconnected(centrals[msg])
+	}
+
+	// the first central is selected until another one is
+	assert.Contains(t, c.render(), "> c0:ff:ee:00:00:01")
+	c.press('n')
+	c.press('n')
+	assert.Equal(t, "central c0:ff:ee:00:00:03 selected, d to disconnect", c.message)
+	screen := c.render()
+	assert.Contains(t, screen, "\n  c0:ff:ee:00:00:01  MTU 185")
+	assert.Contains(t, screen, "\n> c0:ff:ee:00:00:03  MTU 185")
+
+	disconnect("c0:ff:ee:00:00:03")
+	assert.Len(t, em.Centrals(), 2)
+	disconnect("c0:ff:ee:00:00:01")
+	c.press('n')
+	assert.Equal(t, "central c0:ff:ee:00:00:02 selected, d to disconnect", c.message)
+	disconnect("c0:ff:ee:00:00:02")
+	assert.Empty(t, em.Centrals())
+
+	c.press('n')
+	assert.Equal(t, "no central to select", c.message)
+	c.press('d')
+	assert.Equal(t, "no central to disconnect", c.message)
+}
+
+func TestConsole_Run(t *testing.T) {
+	c, em, out := newTestConsole(t, "sq")
+	assert.NoError(t, c.Run())
+	assert.Equal(t, config.PM5_STATE_INUSE, em.State())
+	assert.Contains(t, out.String(), "PM5 EMULATOR")
+	// the screen is restored
+	assert.True(t, strings.HasSuffix(out.String(), "\x1b[?25h\x1b[?1049l"))
+
+	// a file which is not a terminal is read as it is
+	r, w, err := os.Pipe()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer r.Close()
+	_, _ = w.Write([]byte("eq"))
+	c = New(em, r, out)
+	assert.NoError(t, c.Run())
+	assert.Equal(t, config.PM5_STATE_FINISHED, em.State())
+	w.Close()
+}
+
+func TestClockTime(t *testing.T) {
+	assert.Equal(t, "0:00.0", clockTime(0))
+	assert.Equal(t, "1:59.9", clockTime(119949*time.Millisecond))
+	assert.Equal(t, "62:05.0", clockTime(time.Hour+2*time.Minute+5*time.Second))
+}
@@ -0,0 +1,71 @@
+package console
+
+import (
+	"bufio"
+	"io"
+)
+
+//Key is a key pressed on the terminal
+type Key int
+
+//Keys besides the printable characters, which are their own rune
+const (
+	KEY_UP Key = -(iota + 1)
+	KEY_DOWN
+	KEY_RIGHT
+	KEY_LEFT
+	KEY_ENTER
+	KEY_INTERRUPT // Ctrl-C
+	KEY_ESCAPE
+)
+
+//readKeys sends the keys read from r to keys until r ends or stop is closed,
+//then closes keys
+func readKeys(r io.Reader, keys chan<- Key, stop <-chan struct{}) {
+	defer close(keys)
+	send := func(k Key) bool {
+		select {
+		case keys <- k:
+			return true
+		case <-stop:
+			return false
+		}
+	}
+
+	br := bufio.NewReader(r)
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return
+		}
+		k := Key(b)
+		switch b {
+		case '\r', '\n':
+			k = KEY_ENTER
+		case 0x03:
+			k = KEY_INTERRUPT
+		case 0x1b:
+			// arrows are sent at once as ESC [ A to D, an escape followed by
+			// nothing already read is the escape key
+			k = KEY_ESCAPE
+			if br.Buffered() >= 2 {
+				next, _ := br.Peek(2)
+				if arrow, ok := arrows[next[1]]; ok && next[0] == '[' {
+					_, _ = br.Discard(2)
+					k = arrow
+				}
+			}
+		}
+		if !send(k) {
+			return
+		}
+	}
+}
+
+//arrows are the arrow keys by the final byte of their escape sequence
+var arrows = map[byte]Key{
+	'A': KEY_UP,
+	'B': KEY_DOWN,
+	'C': KEY_RIGHT,
+	'D': KEY_LEFT,
+}
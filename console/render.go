@@ -0,0 +1,159 @@
+package console
+
+import (
+	"fmt"
+	"pm5-emulator/capture"
+	"pm5-emulator/emulator"
+	"pm5-emulator/events"
+	"sort"
+	"strings"
+	"time"
+)
+
+//names are the names of the PM5 characteristics by their short identifier
+var names = map[string]string{
+	"0021": "csafe rx",
+	"0022": "csafe tx",
+	"0031": "general status",
+	"0032": "additional status 1",
+	"0033": "additional status 2",
+	"0034": "sample rate",
+	"0035": "stroke data",
+	"0036": "additional stroke data",
+	"0037": "split/interval data",
+	"0038": "additional split/interval",
+	"0039": "workout summary",
+	"003a": "additional summary",
+	"003b": "heart rate belt",
+	"003c": "additional summary 2",
+	"003d": "force curve",
+	"0080": "multiplexed",
+}
+
+//KEYS is the help line listing the keys of the console
+const KEYS = "↑/↓ rate  ←/→ power  0 auto  s start  p pause/resume  e end  m menu  n/d central  c/enter command  q quit"
+
+//render returns the screen of the console, the lock must be held
+func (c *Console) render() string {
+	var b strings.Builder
+	line := func(format string, args ...interface{}) {
+		fmt.Fprintf(&b, format+"\n", args...)
+	}
+
+	rate, power := c.em.Target()
+	target := "auto"
+	if rate != 0 || power != 0 {
+		target = fmt.Sprintf("%s s/m  %s W", orAuto(rate), orAuto(power))
+	}
+	line("PM5 EMULATOR   state %-9s persona %-8s target %s", c.em.State(), c.em.Persona(), target)
+	m := c.em.Metrics()
+	line("time %s  distance %.0fm  pace %s/500m  rate %d s/m  power %dW  calories %d  heart rate %d",
+		clockTime(m.ElapsedTime), m.Distance, clockTime(m.Pace()), m.StrokeRate, m.Power, m.Calories, m.HeartRate)
+	line("")
+
+	centrals := c.em.Centrals()
+	line("CENTRALS (%d)", len(centrals))
+	for i, central := range centrals {
+		mark := "  " // the central selected is marked
+		if central.ID == c.central || (i == 0 && !connected(centrals, c.central)) {
+			mark = "> "
+		}
+		line("%s%s  MTU %d  since %s", mark, central.ID, central.MTU, central.Connected.Format("15:04:05"))
+	}
+	line("")
+
+	line("CHARACTERISTICS")
+	var chars []string
+	for char := range c.chars {
+		chars = append(chars, char)
+	}
+	sort.Strings(chars)
+	for _, char := range chars {
+		e := c.chars[char]
+		line("  %-26s %s", name(char), fields(e))
+	}
+	line("")
+
+	line("CSAFE")
+	for _, e := range c.csafe {
+		line("  %s %s  %s -> %s", e.Time.Format("15:04:05"), e.Central, frame(e.Request), frame(e.Response))
+	}
+	line("")
+
+	line("command: %s", emulator.Commands()[c.command])
+	line("%s", c.message)
+	line("%s", KEYS)
+	// the terminal is in raw mode, lines need a carriage return
+	return strings.Replace(b.String(), "\n", "\r\n", -1)
+}
+
+//name returns the name of the characteristic char
+func name(char string) string {
+	if len(char) >= 8 {
+		if n, ok := names[char[4:8]]; ok {
+			return char[4:8] + " " + n
+		}
+		return char[4:8]
+	}
+	return char
+}
+
+//fields returns the decoded fields of the data of e, sorted by name, or the data itself
+func fields(e events.Event) string {
+	if len(e.Fields) == 0 {
+		return fmt.Sprintf("%x", []byte(e.Data))
+	}
+	var list []string
+	for field, v := range e.Fields {
+		list = append(list, fmt.Sprintf("%s=%d", field, v))
+	}
+	sort.Strings(list)
+	return strings.Join(list, " ")
+}
+
+//frame returns the commands of a csafe frame
+func frame(f *capture.Frame) string {
+	if f == nil {
+		return "-"
+	}
+	if f.Error != "" {
+		return "error " + f.Error
+	}
+	var parts []string
+	if f.Status != nil {
+		parts = append(parts, fmt.Sprintf("status 0x%02x", byte(*f.Status)))
+	}
+	for _, cmd := range f.Commands {
+		parts = append(parts, command(cmd))
+	}
+	return strings.Join(parts, " ")
+}
+
+//command returns a csafe command with its data, and its nested commands
+func command(cmd capture.Command) string {
+	s := fmt.Sprintf("0x%02x", byte(cmd.ID))
+	if len(cmd.Nested) > 0 {
+		var nested []string
+		for _, n := range cmd.Nested {
+			nested = append(nested, command(n))
+		}
+		return s + "[" + strings.Join(nested, " ") + "]"
+	}
+	if len(cmd.Data) > 0 {
+		s += fmt.Sprintf("(%x)", []byte(cmd.Data))
+	}
+	return s
+}
+
+//clockTime returns d as minutes:seconds.tenths
+func clockTime(d time.Duration) string {
+	d = d.Round(100 * time.Millisecond)
+	return fmt.Sprintf("%d:%04.1f", int(d.Minutes()), (d % time.Minute).Seconds())
+}
+
+func orAuto(v int) string {
+	if v == 0 {
+		return "auto"
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,34 @@
+// +build linux
+
+package console
+
+import (
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+//makeRaw puts the terminal fd in raw mode, keys are read as they are pressed
+//without being echoed, and returns the function restoring its mode. A read
+//of the terminal returns nothing once no key was pressed for READ_TIMEOUT.
+//Nothing is done and a nil function is returned when fd is not a terminal.
+func makeRaw(fd int) (func(), error) {
+	old, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err == unix.ENOTTY {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	raw := *old
+	raw.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	raw.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	raw.Cflag &^= unix.CSIZE | unix.PARENB
+	raw.Cflag |= unix.CS8
+	raw.Cc[unix.VMIN] = 0
+	raw.Cc[unix.VTIME] = uint8(READ_TIMEOUT / (100 * time.Millisecond))
+	if err := unix.IoctlSetTermios(fd, unix.TCSETS, &raw); err != nil {
+		return nil, err
+	}
+	return func() { _ = unix.IoctlSetTermios(fd, unix.TCSETS, old) }, nil
+}
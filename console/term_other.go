@@ -0,0 +1,9 @@
+// +build !linux
+
+package console
+
+//makeRaw does nothing, raw mode is only supported on linux where the emulator
+//runs: the keys are read once enter is pressed
+func makeRaw(fd int) (func(), error) {
+	return nil, nil
+}
@@ -0,0 +1,374 @@
+package dashboard
+
+//indexHTML is the page of the dashboard
+const indexHTML = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>PM5 Emulator</title>
+<link rel="stylesheet" href="dashboard.css">
+</head>
+<body>
+<div class="monitor">
+  <div class="bezel">
+    <div class="buttons left">
+      <button data-key="menu" title="Back to the main menu, ending the workout">Menu</button>
+      <button data-key="units" title="Show the pace as /500m, watts or cal/hr">Units</button>
+    </div>
+    <div class="lcd">
+      <div class="status">
+        <span id="state">-</span>
+        <span id="persona"></span>
+        <span id="link">no central</span>
+      </div>
+
+      <div class="screen" id="screen-menu">
+        <div class="title">Main Menu</div>
+        <button class="soft" data-key="just_row">Just Row</button>
+        <div class="hint">or program a workout from the app</div>
+      </div>
+
+      <div class="screen" id="screen-all">
+        <div class="row big"><span id="elapsed">0:00.0</span><span class="unit">time</span></div>
+        <div class="row big"><span id="pace">-:--.-</span><span class="unit" id="pace-unit">/500m</span></div>
+        <div class="row"><span id="stroke-rate">0</span><span class="unit">s/m</span></div>
+        <div class="row"><span id="distance">0</span><span class="unit">meters</span></div>
+        <div class="row"><span id="power">0</span><span class="unit">watts</span></div>
+        <div class="row"><span id="heart-rate">---</span><span class="unit">bpm</span></div>
+      </div>
+
+      <div class="screen" id="screen-force">
+        <div class="row"><span id="force-pace">-:--.-</span><span class="unit" id="force-pace-unit">/500m</span></div>
+        <canvas id="force" width="320" height="160"></canvas>
+        <div class="row small"><span id="force-peak">0</span><span class="unit">peak lbs</span></div>
+      </div>
+
+      <div class="screen" id="screen-pace">
+        <div class="row huge"><span id="large-pace">-:--.-</span></div>
+        <div class="row"><span class="unit" id="large-pace-unit">/500m</span></div>
+      </div>
+
+      <div class="screen" id="screen-connect">
+        <div class="title">Connect</div>
+        <ul id="centrals"></ul>
+        <div class="hint">Advertising as PM5, press Connect to go back</div>
+      </div>
+
+      <div class="error" id="error"></div>
+    </div>
+    <div class="buttons right">
+      <button data-key="display" title="Change the data shown">Change Display</button>
+      <button data-key="connect" title="Show the connected centrals">Connect</button>
+    </div>
+  </div>
+</div>
+<script src="dashboard.js"></script>
+</body>
+</html>
+`
+
+//dashboardCSS styles the dashboard after the PM5 monitor
+const dashboardCSS = `body {
+  margin: 0;
+  min-height: 100vh;
+  display: flex;
+  align-items: center;
+  justify-content: center;
+  background: #2b2f33;
+  font-family: "Helvetica Neue", Arial, sans-serif;
+}
+.monitor {
+  background: #111;
+  border-radius: 24px;
+  padding: 24px;
+  box-shadow: 0 12px 40px rgba(0, 0, 0, 0.6);
+}
+.bezel {
+  display: flex;
+  gap: 16px;
+  align-items: stretch;
+}
+.buttons {
+  display: flex;
+  flex-direction: column;
+  justify-content: space-around;
+  gap: 16px;
+}
+.buttons button {
+  width: 96px;
+  height: 48px;
+  border: none;
+  border-radius: 24px;
+  background: #444;
+  color: #eee;
+  font-size: 13px;
+  cursor: pointer;
+}
+.buttons button:active, .soft:active {
+  background: #666;
+}
+.lcd {
+  width: 360px;
+  min-height: 380px;
+  padding: 12px 16px;
+  background: #b8c4a8;
+  color: #1c2118;
+  border-radius: 6px;
+  box-shadow: inset 0 0 12px rgba(0, 0, 0, 0.4);
+  font-family: "DejaVu Sans Mono", "Courier New", monospace;
+  position: relative;
+}
+.status {
+  display: flex;
+  justify-content: space-between;
+  font-size: 12px;
+  border-bottom: 1px solid #1c2118;
+  padding-bottom: 4px;
+  margin-bottom: 8px;
+}
+.screen {
+  display: none;
+}
+.screen.active {
+  display: block;
+}
+.row {
+  display: flex;
+  justify-content: space-between;
+  align-items: baseline;
+  border-bottom: 1px dotted #5b6650;
+  padding: 4px 0;
+  font-size: 28px;
+}
+.row.big {
+  font-size: 44px;
+}
+.row.huge {
+  font-size: 72px;
+  justify-content: center;
+  border: none;
+  padding-top: 60px;
+}
+.row.small {
+  font-size: 18px;
+}
+.unit {
+  font-size: 13px;
+}
+.title {
+  font-size: 22px;
+  margin: 12px 0;
+}
+.soft {
+  display: block;
+  width: 100%;
+  margin: 8px 0;
+  padding: 10px;
+  font-size: 20px;
+  background: #1c2118;
+  color: #b8c4a8;
+  border: none;
+  border-radius: 4px;
+  cursor: pointer;
+}
+.hint {
+  font-size: 12px;
+  margin-top: 12px;
+}
+#force {
+  width: 100%;
+  margin-top: 8px;
+  background: #aab79a;
+}
+#centrals {
+  padding-left: 16px;
+  font-size: 14px;
+}
+.error {
+  position: absolute;
+  left: 16px;
+  right: 16px;
+  bottom: 8px;
+  font-size: 12px;
+  color: #7a1010;
+}
+`
+
+//dashboardJS polls the state of the emulator and wires the buttons to the control API
+const dashboardJS = `(function () {
+  "use strict";
+
+  var POLL = 500; // ms between reads of the state
+  var UNITS = ["/500m", "watts", "cal/hr"];
+  var DISPLAYS = ["all", "force", "pace"];
+
+  var units = 0;
+  var display = 0;
+  var connect = false;
+  var state = null;
+
+  function $(id) {
+    return document.getElementById(id);
+  }
+
+  function time(seconds, tenths) {
+    var m = Math.floor(seconds / 60);
+    var s = seconds - m * 60;
+    var h = Math.floor(m / 60);
+    m -= h * 60;
+    var text = (h > 0 ? h + ":" + (m < 10 ? "0" : "") : "") + m + ":" + (s < 10 ? "0" : "");
+    return text + (tenths ? s.toFixed(1) : Math.floor(s));
+  }
+
+  // pace shown in the units picked with the Units button
+  function pace(metrics) {
+    if (metrics.pace <= 0) {
+      return units === 0 ? "-:--.-" : "0";
+    }
+    switch (units) {
+      case 1:
+        return String(metrics.power);
+      case 2:
+        // Concept2 calories per hour, with the basal burn of a 175 lb user
+        return String(Math.round(4 * metrics.power * 0.8604 + 300));
+      default:
+        return time(metrics.pace, true);
+    }
+  }
+
+  function drawForce(curve) {
+    var canvas = $("force");
+    var ctx = canvas.getContext("2d");
+    ctx.clearRect(0, 0, canvas.width, canvas.height);
+    var peak = 0;
+    (curve || []).forEach(function (f) {
+      peak = Math.max(peak, f);
+    });
+    $("force-peak").textContent = Math.round(peak);
+    if (!curve || curve.length === 0) {
+      return;
+    }
+    var scale = Math.max(peak * 1.2, 100);
+    ctx.strokeStyle = "#1c2118";
+    ctx.fillStyle = "rgba(28, 33, 24, 0.25)";
+    ctx.lineWidth = 2;
+    ctx.beginPath();
+    ctx.moveTo(0, canvas.height);
+    curve.forEach(function (f, i) {
+      var x = (i + 0.5) * canvas.width / curve.length;
+      ctx.lineTo(x, canvas.height - f / scale * canvas.height);
+    });
+    ctx.lineTo(canvas.width, canvas.height);
+    ctx.fill();
+    ctx.stroke();
+  }
+
+  function show(screen) {
+    ["menu", "all", "force", "pace", "connect"].forEach(function (name) {
+      $("screen-" + name).classList.toggle("active", name === screen);
+    });
+  }
+
+  function render() {
+    if (!state) {
+      return;
+    }
+    var m = state.metrics;
+    $("state").textContent = state.state;
+    $("persona").textContent = state.persona;
+    var n = state.centrals.length;
+    $("link").textContent = n === 0 ? "no central" : n + (n === 1 ? " central" : " centrals");
+
+    $("elapsed").textContent = time(m.elapsed, true);
+    $("distance").textContent = Math.floor(m.distance);
+    $("stroke-rate").textContent = m.stroke_rate;
+    $("power").textContent = m.power;
+    $("heart-rate").textContent = m.heart_rate > 0 ? m.heart_rate : "---";
+    ["pace", "force-pace", "large-pace"].forEach(function (id) {
+      $(id).textContent = pace(m);
+      $(id + "-unit").textContent = UNITS[units];
+    });
+    drawForce(state.force_curve);
+
+    var list = $("centrals");
+    list.innerHTML = "";
+    state.centrals.forEach(function (c) {
+      var item = document.createElement("li");
+      item.textContent = c.id + " (MTU " + c.mtu + ")";
+      list.appendChild(item);
+    });
+    if (n === 0) {
+      list.innerHTML = "<li>none connected</li>";
+    }
+
+    if (connect) {
+      show("connect");
+    } else if (state.state === "READY") {
+      show("menu");
+    } else {
+      show(DISPLAYS[display]);
+    }
+  }
+
+  function report(err) {
+    $("error").textContent = err ? String(err) : "";
+  }
+
+  function request(method, path, body) {
+    return fetch("api/" + path, {
+      method: method,
+      headers: body ? {"Content-Type": "application/json"} : {},
+      body: body ? JSON.stringify(body) : undefined
+    }).then(function (rsp) {
+      return rsp.json().then(function (data) {
+        if (!rsp.ok) {
+          throw new Error(data.error || rsp.statusText);
+        }
+        return data;
+      });
+    });
+  }
+
+  function poll() {
+    request("GET", "state").then(function (s) {
+      state = s;
+      report(null);
+      render();
+    }).catch(report).then(function () {
+      setTimeout(poll, POLL);
+    });
+  }
+
+  function press(key) {
+    switch (key) {
+      case "units":
+        units = (units + 1) % UNITS.length;
+        render();
+        return;
+      case "display":
+        display = (display + 1) % DISPLAYS.length;
+        render();
+        return;
+      case "connect":
+        connect = !connect;
+        render();
+        return;
+    }
+    // menu and just row are buttons of the emulated PM
+    connect = false;
+    request("POST", "button", {button: key}).then(function (s) {
+      state = s;
+      report(null);
+      render();
+    }).catch(report);
+  }
+
+  document.querySelectorAll("button[data-key]").forEach(function (b) {
+    b.addEventListener("click", function () {
+      press(b.getAttribute("data-key"));
+    });
+  });
+  poll();
+})();
+`
@@ -0,0 +1,41 @@
+package dashboard
+
+import (
+	"net/http"
+)
+
+//asset is a file of the dashboard, compiled into the binary
+type asset struct {
+	contentType string
+	body        string
+}
+
+//assets are the files of the dashboard, by path
+var assets = map[string]asset{
+	"/":              {"text/html; charset=utf-8", indexHTML},
+	"/dashboard.css": {"text/css; charset=utf-8", dashboardCSS},
+	"/dashboard.js":  {"application/javascript; charset=utf-8", dashboardJS},
+}
+
+//Handler returns the handler serving the dashboard, a web page showing the
+//emulated PM5 monitor. The page reads the state of the emulator from the
+//control API, which must be served by the same server.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a, ok := assets[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", a.contentType)
+		w.Header().Set("Cache-Control", "no-cache")
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(a.body))
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package dashboard
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		status      int
+		contentType string
+	}{
+		{"Page", http.MethodGet, "/", http.StatusOK, "text/html; charset=utf-8"},
+		{"Style", http.MethodGet, "/dashboard.css", http.StatusOK, "text/css; charset=utf-8"},
+		{"Script", http.MethodGet, "/dashboard.js", http.StatusOK, "application/javascript; charset=utf-8"},
+		{"Head", http.MethodHead, "/", http.StatusOK, "text/html; charset=utf-8"},
+		{"Unknown", http.MethodGet, "/index.php", http.StatusNotFound, "text/plain; charset=utf-8"},
+		{"Post", http.MethodPost, "/", http.StatusMethodNotAllowed, "text/plain; charset=utf-8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			Handler().ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.contentType, w.Header().Get("Content-Type"))
+		})
+	}
+}
+
+func TestAssets(t *testing.T) {
+	// the page loads the other assets and has the PM5 soft buttons
+	for path := range assets {
+		if path != "/" {
+			assert.Contains(t, indexHTML, `"`+strings.TrimPrefix(path, "/")+`"`)
+		}
+	}
+	for _, key := range []string{"menu", "units", "display", "connect", "just_row"} {
+		assert.Contains(t, indexHTML, `data-key="`+key+`"`)
+	}
+	// the ids the script fills in are on the page
+	for _, id := range []string{"state", "elapsed", "distance", "pace", "stroke-rate", "power", "heart-rate", "force", "centrals"} {
+		assert.Contains(t, indexHTML, `id="`+id+`"`)
+	}
+}
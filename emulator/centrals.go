@@ -0,0 +1,81 @@
+package emulator
+
+import (
+	"fmt"
+	"pm5-emulator/capture"
+	"sort"
+	"time"
+
+	"github.com/bettercap/gatt"
+)
+
+//Central is a central connected to the emulated PM
+type Central struct {
+	ID        string    `json:"id"`
+	MTU       int       `json:"mtu"`
+	Connected time.Time `json:"connected"`
+}
+
+//CentralConnected keeps track of the central c connecting, opens its session
+//and records it
+func (em *Emulator) CentralConnected(c gatt.Central) {
+	em.mu.Lock()
+	em.centrals[c.ID()] = Central{ID: c.ID(), MTU: c.MTU(), Connected: em.timebase.Now()}
+	em.conns[c.ID()] = c
+	em.mu.Unlock()
+	em.sessions.Open(c)
+	em.RecordCentral(capture.EVENT_CONNECT, c)
+}
+
+//CentralDisconnected keeps track of the central c disconnecting, and records
+//it once, the centrals dropped with the adapter are reported again by the device
+func (em *Emulator) CentralDisconnected(c gatt.Central) {
+	em.mu.Lock()
+	_, ok := em.conns[c.ID()]
+	delete(em.centrals, c.ID())
+	delete(em.conns, c.ID())
+	em.mu.Unlock()
+	if ok {
+		em.sessions.Close(c.ID())
+		em.RecordCentral(capture.EVENT_DISCONNECT, c)
+	}
+}
+
+//connections returns the connections of the connected centrals
+func (em *Emulator) connections() []gatt.Central {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+	list := make([]gatt.Central, 0, len(em.conns))
+	for _, c := range em.conns {
+		list = append(list, c)
+	}
+	return list
+}
+
+//Disconnect drops the connection of the central id
+func (em *Emulator) Disconnect(id string) error {
+	em.mu.Lock()
+	c, ok := em.conns[id]
+	em.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("central %s is not connected", id)
+	}
+	return c.Close()
+}
+
+//Centrals returns the centrals connected to the emulated PM, in the order they connected
+func (em *Emulator) Centrals() []Central {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+	list := make([]Central, 0, len(em.centrals))
+	for _, c := range em.centrals {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Connected.Equal(list[j].Connected) {
+			return list[i].ID < list[j].ID
+		}
+		return list[i].Connected.Before(list[j].Connected)
+	})
+	return list
+}
@@ -0,0 +1,150 @@
+package emulator
+
+import (
+	"errors"
+	"fmt"
+	"pm5-emulator/config"
+	"pm5-emulator/protocol"
+	"pm5-emulator/random"
+	"pm5-emulator/workout"
+	"sort"
+)
+
+//Buttons of the PM that can be pressed with PressButton
+const (
+	BUTTON_MENU     = "menu"     // back to the main menu, ending the workout
+	BUTTON_JUST_ROW = "just_row" // start rowing without programming a workout
+)
+
+//errReplaying refuses to steer the athlete while a recorded workout is replayed
+var errReplaying = errors.New("a recorded workout is being replayed")
+
+//commands are the state machine commands that can be sent by name
+var commands = map[string]byte{
+	"reset":      config.CSAFE_RESET_CMD,
+	"goidle":     config.CSAFE_GOIDLE_CMD,
+	"gohaveid":   config.CSAFE_GOHAVEID_CMD,
+	"goinuse":    config.CSAFE_GOINUSE_CMD,
+	"gofinished": config.CSAFE_GOFINISHED_CMD,
+	"goready":    config.CSAFE_GOREADY_CMD,
+	"badid":      config.CSAFE_BADID_CMD,
+}
+
+//Commands returns the names of the state machine commands, sorted
+func Commands() []string {
+	var list []string
+	for name := range commands {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
+
+//LookupCommand returns the state machine command named name, one of Commands
+func LookupCommand(name string) (byte, error) {
+	cmd, ok := commands[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown command %q", name)
+	}
+	return cmd, nil
+}
+
+//Start starts the simulation of the athlete rowing the programmed workout,
+//or the replay of the recorded workout
+func (em *Emulator) Start() {
+	if em.player != nil {
+		em.player.Start()
+		return
+	}
+	em.rower.Start()
+}
+
+//Connect opens a protocol session for a central connected in process
+func (em *Emulator) Connect() protocol.Protocol {
+	return em.protocol(em.handler)
+}
+
+//State returns the name of the current state of the emulated PM
+func (em *Emulator) State() string {
+	return em.stateMachine.GetStateName()
+}
+
+//Metrics returns the live values of the workout
+func (em *Emulator) Metrics() workout.Metrics {
+	return em.workout.Metrics()
+}
+
+//ForceCurve returns the force on the handle during the drive of the last
+//stroke, in pounds-force, nil before the first stroke
+func (em *Emulator) ForceCurve() []float64 {
+	curve, _ := em.workout.ForceCurve()
+	return curve
+}
+
+//Logged returns the number of workouts in the logbook
+func (em *Emulator) Logged() int {
+	return em.logbook.Count()
+}
+
+//SetTarget asks the athlete to row at rate strokes per minute and power
+//watts, 0 leaves them to the persona and the workout. A replay can't be
+//steered and is left as recorded.
+func (em *Emulator) SetTarget(rate, power int) error {
+	if em.player != nil {
+		return errReplaying
+	}
+	em.rower.SetTarget(rate, power)
+	return nil
+}
+
+//StopRowing stops the athlete, or the replay, pausing the workout
+func (em *Emulator) StopRowing() error {
+	if em.player != nil {
+		return em.player.Pause()
+	}
+	return em.rower.Pause()
+}
+
+//ResumeRowing has the athlete row again, or the replay play again, after StopRowing
+func (em *Emulator) ResumeRowing() error {
+	if em.player != nil {
+		return em.player.Resume()
+	}
+	return em.rower.Resume()
+}
+
+//Target returns the stroke rate and power the athlete was asked to row at,
+//0 when left to the persona and the workout
+func (em *Emulator) Target() (rate, power int) {
+	return em.rower.Target()
+}
+
+//Goal returns the goal of the programmed workout
+func (em *Emulator) Goal() workout.Goal {
+	return em.workout.Goal()
+}
+
+//Command sends a state machine command, such as config.CSAFE_GOIDLE_CMD, to the emulated PM
+func (em *Emulator) Command(cmd byte) error {
+	return em.stateMachine.Update(cmd)
+}
+
+//PressButton presses a button of the PM
+func (em *Emulator) PressButton(name string) error {
+	switch name {
+	case BUTTON_MENU:
+		em.stateMachine.Reset()
+		return nil
+	case BUTTON_JUST_ROW:
+		if !em.stateMachine.IsReady() {
+			return fmt.Errorf("%s can only be pressed in READY state", name)
+		}
+		return em.stateMachine.Update(config.CSAFE_GOINUSE_CMD)
+	}
+	return fmt.Errorf("unknown button %q", name)
+}
+
+//Rand returns the random source of the session
+func (em *Emulator) Rand() *random.Rand {
+	return em.rand
+}
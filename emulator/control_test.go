@@ -0,0 +1,72 @@
+package emulator
+
+import (
+	"pm5-emulator/clock"
+	"pm5-emulator/config"
+	"pm5-emulator/replay"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//newReplayEmulator returns an emulator replaying a minute of steady rowing,
+//rowing once started
+func newReplayEmulator(t *testing.T) (*Emulator, *clock.Manual) {
+	rec := &replay.Recording{}
+	for i := 1; i <= 30; i++ {
+		rec.Samples = append(rec.Samples, replay.Sample{Time: time.Duration(i) * 2 * time.Second, Distance: float64(10 * i), Power: 200, StrokeRate: 30})
+	}
+	c := clock.NewManual(time.Unix(0, 0))
+	em, err := NewOfflineEmulator(Config{Clock: c, Seed: 1, Replay: rec})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	em.Start()
+	c.Advance(time.Second)
+	assert.Equal(t, config.PM5_STATE_INUSE, em.State())
+	return em, c
+}
+
+func TestEmulator_StopRowingReplay(t *testing.T) {
+	em, c := newReplayEmulator(t)
+	defer em.Stop()
+
+	assert.NoError(t, em.StopRowing())
+	assert.Equal(t, config.PM5_STATE_PAUSED, em.State())
+	elapsed := em.Metrics().ElapsedTime
+	c.Advance(5 * time.Second)
+	assert.Equal(t, elapsed, em.Metrics().ElapsedTime)
+
+	assert.Error(t, em.StopRowing())
+}
+
+func TestEmulator_ResumeRowingReplay(t *testing.T) {
+	em, c := newReplayEmulator(t)
+	defer em.Stop()
+
+	assert.Error(t, em.ResumeRowing())
+	assert.NoError(t, em.StopRowing())
+	c.Advance(5 * time.Second)
+
+	assert.NoError(t, em.ResumeRowing())
+	assert.Equal(t, config.PM5_STATE_INUSE, em.State())
+	elapsed := em.Metrics().ElapsedTime
+	c.Advance(5 * time.Second)
+	assert.Equal(t, elapsed+5*time.Second, em.Metrics().ElapsedTime)
+}
+
+func TestEmulator_SetTargetReplay(t *testing.T) {
+	em, c := newReplayEmulator(t)
+	defer em.Stop()
+
+	assert.Error(t, em.SetTarget(20, 100))
+	rate, power := em.Target()
+	assert.Zero(t, rate)
+	assert.Zero(t, power)
+
+	// the replay rows as recorded
+	c.Advance(5 * time.Second)
+	assert.Equal(t, 200, em.Metrics().Power)
+	assert.Equal(t, 30, em.Metrics().StrokeRate)
+}
@@ -2,9 +2,25 @@ package emulator
 
 import (
 	"fmt"
+	"io"
+	"pm5-emulator/capture"
+	"pm5-emulator/clock"
+	"pm5-emulator/command"
 	"pm5-emulator/config"
+	"pm5-emulator/events"
+	"pm5-emulator/logbook"
+	"pm5-emulator/protocol"
+	"pm5-emulator/random"
+	"pm5-emulator/replay"
 	"pm5-emulator/service"
+	"pm5-emulator/service/decorator"
+	"pm5-emulator/sim"
 	"pm5-emulator/sm"
+	"pm5-emulator/user"
+	"pm5-emulator/workout"
+	"sync"
+	"time"
+
 	"github.com/sirupsen/logrus"
 	"github.com/bettercap/gatt"
 )
@@ -12,42 +28,66 @@ import (
 //Emulator emulates PM5 indoor rower machine
 type Emulator struct {
 	device       gatt.Device
+	identity     config.Device // identity the PM reports
 	stateMachine *sm.StateMachine
+	workout      *workout.Workout
+	handler      *command.Handler
+	protocol     protocol.Factory
+	rower        *sim.Rower
+	player       *replay.Player // plays a recorded workout in place of the rower, when set
+	user         *user.User
+	logbook      *logbook.Logbook
+	clock        *clock.PM
+	timebase     clock.Clock // time the emulator runs on
+	rand         *random.Rand
+	capture      capture.Sink      // records the GATT interactions
+	btsnoop      *capture.Btsnoop  // traces the GATT interactions, when set
+	events       *events.Hub       // broadcasts what the emulator does
+	sessions     *service.Sessions // sessions of the connected centrals
+	closers      []io.Closer       // files of the session, closed when it stops
+	failed       chan error        // receives the error the adapter fails with
+
+	mu       sync.Mutex
+	status   string                  // lifecycle status, one of the STATUS_* values
+	centrals map[string]Central      // connected centrals, by ID
+	conns    map[string]gatt.Central // connections of the centrals, by ID
 }
 
-//RunEmulator registers handlers and starts advertising services
-func (em *Emulator) RunEmulator() {
+//RunEmulator registers handlers and starts advertising services, again each
+//time the adapter powers on
+func (em *Emulator) RunEmulator() error {
 
 	//register optional handlers
 	em.registerHandlers()
 
-	// handler for monitoring config state.
-	onStateChanged := func(d gatt.Device, s gatt.State) {
-		fmt.Printf("State: %s\n", s)
-		switch s {
-		case gatt.StatePoweredOn:
-			// Setup GAP and GATT services for PM5
-			_ = d.AddService(service.NewGapService(config.NAME))
-			_ = d.AddService(service.NewGattService())
-
-			// Setup Device info service for PM5
-			s1 := service.NewDevInfoService()
-			d.AddService(s1)
+	// simulate the athlete rowing the programmed workout, or replay one
+	em.Start()
 
-			s2 := service.NewControlService()
-			d.AddService(s2)
-
-			s3 := service.NewRowingService()
-			d.AddService(s3)
-
-			// Advertise config name and service's UUIDs.
-			d.AdvertiseNameAndServices(config.NAME, []gatt.UUID{gatt.MustParseUUID("CE060000-43E5-11E4-916C-0800200C9A66")})
-
-		default:
-		}
+	// handler for monitoring config state.
+	if err := em.device.Init(em.onStateChanged); err != nil {
+		em.setStatus(STATUS_FAILED)
+		return fmt.Errorf("%w: %v", ErrAdapter, err)
 	}
+	return nil
+}
 
-	em.device.Init(onStateChanged)
+//Services returns the GATT services of the emulated PM, recording the
+//interactions with them
+func (em *Emulator) Services() []*gatt.Service {
+	services := []*gatt.Service{
+		service.NewGapService(em.identity.Name),
+		service.NewGattService(),
+		service.NewDevInfoService(em.identity),
+		service.NewControlService(em.sessions),
+		service.NewRowingService(em.workout, em.user, em.logbook, em.timebase, em.sessions),
+	}
+	for i, s := range services {
+		services[i] = em.record(s)
+	}
+	if em.btsnoop != nil {
+		em.btsnoop.SetServices(services)
+	}
+	return services
 }
 
 //registerHandlers registers optional handlers for handling device connection and disconnection
@@ -63,10 +103,59 @@ func (em *Emulator) registerHandlers() {
 		gatt.CentralConnected(func(c gatt.Central) {
 			logrus.Info("|Device Connected| ID=> ", c.ID())
 			logrus.Info("MTU: ", c.MTU())
+			em.CentralConnected(c)
 		}),
 		gatt.CentralDisconnected(func(c gatt.Central) {
 			logrus.Info("|Device Disconnected| ID=> ", c.ID())
 			logrus.Info("MTU: ", c.MTU())
+			em.CentralDisconnected(c)
 		}),
 	)
 }
+
+//record makes the interactions with the characteristics of s recorded to the
+//capture and broadcast as events
+func (em *Emulator) record(s *gatt.Service) *gatt.Service {
+	return decorator.Record(s, em.capture, em.timebase)
+}
+
+//RecordCentral records a central connecting or disconnecting
+func (em *Emulator) RecordCentral(event string, c gatt.Central) {
+	e := capture.Entry{Time: em.timebase.Now(), Event: event, Central: c.ID()}
+	if event == capture.EVENT_CONNECT {
+		e.MTU = c.MTU()
+	}
+	if err := em.capture.Record(e); err != nil {
+		logrus.Error("[[Capture]] ", err)
+	}
+}
+
+//Events returns the hub broadcasting what the emulator does
+func (em *Emulator) Events() *events.Hub {
+	return em.events
+}
+
+//EnterID enters the user ID on the emulated PM as if typed on its keypad
+func (em *Emulator) EnterID(id string) error {
+	return em.user.EnterID(id)
+}
+
+//Now returns the date and time of the emulated PM
+func (em *Emulator) Now() time.Time {
+	return em.clock.Now()
+}
+
+//Persona returns the name of the persona the simulated athlete rows as
+func (em *Emulator) Persona() string {
+	return em.rower.Persona().Name
+}
+
+//SetPersona changes the persona the simulated athlete rows as
+func (em *Emulator) SetPersona(name string) error {
+	p, err := sim.LookupPersona(name)
+	if err != nil {
+		return err
+	}
+	em.rower.SetPersona(p)
+	return nil
+}
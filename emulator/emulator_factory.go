@@ -1,22 +1,242 @@
 package emulator
 
 import (
-	"log"
-	"pm5-emulator/sm"
+	"fmt"
+	"io"
+	"pm5-emulator/capture"
+	"pm5-emulator/clock"
+	"pm5-emulator/command"
+	"pm5-emulator/config"
 	"pm5-emulator/config/option"
-
+	"pm5-emulator/events"
+	"pm5-emulator/logbook"
+	"pm5-emulator/protocol"
+	"pm5-emulator/protocol/csafe"
+	"pm5-emulator/random"
+	"pm5-emulator/replay"
+	"pm5-emulator/service"
+	"pm5-emulator/sim"
+	"pm5-emulator/sm"
+	"pm5-emulator/user"
+	"pm5-emulator/workout"
+	"time"
 
 	"github.com/bettercap/gatt"
+	"github.com/sirupsen/logrus"
 )
 
+//Config holds the settings of an emulator session
+type Config struct {
+	//Clock is the time the emulator runs on, the simulation, the state
+	//timeouts and the notifications all follow it
+	Clock clock.Clock
+
+	//Seed seeds the random data of the session, a session can be reproduced
+	//by running it again with the same seed
+	Seed int64
+
+	//Persona is the name of the persona the simulated athlete rows as, the
+	//default persona when empty
+	Persona string
+
+	//Logbook is the path of the logbook file, the logbook is kept in memory
+	//when empty
+	Logbook string
+
+	//Replay is a recorded workout played as the live data in place of the
+	//simulated athlete, when set
+	Replay *replay.Recording
+
+	//Capture is the path of the file every GATT interaction of the session
+	//is recorded to, nothing is recorded when empty
+	Capture string
+
+	//Btsnoop is the path of the btsnoop file the HCI packets of every GATT
+	//interaction of the session are traced to, for Wireshark, nothing is
+	//traced when empty
+	Btsnoop string
+
+	//Device is the identity the PM reports, config.DefaultDevice when its
+	//name is empty
+	Device config.Device
+
+	//Profile is the profile of the users that never set one, the default
+	//profile when nil
+	Profile *user.Profile
+
+	//HCI is the index of the HCI device the PM is emulated on, -1 for the
+	//first available
+	HCI int
+
+	//AdvertisingInterval is the interval the PM advertises at,
+	//option.DEFAULT_ADVERTISING_INTERVAL when zero
+	AdvertisingInterval time.Duration
+
+	//MaxCentrals is the number of centrals that can be connected at once,
+	//each with its own session, option.DEFAULT_MAX_CENTRALS when zero
+	MaxCentrals int
+}
+
+//DefaultConfig returns the settings of a session on the first HCI device,
+//on the real time, with a random seed and the logbook in its default file
+func DefaultConfig() Config {
+	return Config{
+		Clock:               clock.Real{},
+		Seed:                random.NewSeed(),
+		Logbook:             logbook.DefaultPath(),
+		Device:              config.DefaultDevice(),
+		HCI:                 option.DEFAULT_HCI,
+		AdvertisingInterval: option.DEFAULT_ADVERTISING_INTERVAL,
+		MaxCentrals:         option.DEFAULT_MAX_CENTRALS,
+	}
+}
+
 //NewEmulator factory methods initializes emulator
-func NewEmulator() *Emulator {
-	d, err := gatt.NewDevice(option.DefaultServerOptions...)
+func NewEmulator() (*Emulator, error) {
+	return NewEmulatorWithConfig(DefaultConfig())
+}
+
+//NewEmulatorWithConfig initializes an emulator with the settings of cfg, the
+//error wraps ErrAdapter when the Bluetooth adapter cannot be opened
+func NewEmulatorWithConfig(cfg Config) (*Emulator, error) {
+	interval := cfg.AdvertisingInterval
+	if interval == 0 {
+		interval = option.DEFAULT_ADVERTISING_INTERVAL
+	}
+	maxCentrals := cfg.MaxCentrals
+	if maxCentrals == 0 {
+		maxCentrals = option.DEFAULT_MAX_CENTRALS
+	}
+	em, err := NewOfflineEmulator(cfg)
 	if err != nil {
-		log.Fatalf("Failed to open config, err: %s", err)
+		return nil, err
 	}
-	return &Emulator{
-		device:       d,
-		stateMachine: sm.NewStateMachine(),
+	d, err := gatt.NewDevice(option.ServerOptions(cfg.HCI, interval, maxCentrals)...)
+	if err != nil {
+		_ = em.Stop()
+		return nil, fmt.Errorf("%w: %v", ErrAdapter, err)
 	}
+	em.device = d
+	em.setStatus(STATUS_STARTING)
+	return em, nil
+}
+
+//NewOfflineEmulator initializes an emulator without a Bluetooth device,
+//driven in process by scenarios and tests. The error tells the persona that
+//is unknown or the file of the session that cannot be opened.
+func NewOfflineEmulator(cfg Config) (*Emulator, error) {
+	tc := cfg.Clock
+	if tc == nil {
+		tc = clock.Real{}
+	}
+	logrus.Infof("Random seed: %d", cfg.Seed)
+	rnd := random.New(cfg.Seed)
+
+	stm := sm.NewStateMachine()
+	stm.SetClock(tc)
+	// Start the state machine from READY state
+	stm.Reset()
+
+	factory, err := protocol.Lookup(csafe.PROTOCOL_NAME)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Persona == "" {
+		cfg.Persona = sim.DEFAULT_PERSONA
+	}
+	persona, err := sim.LookupPersona(cfg.Persona)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Device.Name == "" {
+		cfg.Device = config.DefaultDevice()
+	}
+
+	w := workout.New()
+	u := user.New(stm)
+	if cfg.Profile != nil {
+		u.SetDefaultProfile(*cfg.Profile)
+	}
+	rower := sim.NewRower(stm, w, u, tc, rnd)
+	rower.SetPersona(persona)
+	var player *replay.Player
+	if cfg.Replay != nil {
+		player = replay.NewPlayer(cfg.Replay, stm, w, u, tc)
+	}
+	// the PM clock starts from the time of the clock
+	c := clock.NewPM(tc, time.Time{})
+
+	lb := logbook.New()
+	if cfg.Logbook != "" {
+		lb, err = logbook.Open(cfg.Logbook)
+		if err != nil {
+			return nil, fmt.Errorf("opening logbook: %w", err)
+		}
+	}
+	lb.Attach(stm, w, u, c)
+
+	// the events of the session are always broadcast, to whoever listens
+	hub := events.NewHub(tc)
+	hub.DecodeCSAFE(service.ControlCharacteristics())
+	stm.OnTransition(hub.Transition)
+	sinks := capture.Tee{hub}
+	var closers []io.Closer
+	// the files already created are closed when another cannot be
+	fail := func(err error) (*Emulator, error) {
+		for _, c := range closers {
+			_ = c.Close()
+		}
+		return nil, err
+	}
+	if cfg.Capture != "" {
+		rec, err := capture.Create(cfg.Capture, tc)
+		if err != nil {
+			return nil, fmt.Errorf("creating capture: %w", err)
+		}
+		closers = append(closers, rec)
+		rec.DecodeCSAFE(service.ControlCharacteristics())
+		logrus.Infof("Recording GATT interactions to %s", cfg.Capture)
+		err = rec.Record(capture.Entry{Event: capture.EVENT_START, Seed: cfg.Seed, Persona: cfg.Persona})
+		if err != nil {
+			return fail(fmt.Errorf("writing capture: %w", err))
+		}
+		sinks = append(sinks, rec)
+	}
+	var trace *capture.Btsnoop
+	if cfg.Btsnoop != "" {
+		trace, err = capture.CreateBtsnoop(cfg.Btsnoop)
+		if err != nil {
+			return fail(fmt.Errorf("creating btsnoop trace: %w", err))
+		}
+		logrus.Infof("Tracing GATT interactions to %s", cfg.Btsnoop)
+		sinks = append(sinks, trace)
+		closers = append(closers, trace)
+	}
+
+	h := command.NewHandler(stm, w, u, lb, c)
+	return &Emulator{
+		identity:     cfg.Device,
+		stateMachine: stm,
+		workout:      w,
+		handler:      h,
+		protocol:     factory,
+		rower:        rower,
+		player:       player,
+		user:         u,
+		logbook:      lb,
+		clock:        c,
+		timebase:     tc,
+		rand:         rnd,
+		capture:      sinks,
+		events:       hub,
+		btsnoop:      trace,
+		closers:      closers,
+		sessions:     service.NewSessions(factory, h),
+		failed:       make(chan error, 1),
+		status:       STATUS_OFFLINE,
+		centrals:     make(map[string]Central),
+		conns:        make(map[string]gatt.Central),
+	}, nil
 }
\ No newline at end of file
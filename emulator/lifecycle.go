@@ -0,0 +1,138 @@
+package emulator
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bettercap/gatt"
+	"github.com/sirupsen/logrus"
+)
+
+//ErrAdapter is wrapped by the errors of the Bluetooth adapter
+var ErrAdapter = errors.New("bluetooth adapter")
+
+//SERVICES_UUID is the UUID the PM advertises its services with
+const SERVICES_UUID = "CE060000-43E5-11E4-916C-0800200C9A66"
+
+//Lifecycle statuses of the emulator
+const (
+	STATUS_OFFLINE     = "offline"     // without Bluetooth device, driven in process
+	STATUS_STARTING    = "starting"    // waiting for the adapter to power on
+	STATUS_ADVERTISING = "advertising" // services registered and advertised
+	STATUS_ADAPTER_OFF = "adapter_off" // waiting for the adapter to power on again
+	STATUS_FAILED      = "failed"      // the adapter is unsupported or unauthorized
+	STATUS_STOPPING    = "stopping"
+	STATUS_STOPPED     = "stopped"
+)
+
+//Status returns the lifecycle status of the emulator
+func (em *Emulator) Status() string {
+	em.mu.Lock()
+	defer em.mu.Unlock()
+	return em.status
+}
+
+//setStatus changes the lifecycle status of the emulator and logs it
+func (em *Emulator) setStatus(status string) {
+	em.mu.Lock()
+	from := em.status
+	em.status = status
+	em.mu.Unlock()
+	if from != status {
+		logrus.Infof("Emulator %s -> %s", from, status)
+	}
+}
+
+//Failed returns the channel receiving the error the adapter fails with, the
+//emulator can not recover from it
+func (em *Emulator) Failed() <-chan error {
+	return em.failed
+}
+
+//fail reports the adapter failing with err
+func (em *Emulator) fail(err error) {
+	logrus.Error("[[Adapter]] ", err)
+	em.setStatus(STATUS_FAILED)
+	select {
+	case em.failed <- err:
+	default:
+	}
+}
+
+//onStateChanged registers the services and advertises them each time the
+//adapter powers on, and drops the centrals when it powers off
+func (em *Emulator) onStateChanged(d gatt.Device, s gatt.State) {
+	logrus.Info("Adapter state: ", s)
+	status := em.Status()
+	if status == STATUS_STOPPING || status == STATUS_STOPPED {
+		return
+	}
+	switch s {
+	case gatt.StatePoweredOn:
+		// Setup GAP, GATT, Device info, Control and Rowing services for PM5,
+		// replacing those of a previous power on
+		if err := d.SetServices(em.Services()); err != nil {
+			em.fail(fmt.Errorf("%w: registering services: %v", ErrAdapter, err))
+			return
+		}
+		// Advertise config name and service's UUIDs.
+		if err := d.AdvertiseNameAndServices(em.identity.Name, []gatt.UUID{gatt.MustParseUUID(SERVICES_UUID)}); err != nil {
+			em.fail(fmt.Errorf("%w: advertising: %v", ErrAdapter, err))
+			return
+		}
+		em.setStatus(STATUS_ADVERTISING)
+	case gatt.StatePoweredOff, gatt.StateResetting:
+		// the connections are lost with the adapter
+		for _, c := range em.connections() {
+			em.CentralDisconnected(c)
+		}
+		if status != STATUS_STARTING {
+			em.setStatus(STATUS_ADAPTER_OFF)
+		}
+	case gatt.StateUnsupported, gatt.StateUnauthorized:
+		em.fail(fmt.Errorf("%w: %s", ErrAdapter, s))
+	}
+}
+
+//Stop stops advertising, disconnects the centrals, which ends their
+//notifications, stops the simulation, closes the device and the files of the
+//session. The emulator can not be run again.
+func (em *Emulator) Stop() error {
+	// only the first of concurrent calls stops the emulator
+	em.mu.Lock()
+	from := em.status
+	if from == STATUS_STOPPING || from == STATUS_STOPPED {
+		em.mu.Unlock()
+		return nil
+	}
+	em.status = STATUS_STOPPING
+	em.mu.Unlock()
+	logrus.Infof("Emulator %s -> %s", from, STATUS_STOPPING)
+
+	var first error
+	keep := func(err error) {
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+	if em.device != nil {
+		keep(em.device.StopAdvertising())
+	}
+	for _, c := range em.connections() {
+		keep(c.Close())
+		em.CentralDisconnected(c)
+	}
+	if em.player != nil {
+		em.player.Stop()
+	} else {
+		em.rower.Stop()
+	}
+	if em.device != nil {
+		keep(em.device.Stop())
+	}
+	for _, c := range em.closers {
+		keep(c.Close())
+	}
+	em.setStatus(STATUS_STOPPED)
+	return first
+}
@@ -0,0 +1,161 @@
+package emulator
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"pm5-emulator/clock"
+	"pm5-emulator/transport"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bettercap/gatt"
+	"github.com/stretchr/testify/assert"
+)
+
+//fakeDevice is a gatt device keeping track of what the emulator does with it
+type fakeDevice struct {
+	gatt.Device
+	changed     func(gatt.Device, gatt.State)
+	services    []*gatt.Service
+	registered  int // times the services were registered
+	advertised  int // times the name was advertised
+	advertising bool
+	stopped     bool
+}
+
+func (d *fakeDevice) Handle(...gatt.Handler) {}
+
+func (d *fakeDevice) Init(f func(gatt.Device, gatt.State)) error {
+	d.changed = f
+	f(d, gatt.StatePoweredOn)
+	return nil
+}
+
+func (d *fakeDevice) SetServices(s []*gatt.Service) error {
+	d.services = s
+	d.registered++
+	return nil
+}
+
+func (d *fakeDevice) AdvertiseNameAndServices(name string, uu []gatt.UUID) error {
+	d.advertised++
+	d.advertising = true
+	return nil
+}
+
+func (d *fakeDevice) StopAdvertising() error {
+	d.advertising = false
+	return nil
+}
+
+func (d *fakeDevice) Stop() error {
+	d.stopped = true
+	d.changed(d, gatt.StatePoweredOff)
+	return nil
+}
+
+func TestEmulator_Lifecycle(t *testing.T) {
+	d := &fakeDevice{}
+	em, err := NewOfflineEmulator(Config{Clock: clock.NewManual(time.Unix(0, 0)), Seed: 1})
+	if !assert.NoError(t, err) {
+		return
+	}
+	em.device = d
+	em.setStatus(STATUS_STARTING)
+
+	assert.NoError(t, em.RunEmulator())
+	assert.Equal(t, STATUS_ADVERTISING, em.Status())
+	assert.Len(t, d.services, 5)
+	assert.Equal(t, 1, d.advertised)
+
+	em.CentralConnected(transport.NewLoopback().Connect("c0:ff:ee:00:00:01", 185))
+	assert.Equal(t, []string{"c0:ff:ee:00:00:01"}, em.sessions.IDs())
+	d.changed(d, gatt.StatePoweredOff)
+	assert.Equal(t, STATUS_ADAPTER_OFF, em.Status())
+	assert.Empty(t, em.Centrals())
+	assert.Empty(t, em.sessions.IDs())
+
+	// the services are registered again, not added to those registered before
+	d.changed(d, gatt.StatePoweredOn)
+	assert.Equal(t, STATUS_ADVERTISING, em.Status())
+	assert.Len(t, d.services, 5)
+	assert.Equal(t, 2, d.registered)
+	assert.Equal(t, 2, d.advertised)
+
+	em.CentralConnected(transport.NewLoopback().Connect("c0:ff:ee:00:00:02", 185))
+	assert.NoError(t, em.Stop())
+	assert.Equal(t, STATUS_STOPPED, em.Status())
+	assert.False(t, d.advertising)
+	assert.True(t, d.stopped)
+	assert.Empty(t, em.Centrals())
+	assert.NoError(t, em.Stop())
+}
+
+func TestNewOfflineEmulator_Failed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "emulator")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	_, err = NewOfflineEmulator(Config{Persona: "nobody"})
+	assert.Error(t, err)
+	_, err = NewOfflineEmulator(Config{Logbook: dir})
+	assert.Error(t, err)
+	_, err = NewOfflineEmulator(Config{Capture: filepath.Join(dir, "missing", "capture.jsonl")})
+	assert.True(t, errors.Is(err, os.ErrNotExist), err)
+	_, err = NewOfflineEmulator(Config{Btsnoop: filepath.Join(dir, "missing", "trace.btsnoop")})
+	assert.True(t, errors.Is(err, os.ErrNotExist), err)
+}
+
+//countingCloser counts the times it is closed
+type countingCloser struct{ closed int32 }
+
+func (c *countingCloser) Close() error {
+	atomic.AddInt32(&c.closed, 1)
+	return nil
+}
+
+func TestEmulator_StopConcurrent(t *testing.T) {
+	em, err := NewOfflineEmulator(Config{Clock: clock.NewManual(time.Unix(0, 0)), Seed: 1})
+	if !assert.NoError(t, err) {
+		return
+	}
+	closer := &countingCloser{}
+	em.closers = append(em.closers, closer)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, em.Stop())
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&closer.closed))
+	assert.Equal(t, STATUS_STOPPED, em.Status())
+}
+
+func TestEmulator_AdapterFailed(t *testing.T) {
+	d := &fakeDevice{}
+	em, err := NewOfflineEmulator(Config{Clock: clock.NewManual(time.Unix(0, 0)), Seed: 1})
+	if !assert.NoError(t, err) {
+		return
+	}
+	em.device = d
+
+	assert.NoError(t, em.RunEmulator())
+	d.changed(d, gatt.StateUnsupported)
+	assert.Equal(t, STATUS_FAILED, em.Status())
+	select {
+	case err := <-em.Failed():
+		assert.True(t, errors.Is(err, ErrAdapter))
+	default:
+		t.Fatal("the failure is not reported")
+	}
+}
@@ -0,0 +1,169 @@
+package events
+
+import (
+	"pm5-emulator/capture"
+	"pm5-emulator/clock"
+	"pm5-emulator/service/mux"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bettercap/gatt"
+	"github.com/sirupsen/logrus"
+)
+
+//BUFFER is the number of events a subscriber can lag behind before losing events
+const BUFFER = 256
+
+//Types of the events besides the capture.EVENT_* of the GATT interactions
+const (
+	EVENT_STATE = "state" // the state machine changed state
+	EVENT_CSAFE = "csafe" // a csafe request was answered
+)
+
+//Event is something the emulator did
+type Event struct {
+	Time     time.Time      `json:"time"`
+	Type     string         `json:"type"` // one of capture.EVENT_* or EVENT_*
+	Central  string         `json:"central,omitempty"`
+	MTU      int            `json:"mtu,omitempty"`
+	Char     string         `json:"char,omitempty"`
+	Data     capture.Bytes  `json:"data,omitempty"`
+	Fields   map[string]int `json:"fields,omitempty"` // of rowing data, named as in mux-defs.go
+	Request  *capture.Frame `json:"request,omitempty"`
+	Response *capture.Frame `json:"response,omitempty"`
+	From     string         `json:"from,omitempty"` // state left
+	To       string         `json:"to,omitempty"`   // state entered
+}
+
+//Hub turns the GATT interactions and the state changes of the emulator into
+//events and broadcasts them to its subscribers
+type Hub struct {
+	mu       sync.Mutex
+	clock    clock.Clock
+	frames   *capture.FrameDecoder     // nil when not decoding csafe frames
+	requests map[string]*capture.Frame // csafe requests waiting for their response, by central
+	subs     map[*Subscription]struct{}
+}
+
+//NewHub returns a hub timestamping the events with c
+func NewHub(c clock.Clock) *Hub {
+	return &Hub{
+		clock:    c,
+		requests: make(map[string]*capture.Frame),
+		subs:     make(map[*Subscription]struct{}),
+	}
+}
+
+//DecodeCSAFE pairs the csafe requests written to rx with the responses notified on tx
+func (h *Hub) DecodeCSAFE(rx, tx gatt.UUID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.frames = capture.NewFrameDecoder(rx, tx)
+}
+
+//Record broadcasts a GATT interaction, along with the csafe exchange it completes
+func (h *Hub) Record(e capture.Entry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if e.Time.IsZero() {
+		e.Time = h.clock.Now()
+	}
+	h.broadcast(Event{
+		Time:    e.Time,
+		Type:    e.Event,
+		Central: e.Central,
+		MTU:     e.MTU,
+		Char:    e.Char,
+		Data:    e.Data,
+		Fields:  fields(e),
+	})
+
+	if h.frames == nil {
+		return nil
+	}
+	frame := h.frames.Decode(e)
+	switch {
+	case e.Event == capture.EVENT_DISCONNECT:
+		delete(h.requests, e.Central)
+	case frame == nil:
+	case e.Event == capture.EVENT_WRITE:
+		h.requests[e.Central] = frame
+	case e.Event == capture.EVENT_NOTIFY:
+		h.broadcast(Event{
+			Time:     e.Time,
+			Type:     EVENT_CSAFE,
+			Central:  e.Central,
+			Request:  h.requests[e.Central],
+			Response: frame,
+		})
+		delete(h.requests, e.Central)
+	}
+	return nil
+}
+
+//Transition broadcasts the state machine changing state, it suits sm.StateMachine.OnTransition
+func (h *Hub) Transition(from, to string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.broadcast(Event{Time: h.clock.Now(), Type: EVENT_STATE, From: from, To: to})
+}
+
+//Subscribe returns a subscription to the events broadcast from now on
+func (h *Hub) Subscribe() *Subscription {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := &Subscription{hub: h, events: make(chan Event, BUFFER)}
+	h.subs[s] = struct{}{}
+	return s
+}
+
+//broadcast sends e to the subscribers, the lock must be held
+func (h *Hub) broadcast(e Event) {
+	for s := range h.subs {
+		select {
+		case s.events <- e:
+		default:
+			s.dropped++
+			logrus.Debugf("[[Events]] subscriber lagging, %d events dropped", s.dropped)
+		}
+	}
+}
+
+//fields returns the fields of the rowing data read or notified in e, nil for other data
+func fields(e capture.Entry) map[string]int {
+	if (e.Event != capture.EVENT_NOTIFY && e.Event != capture.EVENT_READ) || len(e.Char) < 8 {
+		return nil
+	}
+	id, err := strconv.ParseUint(e.Char[4:8], 16, 16)
+	if err != nil {
+		return nil
+	}
+	if p := mux.ParseNotification(int(id), e.Data); p != nil {
+		return p.Fields()
+	}
+	return nil
+}
+
+//Subscription receives the events broadcast by a hub
+type Subscription struct {
+	hub     *Hub
+	events  chan Event
+	dropped int // events lost while the subscriber lagged
+}
+
+//Events returns the channel the events are received on, closed once the subscription is closed
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+//Close ends the subscription
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	if _, ok := s.hub.subs[s]; ok {
+		delete(s.hub.subs, s)
+		close(s.events)
+	}
+}
@@ -0,0 +1,122 @@
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"pm5-emulator/capture"
+	"pm5-emulator/clock"
+	"pm5-emulator/config"
+	"pm5-emulator/protocol/csafe"
+	"pm5-emulator/service/mux"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bettercap/gatt"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	rx      = gatt.MustParseUUID("CE060021-43E5-11E4-916C-0800200C9A66")
+	tx      = gatt.MustParseUUID("CE060022-43E5-11E4-916C-0800200C9A66")
+	general = gatt.MustParseUUID("CE060031-43E5-11E4-916C-0800200C9A66")
+)
+
+//drain returns the events received so far
+func drain(s *Subscription) []Event {
+	var list []Event
+	for {
+		select {
+		case e := <-s.Events():
+			list = append(list, e)
+		default:
+			return list
+		}
+	}
+}
+
+func TestHub_Record(t *testing.T) {
+	start := time.Date(2020, time.May, 1, 12, 0, 0, 0, time.UTC)
+	c := clock.NewManual(start)
+	h := NewHub(c)
+	h.DecodeCSAFE(rx, tx)
+	s := h.Subscribe()
+
+	enc := csafe.Encoder{}
+	req, _ := enc.Encode(csafe.Packet{Cmds: []byte{config.CSAFE_GETSTATUS_CMD}, JustCmd: true})
+	rsp, _ := enc.EncodeCommandResponses(0x81, []csafe.Command{{ID: config.CSAFE_GETSTATUS_CMD}})
+	p := mux.NewPayload(mux.Rowing_General_0x31)
+	p.Set("Distance", 1234)
+
+	assert.NoError(t, h.Record(capture.Entry{Event: capture.EVENT_CONNECT, Central: "c1", MTU: 185}))
+	c.Advance(time.Second)
+	assert.NoError(t, h.Record(capture.Entry{Event: capture.EVENT_WRITE, Central: "c1", Char: rx.String(), Data: req[:2]}))
+	assert.NoError(t, h.Record(capture.Entry{Event: capture.EVENT_WRITE, Central: "c1", Char: rx.String(), Data: req[2:]}))
+	assert.NoError(t, h.Record(capture.Entry{Event: capture.EVENT_NOTIFY, Central: "c1", Char: tx.String(), Data: rsp}))
+	assert.NoError(t, h.Record(capture.Entry{Event: capture.EVENT_NOTIFY, Central: "c1", Char: general.String(), Data: p.Bytes()}))
+	h.Transition(config.PM5_STATE_READY, config.PM5_STATE_IDLE)
+
+	got := drain(s)
+	assert.Len(t, got, 7)
+	assert.Equal(t, Event{Time: start, Type: capture.EVENT_CONNECT, Central: "c1", MTU: 185}, got[0])
+	assert.Equal(t, start.Add(time.Second), got[1].Time)
+
+	// the response is paired with the request it answers
+	assert.Equal(t, capture.EVENT_NOTIFY, got[3].Type)
+	status := capture.Byte(0x81)
+	assert.Equal(t, Event{
+		Time:     start.Add(time.Second),
+		Type:     EVENT_CSAFE,
+		Central:  "c1",
+		Request:  &capture.Frame{Commands: []capture.Command{{ID: config.CSAFE_GETSTATUS_CMD}}},
+		Response: &capture.Frame{Status: &status, Commands: []capture.Command{{ID: config.CSAFE_GETSTATUS_CMD, Data: capture.Bytes{}}}},
+	}, got[4])
+
+	// the rowing data is decoded into its fields
+	assert.Equal(t, 1234, got[5].Fields["Distance"])
+	assert.Nil(t, got[3].Fields)
+
+	assert.Equal(t, Event{Time: start.Add(time.Second), Type: EVENT_STATE, From: config.PM5_STATE_READY, To: config.PM5_STATE_IDLE}, got[6])
+
+	s.Close()
+	s.Close()
+	_, ok := <-s.Events()
+	assert.False(t, ok)
+	assert.NoError(t, h.Record(capture.Entry{Event: capture.EVENT_DISCONNECT, Central: "c1"}))
+}
+
+func TestHub_Lagging(t *testing.T) {
+	h := NewHub(clock.NewManual(time.Time{}))
+	s := h.Subscribe()
+	for i := 0; i < BUFFER+10; i++ {
+		h.Transition(config.PM5_STATE_READY, config.PM5_STATE_IDLE)
+	}
+	// the events past the buffer are dropped rather than blocking the emulator
+	assert.Len(t, drain(s), BUFFER)
+	assert.Equal(t, 10, s.dropped)
+}
+
+func TestHub_ServeHTTP(t *testing.T) {
+	h := NewHub(clock.NewManual(time.Time{}))
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	rsp, err := http.Get(srv.URL + "?types=state")
+	assert.NoError(t, err)
+	defer rsp.Body.Close()
+	assert.Equal(t, "text/event-stream", rsp.Header.Get("Content-Type"))
+
+	assert.NoError(t, h.Record(capture.Entry{Event: capture.EVENT_CONNECT, Central: "c1"}))
+	h.Transition(config.PM5_STATE_READY, config.PM5_STATE_IDLE)
+
+	r := bufio.NewReader(rsp.Body)
+	line, _ := r.ReadString('\n')
+	assert.Equal(t, "event: state\n", line)
+	line, _ = r.ReadString('\n')
+	assert.True(t, strings.HasPrefix(line, "data: "))
+	var e Event
+	assert.NoError(t, json.Unmarshal([]byte(line[len("data: "):]), &e))
+	assert.Equal(t, config.PM5_STATE_IDLE, e.To)
+}
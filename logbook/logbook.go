@@ -0,0 +1,220 @@
+package logbook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"pm5-emulator/clock"
+	"pm5-emulator/config"
+	"pm5-emulator/sm"
+	"pm5-emulator/user"
+	"pm5-emulator/workout"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+//DEFAULT_DIR and DEFAULT_FILE are where the emulator keeps its logbook in the
+//config directory of the user
+const (
+	DEFAULT_DIR  = "pm5-emulator"
+	DEFAULT_FILE = "logbook.json"
+)
+
+//DefaultPath returns the file the emulator keeps its logbook in, under the
+//config directory of the user, or empty to keep it in memory when the user
+//has no config directory
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, DEFAULT_DIR, DEFAULT_FILE)
+}
+
+//Entry is a finished workout stored in the logbook
+type Entry struct {
+	Date        time.Time
+	WorkoutType byte
+	ElapsedTime time.Duration
+	Distance    float64 // meters
+	Calories    int
+	StrokeCount int
+	Splits      []workout.Split
+}
+
+//NewEntry returns the entry of a workout finished at date. A split left
+//unfinished when the workout ended is added to the splits.
+func NewEntry(date time.Time, goal workout.Goal, m workout.Metrics, splits []workout.Split) Entry {
+	e := Entry{
+		Date:        date,
+		WorkoutType: goal.WorkoutType(),
+		ElapsedTime: m.ElapsedTime,
+		Distance:    m.Distance,
+		Calories:    m.Calories,
+		StrokeCount: m.StrokeCount,
+		Splits:      splits,
+	}
+
+	last := workout.Split{Time: m.ElapsedTime, Distance: m.Distance, Calories: m.Calories, StrokeCount: m.StrokeCount}
+	for _, s := range splits {
+		last.Time -= s.Time
+		last.Distance -= s.Distance
+		last.Calories -= s.Calories
+		last.StrokeCount -= s.StrokeCount
+	}
+	if last.Time > 0 {
+		e.Splits = append(e.Splits, last)
+	}
+	return e
+}
+
+//AvgPower returns the average power of the workout in watts
+func (e Entry) AvgPower() int {
+	return workout.AvgPower(e.ElapsedTime, e.Distance)
+}
+
+//AvgStrokeRate returns the average stroke rate of the workout
+func (e Entry) AvgStrokeRate() int {
+	if e.ElapsedTime <= 0 {
+		return 0
+	}
+	return int(float64(e.StrokeCount)/e.ElapsedTime.Minutes() + 0.5)
+}
+
+//Logbook keeps the finished workouts by user ID
+type Logbook struct {
+	mu    sync.Mutex
+	path  string
+	users map[int][]Entry
+	last  *lastEntry // workout added last
+}
+
+//lastEntry is a workout along with the ID of its user
+type lastEntry struct {
+	userID int
+	entry  Entry
+}
+
+//New returns an empty logbook kept in memory only
+func New() *Logbook {
+	return &Logbook{users: make(map[int][]Entry)}
+}
+
+//Open returns the logbook stored in the file at path, which is created with
+//its directory on the first workout added
+func Open(path string) (*Logbook, error) {
+	l := New()
+	l.path = path
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &l.users); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+//Add adds a workout of a user and stores the logbook
+func (l *Logbook) Add(userID int, e Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.users[userID] = append(l.users[userID], e)
+	l.last = &lastEntry{userID: userID, entry: e}
+	return l.save()
+}
+
+//Last returns the workout added last and the ID of its user, ok is false
+//until a workout is added
+func (l *Logbook) Last() (userID int, e Entry, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.last == nil {
+		return 0, Entry{}, false
+	}
+	return l.last.userID, l.last.entry, true
+}
+
+//Entries returns the workouts of a user in the order they were rowed
+func (l *Logbook) Entries(userID int) []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Entry(nil), l.users[userID]...)
+}
+
+//Users returns the sorted IDs of the users with workouts in the logbook
+func (l *Logbook) Users() []int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.userIDs()
+}
+
+//Count returns the number of workouts in the logbook
+func (l *Logbook) Count() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	n := 0
+	for _, entries := range l.users {
+		n += len(entries)
+	}
+	return n
+}
+
+//Attach adds the workout w to the logbook of the current user of u whenever
+//stm finishes it, dated by the PM clock c
+func (l *Logbook) Attach(stm *sm.StateMachine, w *workout.Workout, u *user.User, c clock.Source) {
+	stm.OnTransition(func(from, to string) {
+		if to != config.PM5_STATE_FINISHED {
+			return
+		}
+		m := w.Metrics()
+		if m.ElapsedTime <= 0 {
+			return
+		}
+		if err := l.Add(u.ID(), NewEntry(c.Now(), w.Goal(), m, w.Splits())); err != nil {
+			logrus.Error("[[Logbook]] ", err)
+		}
+	})
+}
+
+//userIDs returns the sorted IDs of the users, the lock must be held
+func (l *Logbook) userIDs() []int {
+	ids := make([]int, 0, len(l.users))
+	for id := range l.users {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+//save writes the logbook to its file, the lock must be held
+func (l *Logbook) save() error {
+	if l.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(l.users, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return err
+	}
+	// replace the file at once so that a crash never leaves half a logbook
+	tmp := l.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, l.path)
+}
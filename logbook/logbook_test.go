@@ -0,0 +1,136 @@
+package logbook
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"pm5-emulator/config"
+	"pm5-emulator/sm"
+	"pm5-emulator/user"
+	"pm5-emulator/workout"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testDate = time.Date(2021, time.March, 14, 9, 26, 0, 0, time.UTC)
+
+//fixedSource is a clock source stopped at a date
+type fixedSource time.Time
+
+func (s fixedSource) Now() time.Time {
+	return time.Time(s)
+}
+
+func TestNewEntry(t *testing.T) {
+	m := workout.Metrics{ElapsedTime: 5 * time.Minute, Distance: 1200, Calories: 60, StrokeCount: 120}
+	splits := []workout.Split{
+		{Time: 2 * time.Minute, Distance: 500, Calories: 25, StrokeCount: 48},
+		{Time: 2 * time.Minute, Distance: 500, Calories: 25, StrokeCount: 48},
+	}
+
+	e := NewEntry(testDate, workout.Goal{}, m, splits)
+	assert.Equal(t, config.WORKOUTTYPE_JUSTROW_NOSPLITS, int(e.WorkoutType))
+	assert.Equal(t, []workout.Split{
+		splits[0],
+		splits[1],
+		{Time: time.Minute, Distance: 200, Calories: 10, StrokeCount: 24},
+	}, e.Splits)
+	assert.Equal(t, 24, e.AvgStrokeRate())
+	// 2:05/500m
+	assert.Equal(t, 179, e.AvgPower())
+}
+
+func TestLogbook_Persistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logbook")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, DEFAULT_DIR, DEFAULT_FILE)
+
+	l, err := Open(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, l.Count())
+	_, _, ok := l.Last()
+	assert.False(t, ok)
+
+	e := NewEntry(testDate, workout.Goal{Distance: 500},
+		workout.Metrics{ElapsedTime: 2 * time.Minute, Distance: 500, Calories: 25, StrokeCount: 48}, nil)
+	assert.NoError(t, l.Add(42, e))
+	assert.NoError(t, l.Add(7, e))
+	assert.NoError(t, l.Add(42, e))
+
+	l, err = Open(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, l.Count())
+	assert.Equal(t, []int{7, 42}, l.Users())
+	assert.Len(t, l.Entries(42), 2)
+	assert.True(t, e.Date.Equal(l.Entries(7)[0].Date))
+	assert.Equal(t, e.Splits, l.Entries(7)[0].Splits)
+}
+
+func TestLogbook_Memory(t *testing.T) {
+	l := New()
+	e := NewEntry(testDate, workout.Goal{Distance: 1000},
+		workout.Metrics{ElapsedTime: 4 * time.Minute, Distance: 1000, Calories: 50, StrokeCount: 96},
+		[]workout.Split{{Time: 2 * time.Minute, Distance: 500, Calories: 25, StrokeCount: 48}})
+	l.Add(42, e)
+	l.Add(7, e)
+
+	mem := l.UserMemory(42)
+	if !assert.Len(t, mem, RECORD_SIZE+2*SPLIT_RECORD_SIZE) {
+		return
+	}
+	assert.Equal(t, uint32(42), binary.BigEndian.Uint32(mem[0:]))
+	assert.Equal(t, []byte{0x07, 0xE5, 3, 14, 9, 26, config.WORKOUTTYPE_FIXEDDIST_NOSPLITS}, mem[4:11])
+	assert.Equal(t, uint32(24000), binary.BigEndian.Uint32(mem[11:]))
+	assert.Equal(t, uint32(10000), binary.BigEndian.Uint32(mem[15:]))
+	assert.Equal(t, uint16(50), binary.BigEndian.Uint16(mem[19:]))
+	assert.Equal(t, uint16(96), binary.BigEndian.Uint16(mem[21:]))
+	assert.Equal(t, byte(24), mem[25])
+	assert.Equal(t, byte(2), mem[26])
+	assert.Equal(t, uint32(5000), binary.BigEndian.Uint32(mem[RECORD_SIZE+4:]))
+
+	// all users, by user ID
+	all := l.Memory()
+	assert.Len(t, all, 2*len(mem))
+	assert.Equal(t, uint32(7), binary.BigEndian.Uint32(all[0:]))
+	assert.Equal(t, mem, all[len(mem):])
+}
+
+func TestLogbook_Attach(t *testing.T) {
+	stm := sm.NewStateMachine()
+	stm.SetState(config.PM5_STATE_IDLE)
+	w := workout.New()
+	u := user.New(stm)
+	l := New()
+	l.Attach(stm, w, u, fixedSource(testDate))
+
+	assert.NoError(t, u.EnterID("00042"))
+	assert.NoError(t, stm.Update(config.CSAFE_GOINUSE_CMD))
+	w.Update(func(m *workout.Metrics) {
+		m.ElapsedTime = time.Minute
+		m.Distance = 250
+	})
+	assert.NoError(t, stm.Update(config.CSAFE_GOFINISHED_CMD))
+
+	// nothing rowed
+	assert.NoError(t, stm.Update(config.CSAFE_RESET_CMD))
+	w.Start()
+	stm.SetState(config.PM5_STATE_INUSE)
+	stm.SetState(config.PM5_STATE_FINISHED)
+
+	if assert.Len(t, l.Entries(42), 1) {
+		assert.Equal(t, 250.0, l.Entries(42)[0].Distance)
+		assert.Equal(t, testDate, l.Entries(42)[0].Date)
+	}
+	assert.Equal(t, 1, l.Count())
+
+	id, e, ok := l.Last()
+	assert.True(t, ok)
+	assert.Equal(t, 42, id)
+	assert.Equal(t, l.Entries(42)[0], e)
+}
@@ -0,0 +1,95 @@
+package logbook
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+//Sizes of the records of the logbook memory. A workout record is followed by
+//one split record for each of its splits, values are sent MSB first.
+const (
+	RECORD_SIZE       = 28
+	SPLIT_RECORD_SIZE = 12
+)
+
+//Memory returns the logbook memory holding the workouts of all the users,
+//by user ID
+func (l *Logbook) Memory() []byte {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var mem []byte
+	for _, id := range l.userIDs() {
+		for _, e := range l.users[id] {
+			mem = appendRecord(mem, id, e)
+		}
+	}
+	return mem
+}
+
+//UserMemory returns the logbook memory holding the workouts of a user
+func (l *Logbook) UserMemory(userID int) []byte {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var mem []byte
+	for _, e := range l.users[userID] {
+		mem = appendRecord(mem, userID, e)
+	}
+	return mem
+}
+
+//appendRecord appends the workout and split records of an entry
+//
+//	0-3   user ID
+//	4-9   year (2), month, day, hour, minute
+//	10    workout type
+//	11-14 elapsed time in 0.01s
+//	15-18 distance in 0.1m
+//	19-20 calories
+//	21-22 stroke count
+//	23-24 average power in watts
+//	25    average stroke rate
+//	26    split count
+//	27    reserved
+//
+//and for each split
+//
+//	0-3   time in 0.01s
+//	4-7   distance in 0.1m
+//	8-9   calories
+//	10-11 stroke count
+func appendRecord(mem []byte, userID int, e Entry) []byte {
+	rec := make([]byte, RECORD_SIZE)
+	binary.BigEndian.PutUint32(rec[0:], uint32(userID))
+	binary.BigEndian.PutUint16(rec[4:], uint16(e.Date.Year()))
+	rec[6] = byte(e.Date.Month())
+	rec[7] = byte(e.Date.Day())
+	rec[8] = byte(e.Date.Hour())
+	rec[9] = byte(e.Date.Minute())
+	rec[10] = e.WorkoutType
+	binary.BigEndian.PutUint32(rec[11:], uint32(e.ElapsedTime/(10*time.Millisecond)))
+	binary.BigEndian.PutUint32(rec[15:], uint32(math.Round(e.Distance*10)))
+	binary.BigEndian.PutUint16(rec[19:], uint16(e.Calories))
+	binary.BigEndian.PutUint16(rec[21:], uint16(e.StrokeCount))
+	binary.BigEndian.PutUint16(rec[23:], uint16(e.AvgPower()))
+	rec[25] = byte(e.AvgStrokeRate())
+
+	splits := e.Splits
+	if len(splits) > math.MaxUint8 {
+		splits = splits[:math.MaxUint8]
+	}
+	rec[26] = byte(len(splits))
+	mem = append(mem, rec...)
+
+	for _, s := range splits {
+		rec := make([]byte, SPLIT_RECORD_SIZE)
+		binary.BigEndian.PutUint32(rec[0:], uint32(s.Time/(10*time.Millisecond)))
+		binary.BigEndian.PutUint32(rec[4:], uint32(math.Round(s.Distance*10)))
+		binary.BigEndian.PutUint16(rec[8:], uint16(s.Calories))
+		binary.BigEndian.PutUint16(rec[10:], uint16(s.StrokeCount))
+		mem = append(mem, rec...)
+	}
+	return mem
+}
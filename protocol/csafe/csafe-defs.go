@@ -85,21 +85,21 @@ const (
 type SHORT_STATUS_CMDS byte
 
 const (
-	GETVERSION_CMD      SHORT_STATUS_CMDS = 0x91 // STATUS_CMD_SHORT_MIN
-	GETID_CMD                                    // 0x92
-	GETUNITS_CMD                                 // 0x93
-	GETSERIAL_CMD                                // 0x94
-	_                                            // 0x95
-	_                                            // 0x96
-	_                                            // 0x97
-	GETLIST_CMD                                  // 0x98
-	GETUTILIZATION_CMD                           // 0x99
-	GETMOTORCURRENT_CMD                          // 0x9A
-	GETODOMETER_CMD                              // 0x9B
-	GETERRORCODE_CMD                             // 0x9C
-	GETSERVICECODE_CMD                           // 0x9D
-	GETUSERCFG1_CMD                              // 0x9E
-	GETUSERCFG2_CMD                              // 0x9F
+	GETVERSION_CMD      SHORT_STATUS_CMDS = 0x91 + iota // STATUS_CMD_SHORT_MIN
+	GETID_CMD                                           // 0x92
+	GETUNITS_CMD                                        // 0x93
+	GETSERIAL_CMD                                       // 0x94
+	_                                                   // 0x95
+	_                                                   // 0x96
+	_                                                   // 0x97
+	GETLIST_CMD                                         // 0x98
+	GETUTILIZATION_CMD                                  // 0x99
+	GETMOTORCURRENT_CMD                                 // 0x9A
+	GETODOMETER_CMD                                     // 0x9B
+	GETERRORCODE_CMD                                    // 0x9C
+	GETSERVICECODE_CMD                                  // 0x9D
+	GETUSERCFG1_CMD                                     // 0x9E
+	GETUSERCFG2_CMD                                     // 0x9F
 	STATUS_CMD_SHORT_MAX
 )
 
@@ -315,7 +315,7 @@ const (
 	PM_GET_HW_ADDRESS                                                         // 0x82
 	PM_GET_TICK_TIMEBASE                                                      // 0x83
 	PM_GET_HRM                                                                // 0x84
-	_                                                                         // Unused 0x85
+	PM_GET_DATETIME                                                           // 0x85
 	PM_GET_SCREENSTATESTATUS                                                  // 0x86
 	PM_GET_RACE_LANE_REQUEST                                                  // 0x87
 	PM_GET_ERG_LOGICALADDR_REQUEST                                            // 0x88
@@ -17,3 +17,25 @@ type ResponsePacket struct {
 	Data                []byte // Additional data to be sent to client
 	JustCmd             bool   // Represents if just commands are to be sent
 }
+
+// Command is a single csafe command along with its data. Short commands
+// (0x80 and above) never carry data.
+type Command struct {
+	ID   byte   // Command identifier
+	Data []byte // Data bytes of a long command
+}
+
+// IsShort returns true for short commands, that are sent without a byte count
+func (c Command) IsShort() bool {
+	return c.ID&SHORT_CMD_TYPE_MSK != 0
+}
+
+// IsWrapper returns true for the PM proprietary commands that wrap a list of
+// PM specific commands in their data
+func (c Command) IsWrapper() bool {
+	switch LONG_PMPROPRIETARY_CMDS(c.ID) {
+	case SETPMCFG_CMD, SETPMDATA_CMD, GETPMCFG_CMD, GETPMDATA_CMD:
+		return true
+	}
+	return false
+}
@@ -4,6 +4,15 @@ import (
 	"errors"
 )
 
+// Errors returned by the Decoder. Decode never panics, any malformed input is
+// reported through one of these.
+var (
+	ErrNotFramed   = errors.New("not a csafe frame")
+	ErrTruncated   = errors.New("frame shorter than its declared length")
+	ErrBadStuffing = errors.New("invalid byte stuffing")
+	ErrChecksum    = errors.New("checksum mismatched")
+)
+
 // Decoder can decode the raw data - considering it as a csafe-encoded packet.
 type Decoder struct {
 }
@@ -11,33 +20,15 @@ type Decoder struct {
 // Decode decodes the raw csafe-encoded data.
 func (d *Decoder) Decode(raw []byte) (*Packet, error) {
 
-	if len(raw) < 4 {
-		return nil, errors.New("raw data length less than minimum length")
-	}
-
-	// Remove frame start and end bytes
-	body, err := d.stripHeadTail(raw)
-	if err != nil {
-		return nil, err
-	}
-
-	// Perform reverse byte-stuffing
-	pck, err := d.unstuff(body)
+	dta, err := d.unframe(raw)
 	if err != nil {
 		return nil, err
 	}
 
-	// Check the checksum
-	dta := pck[0 : len(pck)-1]
-	checksum := calculateChecksum(dta)
-	if checksum != pck[len(pck)-1] {
-		return nil, errors.New("checksum mismatched")
-	}
-
 	// Extract Command
-	cmd := pck[0]
+	cmd := dta[0]
 
-	if len(pck) == 2 {
+	if len(dta) == 1 {
 		// Command only
 		p := &Packet{
 			Data:    nil,
@@ -49,7 +40,10 @@ func (d *Decoder) Decode(raw []byte) (*Packet, error) {
 	}
 
 	// Extract the data length
-	dataLen := pck[1]
+	dataLen := int(dta[1])
+	if 2+dataLen > len(dta) {
+		return nil, ErrTruncated
+	}
 
 	p := &Packet{
 		Data:    make([]byte, dataLen),
@@ -58,17 +52,69 @@ func (d *Decoder) Decode(raw []byte) (*Packet, error) {
 	}
 
 	// Extract data
-	for i := 0; i < int(dataLen); i++ {
-		p.Data[i] = pck[2+i]
-	}
+	copy(p.Data, dta[2:2+dataLen])
 
 	return p, nil
 }
 
+// DecodeCommands decodes the raw csafe-encoded data into the list of commands
+// the frame carries.
+func (d *Decoder) DecodeCommands(raw []byte) ([]Command, error) {
+	dta, err := d.unframe(raw)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCommands(dta)
+}
+
+// DecodeResponse decodes a raw csafe-encoded response frame into its status
+// byte and the responses to each command of the request.
+func (d *Decoder) DecodeResponse(raw []byte) (byte, []Command, error) {
+	dta, err := d.unframe(raw)
+	if err != nil {
+		return 0, nil, err
+	}
+	rsps, err := ParseResponses(dta[1:])
+	if err != nil {
+		return 0, nil, err
+	}
+	return dta[0], rsps, nil
+}
+
+// unframe validates the framing, stuffing and checksum of raw data and returns
+// the frame contents without the checksum.
+func (d *Decoder) unframe(raw []byte) ([]byte, error) {
+	// Remove frame start and end bytes
+	body, err := d.stripHeadTail(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	// Perform reverse byte-stuffing
+	pck, err := d.unstuff(body)
+	if err != nil {
+		return nil, err
+	}
+
+	// A frame holds at least a command and the checksum
+	if len(pck) < 2 {
+		return nil, ErrTruncated
+	}
+
+	// Check the checksum
+	dta := pck[0 : len(pck)-1]
+	checksum := calculateChecksum(dta)
+	if checksum != pck[len(pck)-1] {
+		return nil, ErrChecksum
+	}
+
+	return dta, nil
+}
+
 // stripHeadTail removes the framing head and tail bytes.
 func (d *Decoder) stripHeadTail(raw []byte) ([]byte, error) {
-	if raw[0] != FRAME_START_BYTE || raw[len(raw)-1] != FRAME_END_BYTE {
-		return raw, errors.New("not a packet")
+	if len(raw) < 2 || raw[0] != FRAME_START_BYTE || raw[len(raw)-1] != FRAME_END_BYTE {
+		return raw, ErrNotFramed
 	}
 
 	return raw[1 : len(raw)-1], nil
@@ -82,10 +128,13 @@ func (d *Decoder) unstuff(raw []byte) ([]byte, error) {
 		curByte := raw[i]
 		if curByte == FRAME_STUFF_BYTE {
 			if (i == len(raw)-1) || (0b11111100&raw[i+1]) != 0 {
-				return raw, errors.New("unspecified Format")
+				return raw, ErrBadStuffing
 			}
 			buffer = append(buffer, 0xF0|raw[i+1])
 			i++
+		} else if curByte&0b11111100 == 0b11110000 {
+			// Unstuffed flag bytes can not appear inside a frame
+			return raw, ErrBadStuffing
 		} else {
 			buffer = append(buffer, curByte)
 		}
@@ -93,3 +142,16 @@ func (d *Decoder) unstuff(raw []byte) ([]byte, error) {
 
 	return buffer, nil
 }
+
+// FrameStatus returns the previous frame status flag that reports the outcome
+// of decoding a frame to the host.
+func FrameStatus(err error) byte {
+	switch err {
+	case nil:
+		return PREVOK_FLG
+	case ErrNotFramed:
+		return PREVREJECT_FLG
+	default:
+		return PREVBAD_FLG
+	}
+}
@@ -0,0 +1,46 @@
+//go:build go1.18
+// +build go1.18
+
+package csafe
+
+import (
+	"testing"
+)
+
+func FuzzDecoder_Decode(f *testing.F) {
+	f.Add([]byte{0xF1, 0x01, 0x01, 0xF2})
+	f.Add([]byte{0xF1, 0x01, 0x01, 0x02, 0x02, 0xF2})
+	f.Add([]byte{0xF1, 0xF3, 0x00, 0x01, 0xF3, 0x01, 0x00, 0xF2})
+	f.Add([]byte{0xF1, 0x01, 0xFF, 0xF2})
+	f.Add([]byte{0xF1, 0xF3, 0xF2})
+
+	enc := Encoder{}
+	f.Fuzz(func(t *testing.T, raw []byte) {
+		d := &Decoder{}
+		pck, err := d.Decode(raw)
+		if err != nil {
+			if pck != nil {
+				t.Errorf("Decoder.Decode() returned packet with error %v", err)
+			}
+			switch err {
+			case ErrNotFramed, ErrTruncated, ErrBadStuffing, ErrChecksum:
+			default:
+				t.Errorf("Decoder.Decode() returned untyped error %v", err)
+			}
+			return
+		}
+
+		// Whatever decodes must encode back into a frame that decodes the same
+		frame, err := enc.Encode(*pck)
+		if err != nil {
+			return
+		}
+		again, err := d.Decode(frame)
+		if err != nil {
+			t.Fatalf("Decoder.Decode() failed on re-encoded frame %v: %v", frame, err)
+		}
+		if again.Cmds[0] != pck.Cmds[0] || string(again.Data) != string(pck.Data) {
+			t.Errorf("Decoder.Decode() = %v, want %v", again, pck)
+		}
+	})
+}
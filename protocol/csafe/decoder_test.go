@@ -10,38 +10,48 @@ func TestDecoder_Decode(t *testing.T) {
 		name    string
 		raw     []byte
 		want    *Packet
-		wantErr bool
+		wantErr error
 	}{
 		// Just Commands tests
-		{"Test1", []byte{0xF1, 0x00, 0x00, 0xF2}, &Packet{Data: nil, Cmds: []byte{0x00}, JustCmd: true}, false},
-		{"Test2", []byte{0xF1, 0x01, 0x01, 0xF2}, &Packet{Data: nil, Cmds: []byte{0x01}, JustCmd: true}, false},
+		{"Test1", []byte{0xF1, 0x00, 0x00, 0xF2}, &Packet{Data: nil, Cmds: []byte{0x00}, JustCmd: true}, nil},
+		{"Test2", []byte{0xF1, 0x01, 0x01, 0xF2}, &Packet{Data: nil, Cmds: []byte{0x01}, JustCmd: true}, nil},
 
 		// Incorrect frame start or end bytes
-		{"Test4", []byte{0xF0, 0x00, 0x00, 0x00, 0xF2}, nil, true},
-		{"Test5", []byte{0xF1, 0x00, 0x00, 0x00, 0xF1}, nil, true},
+		{"Test4", []byte{0xF0, 0x00, 0x00, 0x00, 0xF2}, nil, ErrNotFramed},
+		{"Test5", []byte{0xF1, 0x00, 0x00, 0x00, 0xF1}, nil, ErrNotFramed},
 
 		{"Test With Data", []byte{0xF1, 0x01, 0x01, 0x02, calculateChecksum([]byte{0x01, 0x01, 0x02}), 0xF2},
-			&Packet{Data: []byte{0x02}, Cmds: []byte{0x01}, JustCmd: false}, false},
+			&Packet{Data: []byte{0x02}, Cmds: []byte{0x01}, JustCmd: false}, nil},
 		{"Test ByteStuffing", []byte{0xF1, 0xF3, 0x00, 0x01, 0xF3, 0x01, calculateChecksum([]byte{0xF0, 0x01, 0xF1}), 0xF2},
-			&Packet{Data: []byte{0xF1}, Cmds: []byte{0xF0}, JustCmd: false}, false},
+			&Packet{Data: []byte{0xF1}, Cmds: []byte{0xF0}, JustCmd: false}, nil},
 
 		// Incorrect byte stuffing
 		{"Test6", []byte{0xF1, 0xF3, 0x00, 0x01, 0xF3, 0xF2},
-			nil, true},
+			nil, ErrBadStuffing},
 		{"Test7", []byte{0xF1, 0xF3, 0x00, 0x01, 0xF3, 0x04, 0xF2},
-			nil, true},
+			nil, ErrBadStuffing},
+		{"Unstuffed Flag", []byte{0xF1, 0x01, 0xF1, 0xF0, 0xF2},
+			nil, ErrBadStuffing},
 
 		// Incorrect Checksum
-		{"Test2", []byte{0xF1, 0x01, 0x02, 0xF2}, nil, true},
+		{"Test2", []byte{0xF1, 0x01, 0x02, 0xF2}, nil, ErrChecksum},
 
 		// Less Data than required
-		{"Test2", []byte{0xF1, 0x01, 0xF2}, nil, true},
+		{"Test2", []byte{0xF1, 0x01, 0xF2}, nil, ErrTruncated},
+		{"Empty Frame", []byte{0xF1, 0xF2}, nil, ErrTruncated},
+		{"Stuffed Single Byte", []byte{0xF1, 0xF3, 0x00, 0xF2}, nil, ErrTruncated},
+		{"Declared Length Too Long", []byte{0xF1, 0x01, 0x40, 0x02, calculateChecksum([]byte{0x01, 0x40, 0x02}), 0xF2},
+			nil, ErrTruncated},
+
+		// Not a frame at all
+		{"Empty", []byte{}, nil, ErrNotFramed},
+		{"Single Byte", []byte{0xF1}, nil, ErrNotFramed},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			d := &Decoder{}
 			got, err := d.Decode(tt.raw)
-			if (err != nil) != tt.wantErr {
+			if err != tt.wantErr {
 				t.Errorf("Decoder.Decode() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
@@ -51,3 +61,24 @@ func TestDecoder_Decode(t *testing.T) {
 		})
 	}
 }
+
+func TestFrameStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want byte
+	}{
+		{"OK", nil, PREVOK_FLG},
+		{"Not Framed", ErrNotFramed, PREVREJECT_FLG},
+		{"Truncated", ErrTruncated, PREVBAD_FLG},
+		{"Bad Stuffing", ErrBadStuffing, PREVBAD_FLG},
+		{"Checksum", ErrChecksum, PREVBAD_FLG},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FrameStatus(tt.err); got != tt.want {
+				t.Errorf("FrameStatus() = 0x%x, want 0x%x", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,12 @@
 package csafe
 
-import "encoding/binary"
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// ErrFrameTooLarge is returned when a payload does not fit in a single csafe frame.
+var ErrFrameTooLarge = errors.New("payload exceeds maximum frame size")
 
 // Encoder can encode a payload according to the csafe format.
 type Encoder struct {
@@ -62,14 +68,11 @@ func (cp *Encoder) getType(tpe string) byte {
 	return tp
 }
 
-// Creates a payload for the provided command and the data, and returns it
-func (cp *Encoder) Encode(p Packet) []byte {
+// Creates a payload for the provided command and the data, and returns it.
+// ErrFrameTooLarge is returned if the resulting frame would exceed FRAME_MAXSIZE.
+func (cp *Encoder) Encode(p Packet) ([]byte, error) {
 	var buffer []byte // The Payload
 
-	if len(p.Data) > 90 {
-		panic("Can only send max 90 data at a time")
-	}
-
 	buffer = append(buffer, p.Cmds...) // Commands
 
 	if !p.JustCmd {
@@ -80,17 +83,22 @@ func (cp *Encoder) Encode(p Packet) []byte {
 		}
 	}
 
+	// Frame size is checked before stuffing, stuffed bytes do not count against it
+	if len(buffer)+FRAME_CHKSUM_LEN+FRAME_FLG_LEN > FRAME_MAXSIZE {
+		return nil, ErrFrameTooLarge
+	}
+
 	buffer = append(buffer, calculateChecksum(buffer)) // Insert checksum
 	buffer = cp.byteStuffing(buffer)                   // Stuff bytes properly
 
 	buffer = append([]byte{FRAME_START_BYTE}, buffer...) // Frame Start Flag
 	buffer = append(buffer, FRAME_END_BYTE)              // Stop Frame Flag
 
-	return buffer
+	return buffer, nil
 }
 
 // EncodeResponse encodes a response packet
-func (cp *Encoder) EncodeResponse(rp ResponsePacket) []byte {
+func (cp *Encoder) EncodeResponse(rp ResponsePacket) ([]byte, error) {
 	cmds := append([]byte{rp.Status}, rp.CommandResponseData...)
 	cmds = append(cmds, rp.Identifier)
 
@@ -102,3 +110,14 @@ func (cp *Encoder) EncodeResponse(rp ResponsePacket) []byte {
 
 	return cp.Encode(pck)
 }
+
+// EncodeCommandResponses encodes a response frame made of the status byte
+// followed by the responses of every command of the request frame
+func (cp *Encoder) EncodeCommandResponses(status byte, rsps []Command) ([]byte, error) {
+	buffer := []byte{status}
+	for _, rsp := range rsps {
+		buffer = appendResponse(buffer, rsp)
+	}
+
+	return cp.Encode(Packet{Cmds: buffer, JustCmd: true})
+}
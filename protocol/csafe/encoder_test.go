@@ -70,24 +70,33 @@ func TestEncoder_Encode(t *testing.T) {
 	cp := Encoder{}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := cp.Encode(tt.args.p); !reflect.DeepEqual(got, tt.want) {
+			got, err := cp.Encode(tt.args.p)
+			if err != nil {
+				t.Errorf("Encoder.Encode() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("Encoder.Encode() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 
-	// Panic Test
+	// Largest payload that fits a frame
 	pck := Packet{
 		Cmds:    []byte{0x01},
-		Data:    make([]byte, 100),
+		Data:    make([]byte, 90),
 		JustCmd: false,
 	}
-
-	f := func() {
-		cp.Encode(pck)
+	got, err := cp.Encode(pck)
+	if assert.NoError(t, err) {
+		assert.Len(t, got, 95)
 	}
 
-	assert.Panics(t, f)
+	// Oversized payload
+	pck.Data = make([]byte, 100)
+	got, err = cp.Encode(pck)
+	assert.Equal(t, ErrFrameTooLarge, err)
+	assert.Nil(t, got)
 }
 
 func TestEncoder_EncodeResponse(t *testing.T) {
@@ -112,7 +121,12 @@ func TestEncoder_EncodeResponse(t *testing.T) {
 	cp := Encoder{}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := cp.EncodeResponse(tt.args.rp); !reflect.DeepEqual(got, tt.want) {
+			got, err := cp.EncodeResponse(tt.args.rp)
+			if err != nil {
+				t.Errorf("Encoder.EncodeResponse() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("Encoder.EncodeResponse() = %v, want %v", got, tt.want)
 			}
 		})
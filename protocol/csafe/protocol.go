@@ -0,0 +1,101 @@
+package csafe
+
+import "pm5-emulator/protocol"
+
+// PROTOCOL_NAME is the name csafe is registered with in the protocol registry
+const PROTOCOL_NAME = "csafe"
+
+func init() {
+	protocol.Register(PROTOCOL_NAME, NewProtocol)
+}
+
+// Protocol speaks csafe over the PM control characteristics. Each instance
+// keeps the csafe session of one connection.
+type Protocol struct {
+	enc     Encoder
+	dec     Decoder
+	session *Session
+	handler protocol.Handler
+}
+
+// NewProtocol creates a csafe protocol that answers commands with h
+func NewProtocol(h protocol.Handler) protocol.Protocol {
+	return &Protocol{
+		session: NewSession(),
+		handler: h,
+	}
+}
+
+// Name returns the name of the protocol
+func (p *Protocol) Name() string {
+	return PROTOCOL_NAME
+}
+
+// ReadPayload decodes a csafe frame, passes each of its commands to the
+// handler and returns the encoded response frame. Frames that fail to decode,
+// hold a command rejected by the handler or whose responses fail to encode are
+// answered with a status only frame, returned along with the error.
+func (p *Protocol) ReadPayload(payload []byte) ([]byte, error) {
+	cmds, err := p.dec.DecodeCommands(payload)
+	if err != nil {
+		return p.statusFrame(FrameStatus(err)), err
+	}
+
+	rsps := make([]Command, 0, len(cmds))
+	for _, cmd := range cmds {
+		data, err := p.handle(cmd)
+		if err != nil {
+			return p.statusFrame(PREVREJECT_FLG), err
+		}
+		rsps = append(rsps, Command{ID: cmd.ID, Data: data})
+	}
+
+	// the frame is only accepted once its response frame is encoded
+	status := p.session.Pending(PREVOK_FLG, SlaveState(p.handler.State()))
+	frame, err := p.enc.EncodeCommandResponses(status, rsps)
+	if err != nil {
+		return p.statusFrame(PREVBAD_FLG), err
+	}
+	p.session.Record(PREVOK_FLG)
+	return frame, nil
+}
+
+// handle passes a command to the handler and returns its response data. The
+// commands nested in a PM proprietary wrapper are handled one by one and
+// their responses nested in the response to the wrapper.
+func (p *Protocol) handle(cmd Command) ([]byte, error) {
+	if !cmd.IsWrapper() {
+		return p.handler.Handle(protocol.Request{Command: cmd.ID, Data: cmd.Data})
+	}
+
+	nested, err := ParseCommands(cmd.Data)
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	for _, n := range nested {
+		rsp, err := p.handler.Handle(protocol.Request{Wrapper: cmd.ID, Command: n.ID, Data: n.Data})
+		if err != nil {
+			return nil, err
+		}
+		data = appendResponse(data, Command{ID: n.ID, Data: rsp})
+	}
+	return data, nil
+}
+
+// WritePayload wraps a payload into a csafe frame
+func (p *Protocol) WritePayload(payload []byte) ([]byte, error) {
+	return p.enc.Encode(Packet{Cmds: payload, JustCmd: true})
+}
+
+// status returns the status byte for the reply to the last frame
+func (p *Protocol) status() byte {
+	return p.session.Status(SlaveState(p.handler.State()))
+}
+
+// statusFrame records the frame status and returns a frame holding only the status byte
+func (p *Protocol) statusFrame(prevStatus byte) []byte {
+	p.session.Record(prevStatus)
+	frame, _ := p.enc.Encode(Packet{Cmds: []byte{p.status()}, JustCmd: true})
+	return frame
+}
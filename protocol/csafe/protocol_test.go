@@ -0,0 +1,88 @@
+package csafe
+
+import (
+	"errors"
+	"pm5-emulator/config"
+	"pm5-emulator/protocol"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type stubHandler struct {
+	state string
+}
+
+func (h *stubHandler) Handle(req protocol.Request) ([]byte, error) {
+	switch req.Command {
+	case 0xA1:
+		return []byte{0xAA, 0xBB}, nil
+	case 0xA2:
+		return nil, nil
+	case 0xA4:
+		return make([]byte, FRAME_MAXSIZE), nil
+	case 0x10:
+		return req.Data, nil
+	case 0x55:
+		if req.Wrapper == byte(GETPMCFG_CMD) {
+			return append([]byte{0x01}, req.Data...), nil
+		}
+		return nil, errors.New("unwrapped command")
+	default:
+		return nil, errors.New("unsupported command")
+	}
+}
+
+func (h *stubHandler) State() string {
+	return h.state
+}
+
+func TestProtocol_ReadPayload(t *testing.T) {
+	p, err := protocol.New(PROTOCOL_NAME, &stubHandler{state: config.PM5_STATE_IDLE})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, PROTOCOL_NAME, p.Name())
+
+	frame := func(b ...byte) []byte {
+		return append(append([]byte{FRAME_START_BYTE}, b...), calculateChecksum(b), FRAME_END_BYTE)
+	}
+
+	tests := []struct {
+		name    string
+		payload []byte
+		want    []byte
+		wantErr bool
+	}{
+		{"Command With Data", frame(0xA1), frame(FRAMECNT_FLG|SLAVESTATE_IDLE_FLG, 0xA1, 0x02, 0xAA, 0xBB), false},
+		{"Command Without Data", frame(0xA2), frame(SLAVESTATE_IDLE_FLG, 0xA2, 0x00), false},
+		{"Multiple Commands", frame(0xA1, 0xA2), frame(FRAMECNT_FLG|SLAVESTATE_IDLE_FLG, 0xA1, 0x02, 0xAA, 0xBB, 0xA2, 0x00), false},
+		{"Long Command", frame(0x10, 0x01, 0x05, 0xA2), frame(SLAVESTATE_IDLE_FLG, 0x10, 0x01, 0x05, 0xA2, 0x00), false},
+		{"Rejected Command", frame(0xA1, 0xA3), frame(PREVREJECT_FLG | SLAVESTATE_IDLE_FLG), true},
+		{"Truncated Long Command", frame(0x10, 0x02, 0x05), frame(PREVBAD_FLG | SLAVESTATE_IDLE_FLG), true},
+		{"Bad Checksum", []byte{FRAME_START_BYTE, 0xA1, 0x00, FRAME_END_BYTE}, frame(PREVBAD_FLG | SLAVESTATE_IDLE_FLG), true},
+		{"Not Framed", []byte{0xA1, 0xA1}, frame(PREVREJECT_FLG | SLAVESTATE_IDLE_FLG), true},
+		{"Wrapped Commands", frame(0x7E, 0x05, 0x55, 0x01, 0x02, 0x55, 0x00), frame(FRAMECNT_FLG|SLAVESTATE_IDLE_FLG, 0x7E, 0x07, 0x55, 0x02, 0x01, 0x02, 0x55, 0x01, 0x01), false},
+		{"Rejected Wrapped Command", frame(0x7E, 0x02, 0x54, 0x00), frame(FRAMECNT_FLG | PREVREJECT_FLG | SLAVESTATE_IDLE_FLG), true},
+		{"Unwrapped PM Command", frame(0x55, 0x00), frame(FRAMECNT_FLG | PREVREJECT_FLG | SLAVESTATE_IDLE_FLG), true},
+		{"Response Too Large", frame(0xA4), frame(FRAMECNT_FLG | PREVBAD_FLG | SLAVESTATE_IDLE_FLG), true},
+		{"Accepted After Too Large", frame(0xA2), frame(SLAVESTATE_IDLE_FLG, 0xA2, 0x00), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.ReadPayload(tt.payload)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Protocol.ReadPayload() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestProtocol_WritePayload(t *testing.T) {
+	p := NewProtocol(&stubHandler{})
+	got, err := p.WritePayload([]byte{0x01})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []byte{0xF1, 0x01, 0x01, 0xF2}, got)
+	}
+}
@@ -0,0 +1,83 @@
+package csafe
+
+import (
+	"pm5-emulator/config"
+	"sync"
+)
+
+// Session tracks the frame toggle and previous frame status that a PM reports
+// in the status byte of every response on a connection.
+type Session struct {
+	mu         sync.Mutex
+	toggle     bool // frame count flag, flipped on every accepted frame
+	prevStatus byte // status of the last frame received from the host
+}
+
+// NewSession returns a session for a new connection
+func NewSession() *Session {
+	return &Session{prevStatus: PREVOK_FLG}
+}
+
+// Record stores how the last frame was handled. prevStatus is one of the
+// PREVOK_FLG, PREVREJECT_FLG, PREVBAD_FLG or PREVNOTRDY_FLG flags. The frame
+// toggle only flips when the frame was accepted.
+func (s *Session) Record(prevStatus byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prevStatus = prevStatus & PREVFRAMESTATUS_MSK
+	if s.prevStatus == PREVOK_FLG {
+		s.toggle = !s.toggle
+	}
+}
+
+// Status builds the status byte from the frame toggle, the previous frame
+// status and the given slave state.
+func (s *Session) Status(slaveState byte) byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return status(s.toggle, s.prevStatus, slaveState)
+}
+
+// Pending builds the status byte Status will return once prevStatus is
+// recorded, leaving the session as it is until Record is called.
+func (s *Session) Pending(prevStatus, slaveState byte) byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prevStatus &= PREVFRAMESTATUS_MSK
+	return status(s.toggle != (prevStatus == PREVOK_FLG), prevStatus, slaveState)
+}
+
+// status builds a status byte from its frame toggle, previous frame status
+// and slave state
+func status(toggle bool, prevStatus, slaveState byte) byte {
+	status := prevStatus | (slaveState & SLAVESTATE_MSK)
+	if toggle {
+		status |= FRAMECNT_FLG
+	}
+	return status
+}
+
+// SlaveState returns the SLAVESTATE_* flag for a state machine state name
+func SlaveState(state string) byte {
+	switch state {
+	case config.PM5_STATE_READY:
+		return SLAVESTATE_RDY_FLG
+	case config.PM5_STATE_IDLE:
+		return SLAVESTATE_IDLE_FLG
+	case config.PM5_STATE_HAVEID:
+		return SLAVESTATE_HAVEID_FLG
+	case config.PM5_STATE_INUSE:
+		return SLAVESTATE_INUSE_FLG
+	case config.PM5_STATE_PAUSED:
+		return SLAVESTATE_PAUSE_FLG
+	case config.PM5_STATE_FINISHED:
+		return SLAVESTATE_FINISH_FLG
+	case config.PM5_STATE_MANUAL:
+		return SLAVESTATE_MANUAL_FLG
+	default:
+		return SLAVESTATE_ERR_FLG
+	}
+}
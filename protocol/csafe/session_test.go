@@ -0,0 +1,66 @@
+package csafe
+
+import (
+	"pm5-emulator/config"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSession_Status(t *testing.T) {
+	s := NewSession()
+
+	// Nothing received yet
+	assert.Equal(t, byte(SLAVESTATE_RDY_FLG), s.Status(SLAVESTATE_RDY_FLG))
+
+	steps := []struct {
+		name       string
+		prevStatus byte
+		slaveState byte
+		want       byte
+	}{
+		{"first accepted frame", PREVOK_FLG, SLAVESTATE_IDLE_FLG, FRAMECNT_FLG | SLAVESTATE_IDLE_FLG},
+		{"second accepted frame", PREVOK_FLG, SLAVESTATE_IDLE_FLG, SLAVESTATE_IDLE_FLG},
+		{"bad frame keeps toggle", PREVBAD_FLG, SLAVESTATE_IDLE_FLG, PREVBAD_FLG | SLAVESTATE_IDLE_FLG},
+		{"rejected frame keeps toggle", PREVREJECT_FLG, SLAVESTATE_INUSE_FLG, PREVREJECT_FLG | SLAVESTATE_INUSE_FLG},
+		{"accepted after reject", PREVOK_FLG, SLAVESTATE_INUSE_FLG, FRAMECNT_FLG | SLAVESTATE_INUSE_FLG},
+		{"not ready", PREVNOTRDY_FLG, SLAVESTATE_FINISH_FLG, FRAMECNT_FLG | PREVNOTRDY_FLG | SLAVESTATE_FINISH_FLG},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			// the pending status is that of the frame once recorded
+			pending := s.Pending(step.prevStatus, step.slaveState)
+			if pending != step.want {
+				t.Errorf("Session.Pending() = 0x%x, want 0x%x", pending, step.want)
+			}
+			s.Record(step.prevStatus)
+			got := s.Status(step.slaveState)
+			if got != step.want {
+				t.Errorf("Session.Status() = 0x%x, want 0x%x", got, step.want)
+			}
+		})
+	}
+}
+
+func TestSlaveState(t *testing.T) {
+	tests := []struct {
+		state string
+		want  byte
+	}{
+		{config.PM5_STATE_READY, SLAVESTATE_RDY_FLG},
+		{config.PM5_STATE_IDLE, SLAVESTATE_IDLE_FLG},
+		{config.PM5_STATE_HAVEID, SLAVESTATE_HAVEID_FLG},
+		{config.PM5_STATE_INUSE, SLAVESTATE_INUSE_FLG},
+		{config.PM5_STATE_PAUSED, SLAVESTATE_PAUSE_FLG},
+		{config.PM5_STATE_FINISHED, SLAVESTATE_FINISH_FLG},
+		{config.PM5_STATE_MANUAL, SLAVESTATE_MANUAL_FLG},
+		{"UNKNOWN", SLAVESTATE_ERR_FLG},
+	}
+	for _, tt := range tests {
+		t.Run(tt.state, func(t *testing.T) {
+			if got := SlaveState(tt.state); got != tt.want {
+				t.Errorf("SlaveState() = 0x%x, want 0x%x", got, tt.want)
+			}
+		})
+	}
+}
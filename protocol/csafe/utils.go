@@ -26,3 +26,57 @@ func padStart(str, pad string, maxlength int) (string, error) {
 
 	return str, nil
 }
+
+// ParseCommands splits the contents of a frame into its commands
+func ParseCommands(buffer []byte) ([]Command, error) {
+	var cmds []Command
+	for i := 0; i < len(buffer); {
+		cmd := Command{ID: buffer[i]}
+		i++
+
+		if !cmd.IsShort() {
+			if i >= len(buffer) {
+				return nil, ErrTruncated
+			}
+			dataLen := int(buffer[i])
+			i++
+			if i+dataLen > len(buffer) {
+				return nil, ErrTruncated
+			}
+			cmd.Data = buffer[i : i+dataLen]
+			i += dataLen
+		}
+
+		cmds = append(cmds, cmd)
+	}
+	return cmds, nil
+}
+
+// ParseResponses splits the contents of a response frame, following the status
+// byte, into the responses it holds. Unlike commands, every response carries a
+// byte count.
+func ParseResponses(buffer []byte) ([]Command, error) {
+	var rsps []Command
+	for i := 0; i < len(buffer); {
+		if i+2 > len(buffer) {
+			return nil, ErrTruncated
+		}
+		rsp := Command{ID: buffer[i]}
+		dataLen := int(buffer[i+1])
+		i += 2
+		if i+dataLen > len(buffer) {
+			return nil, ErrTruncated
+		}
+		rsp.Data = buffer[i : i+dataLen]
+		i += dataLen
+
+		rsps = append(rsps, rsp)
+	}
+	return rsps, nil
+}
+
+// appendResponse appends the identifier, byte count and data of a command response
+func appendResponse(buffer []byte, rsp Command) []byte {
+	buffer = append(buffer, rsp.ID, byte(len(rsp.Data)))
+	return append(buffer, rsp.Data...)
+}
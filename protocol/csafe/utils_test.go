@@ -1,6 +1,7 @@
 package csafe
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -57,3 +58,60 @@ func Test_padStart(t *testing.T) {
 		})
 	}
 }
+
+func TestParseCommands(t *testing.T) {
+	tests := []struct {
+		name    string
+		buffer  []byte
+		want    []Command
+		wantErr error
+	}{
+		{"Short Command", []byte{0xA0}, []Command{{ID: 0xA0}}, nil},
+		{"Short Commands", []byte{0x80, 0xA0, 0xA1}, []Command{{ID: 0x80}, {ID: 0xA0}, {ID: 0xA1}}, nil},
+		{"Long Command", []byte{0x20, 0x03, 0x00, 0x14, 0x00}, []Command{{ID: 0x20, Data: []byte{0x00, 0x14, 0x00}}}, nil},
+		{"Mixed Commands", []byte{0x21, 0x03, 0xD0, 0x07, 0x24, 0x85},
+			[]Command{{ID: 0x21, Data: []byte{0xD0, 0x07, 0x24}}, {ID: 0x85}}, nil},
+		{"Missing Byte Count", []byte{0xA0, 0x20}, nil, ErrTruncated},
+		{"Missing Data", []byte{0x20, 0x03, 0x00}, nil, ErrTruncated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCommands(tt.buffer)
+			if err != tt.wantErr {
+				t.Errorf("ParseCommands() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseCommands() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseResponses(t *testing.T) {
+	tests := []struct {
+		name    string
+		buffer  []byte
+		want    []Command
+		wantErr error
+	}{
+		{"No Response", []byte{}, nil, nil},
+		{"Empty Response", []byte{0x80, 0x00}, []Command{{ID: 0x80, Data: []byte{}}}, nil},
+		{"Responses", []byte{0xA0, 0x03, 0x00, 0x14, 0x00, 0x91, 0x01, 0x05},
+			[]Command{{ID: 0xA0, Data: []byte{0x00, 0x14, 0x00}}, {ID: 0x91, Data: []byte{0x05}}}, nil},
+		{"Missing Byte Count", []byte{0xA0}, nil, ErrTruncated},
+		{"Missing Data", []byte{0xA0, 0x03, 0x00}, nil, ErrTruncated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseResponses(tt.buffer)
+			if err != tt.wantErr {
+				t.Errorf("ParseResponses() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseResponses() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
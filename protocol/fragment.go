@@ -0,0 +1,35 @@
+package protocol
+
+import "errors"
+
+// ATT_NOTIFY_HDR_LEN is the number of bytes of an ATT PDU taken by the opcode
+// and attribute handle of a notification
+const ATT_NOTIFY_HDR_LEN = 3
+
+// ATT_MIN_MTU is the smallest MTU a BLE connection may negotiate
+const ATT_MIN_MTU = 23
+
+// ErrMTUTooSmall is returned when the MTU is below the BLE minimum.
+var ErrMTUTooSmall = errors.New("mtu smaller than minimum att mtu")
+
+// Fragment splits an encoded frame into chunks that each fit a single
+// notification on a connection with the given MTU. Every chunk except the
+// last one is filled completely.
+func Fragment(frame []byte, mtu int) ([][]byte, error) {
+	if mtu < ATT_MIN_MTU {
+		return nil, ErrMTUTooSmall
+	}
+
+	size := mtu - ATT_NOTIFY_HDR_LEN
+
+	var chunks [][]byte
+	for len(frame) > size {
+		chunks = append(chunks, frame[:size])
+		frame = frame[size:]
+	}
+	if len(frame) > 0 {
+		chunks = append(chunks, frame)
+	}
+
+	return chunks, nil
+}
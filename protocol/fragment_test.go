@@ -0,0 +1,49 @@
+package protocol
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFragment(t *testing.T) {
+	// Size of the largest csafe frame
+	frame := bytes.Repeat([]byte{0x55}, 95)
+
+	tests := []struct {
+		name  string
+		mtu   int
+		sizes []int
+	}{
+		{"MTU 23", 23, []int{20, 20, 20, 20, 15}},
+		{"MTU 100", 100, []int{95}},
+		{"MTU 512", 512, []int{95}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks, err := Fragment(frame, tt.mtu)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			var sizes []int
+			for _, c := range chunks {
+				sizes = append(sizes, len(c))
+			}
+			assert.Equal(t, tt.sizes, sizes)
+
+			// Reassembled chunks must give back the original frame
+			assert.Equal(t, frame, bytes.Join(chunks, nil))
+		})
+	}
+
+	// Short frames are sent as is
+	chunks, err := Fragment([]byte{0xF1, 0x01, 0x01, 0xF2}, 23)
+	if assert.NoError(t, err) {
+		assert.Equal(t, [][]byte{{0xF1, 0x01, 0x01, 0xF2}}, chunks)
+	}
+
+	_, err = Fragment(frame, 20)
+	assert.Equal(t, ErrMTUTooSmall, err)
+}
@@ -1,14 +1,31 @@
 package protocol
 
-//Protocol
-type Protocol interface {
-	ReadPayload(payload []byte) error //for reading
+//Request is a single command decoded by a protocol from a payload written by the central
+type Request struct {
+	Wrapper byte   // command the request was nested in, 0 when sent on its own
+	Command byte   // command or opcode
+	Data    []byte // data sent along with the command
+}
 
-	WritePayload(payload []byte) ([]byte, error) //for creating a payload
+//Handler answers the requests decoded by a protocol from the state of the emulated machine
+type Handler interface {
+	//Handle returns the response data for a request, an error rejects the request
+	Handle(req Request) ([]byte, error)
 
-	//ByteStuffing(cmd csafe.Command) (csafe.Command,error)
+	//State returns the name of the current state of the emulated machine
+	State() string
+}
+
+//Protocol is a wire format spoken over the PM control characteristics
+type Protocol interface {
+	//Name returns the name the protocol is registered with
+	Name() string
 
-	//ByteUnStuffing(cmd csafe.Command) (csafe.Command,error)
+	//ReadPayload decodes a payload written by the central, hands it to the
+	//handler and returns the encoded reply. A reply may be returned along
+	//with an error when the protocol reports the failure to the central.
+	ReadPayload(payload []byte) ([]byte, error)
 
-	//TODO: add more related methods
+	//WritePayload encodes a payload for transmission to the central
+	WritePayload(payload []byte) ([]byte, error)
 }
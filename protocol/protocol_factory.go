@@ -1 +1,65 @@
 package protocol
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+//Factory creates a new protocol instance that answers requests with h.
+//An instance holds the state of a single connection.
+type Factory func(h Handler) Protocol
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = make(map[string]Factory)
+)
+
+//Register makes a protocol available by the provided name.
+//If Register is called twice with the same name or if factory is nil, it panics.
+func Register(name string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	if factory == nil {
+		panic("protocol: Register factory is nil")
+	}
+	if _, dup := factories[name]; dup {
+		panic("protocol: Register called twice for protocol " + name)
+	}
+	factories[name] = factory
+}
+
+//Lookup returns the factory of a registered protocol
+func Lookup(name string) (Factory, error) {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	factory, ok := factories[name]
+	if !ok {
+		return nil, fmt.Errorf("protocol: unknown protocol %q", name)
+	}
+	return factory, nil
+}
+
+//New creates an instance of a registered protocol
+func New(name string, h Handler) (Protocol, error) {
+	factory, err := Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	return factory(h), nil
+}
+
+//Protocols returns a sorted list of the names of the registered protocols
+func Protocols() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	var list []string
+	for name := range factories {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
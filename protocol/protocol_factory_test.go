@@ -0,0 +1,53 @@
+package protocol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type echoProtocol struct {
+	h Handler
+}
+
+func (p *echoProtocol) Name() string { return "echo" }
+
+func (p *echoProtocol) ReadPayload(payload []byte) ([]byte, error) {
+	return p.h.Handle(Request{Command: payload[0], Data: payload[1:]})
+}
+
+func (p *echoProtocol) WritePayload(payload []byte) ([]byte, error) {
+	return payload, nil
+}
+
+type echoHandler struct{}
+
+func (echoHandler) Handle(req Request) ([]byte, error) {
+	return append([]byte{req.Command}, req.Data...), nil
+}
+
+func (echoHandler) State() string { return "READY" }
+
+func TestRegister(t *testing.T) {
+	Register("echo", func(h Handler) Protocol { return &echoProtocol{h: h} })
+
+	assert.Contains(t, Protocols(), "echo")
+
+	p, err := New("echo", echoHandler{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "echo", p.Name())
+		rsp, err := p.ReadPayload([]byte{0x01, 0x02})
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{0x01, 0x02}, rsp)
+	}
+
+	_, err = New("unknown", echoHandler{})
+	assert.Error(t, err)
+
+	assert.Panics(t, func() {
+		Register("echo", func(h Handler) Protocol { return &echoProtocol{h: h} })
+	})
+	assert.Panics(t, func() {
+		Register("nil", nil)
+	})
+}
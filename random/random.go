@@ -0,0 +1,59 @@
+package random
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+//Rand is the pseudo random source of the emulator. Every random element of
+//a session draws from a single Rand, so that a session started with the same
+//seed produces the same data.
+type Rand struct {
+	mu   sync.Mutex
+	seed int64
+	r    *rand.Rand
+}
+
+//NewSeed returns a seed for a new session
+func NewSeed() int64 {
+	return time.Now().UnixNano()
+}
+
+//New returns a source seeded with seed
+func New(seed int64) *Rand {
+	return &Rand{seed: seed, r: rand.New(rand.NewSource(seed))}
+}
+
+//Seed returns the seed of the source
+func (r *Rand) Seed() int64 {
+	return r.seed
+}
+
+//Intn returns a number in [0,n)
+func (r *Rand) Intn(n int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.r.Intn(n)
+}
+
+//Float64 returns a number in [0,1)
+func (r *Rand) Float64() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.r.Float64()
+}
+
+//NormFloat64 returns a normally distributed number with mean 0 and standard deviation 1
+func (r *Rand) NormFloat64() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.r.NormFloat64()
+}
+
+//Read fills p with random bytes
+func (r *Rand) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.r.Read(p)
+}
@@ -0,0 +1,20 @@
+package random
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRand_Deterministic(t *testing.T) {
+	draw := func(r *Rand) []interface{} {
+		b := make([]byte, 4)
+		r.Read(b)
+		return []interface{}{r.Intn(100), r.Float64(), r.NormFloat64(), b}
+	}
+
+	a, b := New(42), New(42)
+	assert.Equal(t, int64(42), a.Seed())
+	assert.Equal(t, draw(a), draw(b))
+	assert.NotEqual(t, draw(New(42)), draw(New(43)))
+}
@@ -0,0 +1,92 @@
+package regression
+
+import (
+	"pm5-emulator/capture"
+	"pm5-emulator/protocol/csafe"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func loadSession(t *testing.T) []capture.Entry {
+	entries, err := capture.Load("testdata/session.jsonl")
+	assert.NoError(t, err)
+	return entries
+}
+
+func TestRun(t *testing.T) {
+	report := Run(loadSession(t), Options{})
+	assert.True(t, report.Passed(), report.String())
+	assert.Equal(t, 110, report.Entries)
+	assert.True(t, report.Compared > 80)
+}
+
+//find returns the index of the n-th entry of event on the characteristic with the short id
+func find(entries []capture.Entry, event, id string, n int) int {
+	for i, e := range entries {
+		if e.Event == event && len(e.Char) >= 8 && e.Char[4:8] == id {
+			if n == 0 {
+				return i
+			}
+			n--
+		}
+	}
+	return -1
+}
+
+func TestRun_Ignore(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(entries []capture.Entry)
+		ignore []string
+	}{
+		{"Rowing Field", func(entries []capture.Entry) {
+			// a stroke data notification while rowing, elapsed time off by 10ms
+			entries[find(entries, capture.EVENT_NOTIFY, "0035", 10)].Data[0]++
+		}, []string{"Elapsed_Time"}},
+		{"CSAFE Response", func(entries []capture.Entry) {
+			// the distance answered to GETHORIZONTAL
+			i := find(entries, capture.EVENT_NOTIFY, "0022", 4)
+			dec := csafe.Decoder{}
+			status, rsps, err := dec.DecodeResponse(entries[i].Data)
+			assert.NoError(t, err)
+			assert.Equal(t, byte(csafe.GETHORIZONTAL_CMD), rsps[1].ID)
+			rsps[1].Data[0]++
+			enc := csafe.Encoder{}
+			entries[i].Data, _ = enc.EncodeCommandResponses(status, rsps)
+		}, []string{"0xa1"}},
+		{"Read", func(entries []capture.Entry) {
+			entries[find(entries, capture.EVENT_READ, "0011", 0)].Data = []byte("PM4")
+		}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := loadSession(t)
+			tt.tamper(entries)
+
+			report := Run(entries, Options{})
+			assert.False(t, report.Passed())
+			assert.Len(t, report.Mismatches, 1, report.String())
+
+			if tt.ignore != nil {
+				report = Run(entries, Options{Ignore: tt.ignore})
+				assert.True(t, report.Passed(), report.String())
+			}
+		})
+	}
+}
+
+func TestRun_Slack(t *testing.T) {
+	entries := loadSession(t)
+	// the last stroke data notification before unsubscribing was not sent
+	last := find(entries, capture.EVENT_UNSUBSCRIBE, "0035", 0)
+	for i := last; i >= 0; i-- {
+		if entries[i].Event == capture.EVENT_NOTIFY && entries[i].Char[4:8] == "0035" {
+			entries = append(entries[:i], entries[i+1:]...)
+			break
+		}
+	}
+	assert.True(t, Run(entries, Options{}).Passed())
+	assert.False(t, Run(entries, Options{Slack: time.Millisecond}).Passed())
+}
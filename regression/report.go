@@ -0,0 +1,53 @@
+package regression
+
+import (
+	"fmt"
+	"pm5-emulator/capture"
+	"strings"
+	"time"
+)
+
+//Mismatch is a difference between the capture and the replay
+type Mismatch struct {
+	At      time.Duration `json:"at"` // since the start of the capture
+	Central string        `json:"central,omitempty"`
+	Char    string        `json:"char,omitempty"`
+	Event   string        `json:"event"`
+	Want    capture.Bytes `json:"want,omitempty"`
+	Got     capture.Bytes `json:"got,omitempty"`
+	Detail  string        `json:"detail,omitempty"`
+}
+
+//Report lists the differences found by a replay
+type Report struct {
+	Entries    int        `json:"entries"`  // number of entries in the capture
+	Compared   int        `json:"compared"` // number of reads and notifications compared
+	Mismatches []Mismatch `json:"mismatches"`
+}
+
+//Passed returns true if the emulator answered as recorded
+func (r *Report) Passed() bool {
+	return len(r.Mismatches) == 0
+}
+
+//String formats the report with a line per mismatch and a verdict
+func (r *Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d entries replayed, %d reads and notifications compared\n", r.Entries, r.Compared)
+	for _, m := range r.Mismatches {
+		fmt.Fprintf(&b, "FAIL %10s  %s %s %s", m.At.Round(time.Millisecond), m.Event, m.Central, m.Char)
+		if m.Detail != "" {
+			fmt.Fprintf(&b, ": %s", m.Detail)
+		}
+		if m.Want != nil || m.Got != nil {
+			fmt.Fprintf(&b, " want [% x] got [% x]", []byte(m.Want), []byte(m.Got))
+		}
+		b.WriteString("\n")
+	}
+	if r.Passed() {
+		b.WriteString("PASSED\n")
+	} else {
+		b.WriteString("FAILED\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,346 @@
+package regression
+
+import (
+	"bytes"
+	"fmt"
+	"pm5-emulator/capture"
+	"pm5-emulator/clock"
+	"pm5-emulator/emulator"
+	"pm5-emulator/protocol/csafe"
+	"pm5-emulator/service"
+	"pm5-emulator/service/mux"
+	"pm5-emulator/transport"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bettercap/gatt"
+)
+
+//DEFAULT_SLACK is the default Options.Slack, the longest period the rowing
+//characteristics notify at
+const DEFAULT_SLACK = time.Second
+
+//Options are the settings of a replay
+type Options struct {
+	//Seed and Persona of the emulator, those the capture was recorded with
+	//when zero
+	Seed    int64
+	Persona string
+
+	//Ignore lists the fields left out of the comparison: the mux field names
+	//of the rowing characteristics, such as Elapsed_Time, the 0x prefixed
+	//identifiers of the csafe commands whose response data is ignored, or
+	//"status" for the status byte of the csafe responses
+	Ignore []string
+
+	//Slack is how close to the end of a subscription a notification found in
+	//only one of the capture and the replay is tolerated, DEFAULT_SLACK when 0.
+	//Subscriptions end at slightly different times on a live connection.
+	Slack time.Duration
+}
+
+//notification is a notification sent to a central
+type notification struct {
+	at   time.Duration
+	data []byte
+}
+
+//key identifies the characteristic of a central
+type key struct {
+	central string
+	char    string
+}
+
+//runner plays the writes of a capture as the central and compares the
+//emulator's responses with the recorded ones
+type runner struct {
+	opts     Options
+	start    time.Time
+	clock    *clock.Manual
+	loopback *transport.Loopback
+	centrals map[string]*transport.Central
+	tx       string // characteristic the csafe responses are notified on
+
+	want   map[key][]notification
+	got    map[key][]notification
+	ends   map[key][]time.Duration // times the subscriptions ended
+	keys   []key                   // in the order of their first notification
+	report *Report
+}
+
+//Run replays the capture entries against an offline emulator on a manual
+//clock, sending the recorded writes with their original timing, and reports
+//where the reads and notifications of the emulator differ from the recorded ones
+func Run(entries []capture.Entry, opts Options) *Report {
+	report := &Report{Entries: len(entries)}
+	if len(entries) == 0 {
+		return report
+	}
+	if opts.Slack == 0 {
+		opts.Slack = DEFAULT_SLACK
+	}
+	if start := entries[0]; start.Event == capture.EVENT_START {
+		if opts.Seed == 0 {
+			opts.Seed = start.Seed
+		}
+		if opts.Persona == "" {
+			opts.Persona = start.Persona
+		}
+	}
+
+	c := clock.NewManual(entries[0].Time)
+	em, err := emulator.NewOfflineEmulator(emulator.Config{Clock: c, Seed: opts.Seed, Persona: opts.Persona})
+	if err != nil {
+		report.Mismatches = append(report.Mismatches, Mismatch{Event: capture.EVENT_START, Detail: err.Error()})
+		return report
+	}
+	em.Start()
+	_, tx := service.ControlCharacteristics()
+	r := &runner{
+		opts:     opts,
+		start:    entries[0].Time,
+		clock:    c,
+		loopback: transport.NewLoopback(em.Services()...),
+		centrals: make(map[string]*transport.Central),
+		tx:       tx.String(),
+		want:     make(map[key][]notification),
+		got:      make(map[key][]notification),
+		ends:     make(map[key][]time.Duration),
+		report:   report,
+	}
+
+	for _, e := range entries {
+		if d := e.Time.Sub(c.Now()); d > 0 {
+			c.Advance(d)
+		}
+		if err := r.play(e); err != nil {
+			r.mismatch(Mismatch{At: r.elapsed(), Central: e.Central, Char: e.Char, Event: e.Event, Detail: err.Error()})
+		}
+	}
+	for _, k := range r.keys {
+		r.ends[k] = append(r.ends[k], r.elapsed())
+		r.compare(k)
+	}
+	return report
+}
+
+//elapsed returns the time since the start of the capture
+func (r *runner) elapsed() time.Duration {
+	return r.clock.Now().Sub(r.start)
+}
+
+//play plays an entry of the capture
+func (r *runner) play(e capture.Entry) error {
+	if e.Event == capture.EVENT_START {
+		return nil
+	}
+	if e.Event == capture.EVENT_CONNECT {
+		if _, ok := r.centrals[e.Central]; ok {
+			return fmt.Errorf("central %s connected twice", e.Central)
+		}
+		r.centrals[e.Central] = r.loopback.Connect(e.Central, e.MTU)
+		return nil
+	}
+
+	c, ok := r.centrals[e.Central]
+	if !ok && e.Event == capture.EVENT_UNSUBSCRIBE {
+		// subscriptions are reported to end once the central disconnected
+		return nil
+	}
+	if !ok {
+		// captures started while connected
+		c = r.loopback.Connect(e.Central, 0)
+		r.centrals[e.Central] = c
+	}
+	var u gatt.UUID
+	if e.Char != "" {
+		var err error
+		if u, err = gatt.ParseUUID(e.Char); err != nil {
+			return err
+		}
+	}
+
+	k := key{central: e.Central, char: e.Char}
+	switch e.Event {
+	case capture.EVENT_DISCONNECT:
+		for _, k := range r.keys {
+			if k.central == e.Central {
+				r.ends[k] = append(r.ends[k], r.elapsed())
+			}
+		}
+		delete(r.centrals, e.Central)
+		return c.Close()
+	case capture.EVENT_WRITE:
+		return c.Write(u, e.Data)
+	case capture.EVENT_SUBSCRIBE:
+		r.track(k)
+		return c.Subscribe(u, func(data []byte) {
+			r.got[k] = append(r.got[k], notification{at: r.elapsed(), data: append([]byte(nil), data...)})
+		})
+	case capture.EVENT_UNSUBSCRIBE:
+		r.ends[k] = append(r.ends[k], r.elapsed())
+		return c.Unsubscribe(u)
+	case capture.EVENT_READ:
+		data, err := c.Read(u)
+		if err != nil {
+			return err
+		}
+		r.report.Compared++
+		if !bytes.Equal(r.mask(e.Char, e.Data), r.mask(e.Char, data)) {
+			r.mismatch(Mismatch{At: r.elapsed(), Central: e.Central, Char: e.Char, Event: e.Event, Want: e.Data, Got: data})
+		}
+		return nil
+	case capture.EVENT_NOTIFY:
+		r.track(k)
+		r.want[k] = append(r.want[k], notification{at: r.elapsed(), data: e.Data})
+		return nil
+	}
+	return fmt.Errorf("unknown event %q", e.Event)
+}
+
+//track keeps the order the notified characteristics are compared in
+func (r *runner) track(k key) {
+	if _, ok := r.want[k]; ok {
+		return
+	}
+	if _, ok := r.got[k]; ok {
+		return
+	}
+	r.want[k] = nil
+	r.keys = append(r.keys, k)
+}
+
+//compare compares the notifications of a characteristic in order, the csafe
+//responses frame by frame
+func (r *runner) compare(k key) {
+	want, got := r.want[k], r.got[k]
+	if k.char == r.tx {
+		want, got = frames(want), frames(got)
+	}
+
+	for i := 0; i < len(want) || i < len(got); i++ {
+		m := Mismatch{Central: k.central, Char: k.char, Event: capture.EVENT_NOTIFY}
+		switch {
+		case i >= len(got):
+			if r.ending(k, want[i].at) {
+				continue
+			}
+			m.At, m.Want, m.Detail = want[i].at, want[i].data, "missing notification"
+		case i >= len(want):
+			if r.ending(k, got[i].at) {
+				continue
+			}
+			m.At, m.Got, m.Detail = got[i].at, got[i].data, "unexpected notification"
+		default:
+			r.report.Compared++
+			if bytes.Equal(r.mask(k.char, want[i].data), r.mask(k.char, got[i].data)) {
+				continue
+			}
+			m.At, m.Want, m.Got = want[i].at, want[i].data, got[i].data
+		}
+		r.mismatch(m)
+	}
+}
+
+//ending returns true when a subscription to the characteristic ended within
+//the slack after at
+func (r *runner) ending(k key, at time.Duration) bool {
+	for _, end := range r.ends[k] {
+		if end >= at && end-at <= r.opts.Slack {
+			return true
+		}
+	}
+	return false
+}
+
+//mismatch adds a mismatch to the report
+func (r *runner) mismatch(m Mismatch) {
+	r.report.Mismatches = append(r.report.Mismatches, m)
+}
+
+//mask returns data with the ignored fields cleared
+func (r *runner) mask(char string, data []byte) []byte {
+	if len(r.opts.Ignore) == 0 {
+		return data
+	}
+	if char == r.tx {
+		return r.maskFrame(data)
+	}
+	if len(char) < 8 {
+		return data
+	}
+	id, err := strconv.ParseUint(char[4:8], 16, 16)
+	if err != nil {
+		return data
+	}
+
+	p := mux.ParseNotification(int(id), data)
+	if p == nil {
+		return data
+	}
+	for _, field := range r.opts.Ignore {
+		p.Set(field, 0)
+	}
+	if id == mux.MULTIPLEXED {
+		return p.Multiplexed()
+	}
+	return p.Bytes()
+}
+
+//maskFrame returns a csafe response frame decoded with the ignored status and
+//command responses cleared, the frame itself when it does not decode
+func (r *runner) maskFrame(frame []byte) []byte {
+	dec := csafe.Decoder{}
+	status, rsps, err := dec.DecodeResponse(frame)
+	if err != nil {
+		return frame
+	}
+	var b []byte
+	for _, ignore := range r.opts.Ignore {
+		if ignore == "status" {
+			status = 0
+		}
+	}
+	b = append(b, status)
+	for _, rsp := range rsps {
+		b = append(b, rsp.ID, byte(len(rsp.Data)))
+		if !r.ignored(rsp.ID) {
+			b = append(b, rsp.Data...)
+		}
+	}
+	return b
+}
+
+//ignored returns true when the response data of the csafe command id is ignored
+func (r *runner) ignored(id byte) bool {
+	for _, ignore := range r.opts.Ignore {
+		if !strings.HasPrefix(ignore, "0x") {
+			continue
+		}
+		if v, err := strconv.ParseUint(ignore, 0, 8); err == nil && byte(v) == id {
+			return true
+		}
+	}
+	return false
+}
+
+//frames joins notifications split to fit the MTU into whole csafe frames
+func frames(ns []notification) []notification {
+	var out []notification
+	var cur *notification
+	for _, n := range ns {
+		if cur == nil {
+			cur = &notification{at: n.at}
+		}
+		cur.data = append(cur.data, n.data...)
+		if len(n.data) > 0 && n.data[len(n.data)-1] == csafe.FRAME_END_BYTE {
+			out = append(out, *cur)
+			cur = nil
+		}
+	}
+	if cur != nil {
+		out = append(out, *cur)
+	}
+	return out
+}
@@ -0,0 +1,126 @@
+package replay
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//csvColumns are the words identifying the columns of the stroke data
+//exported by the Concept2 logbook and ErgData, by order of preference
+var csvColumns = map[string][]string{
+	"time":        {"time"},
+	"distance":    {"distance"},
+	"pace":        {"pace"},
+	"power":       {"watts", "power"},
+	"stroke_rate": {"stroke rate", "spm", "cadence"},
+	"heart_rate":  {"heart rate", "hr"},
+}
+
+//ReadCSV reads the stroke data of a workout exported as CSV, one stroke per row
+func ReadCSV(r io.Reader) (*Recording, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+	columns := csvHeader(header)
+	if _, ok := columns["time"]; !ok {
+		return nil, fmt.Errorf("no time column")
+	}
+
+	rec := &Recording{}
+	for line := 2; ; line++ {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var s Sample
+		if s.Time, err = parseTime(field(row, columns, "time")); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if s.Pace, err = parseTime(field(row, columns, "pace")); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if s.Distance, err = parseNumber(field(row, columns, "distance")); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		ints := []struct {
+			column string
+			value  *int
+		}{
+			{"power", &s.Power},
+			{"stroke_rate", &s.StrokeRate},
+			{"heart_rate", &s.HeartRate},
+		}
+		for _, n := range ints {
+			v, err := parseNumber(field(row, columns, n.column))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			*n.value = int(math.Round(v))
+		}
+		rec.Samples = append(rec.Samples, s)
+	}
+	return rec, rec.normalize()
+}
+
+//csvHeader returns the index of the known columns of a header
+func csvHeader(header []string) map[string]int {
+	columns := map[string]int{}
+	for name, words := range csvColumns {
+	search:
+		for _, word := range words {
+			for i, h := range header {
+				h = strings.ToLower(h)
+				// "Time (seconds)" is the time column, "Cal/Hr" is not the heart rate
+				if h == word || strings.HasPrefix(h, word+" ") {
+					columns[name] = i
+					break search
+				}
+			}
+		}
+	}
+	return columns
+}
+
+//field returns the value of a column of a row, empty when missing
+func field(row []string, columns map[string]int, name string) string {
+	i, ok := columns[name]
+	if !ok || i >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[i])
+}
+
+//parseNumber parses a number, zero when empty
+func parseNumber(s string) (float64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+//parseTime parses a time in seconds or as [h:]mm:ss.t, zero when empty
+func parseTime(s string) (time.Duration, error) {
+	var seconds float64
+	for _, part := range strings.Split(s, ":") {
+		v, err := parseNumber(part)
+		if err != nil {
+			return 0, fmt.Errorf("bad time %q", s)
+		}
+		seconds = seconds*60 + v
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
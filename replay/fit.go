@@ -0,0 +1,257 @@
+package replay
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+//FIT messages and fields read from workout files
+const (
+	FIT_MESG_LAP    = 19
+	FIT_MESG_RECORD = 20
+
+	FIT_FIELD_TIMESTAMP      = 253 // s since the FIT epoch
+	FIT_FIELD_HEART_RATE     = 3   // bpm
+	FIT_FIELD_CADENCE        = 4   // strokes per minute
+	FIT_FIELD_DISTANCE       = 5   // 0.01 m
+	FIT_FIELD_SPEED          = 6   // 0.001 m/s
+	FIT_FIELD_POWER          = 7   // watts
+	FIT_FIELD_ENHANCED_SPEED = 73  // 0.001 m/s
+)
+
+//errFITHeader is returned for files that do not start with a FIT header
+var errFITHeader = errors.New("not a FIT file")
+
+//fitField is a field of a FIT definition message
+type fitField struct {
+	num  byte
+	size int
+}
+
+//fitDefinition describes the data messages of a local message type
+type fitDefinition struct {
+	global    uint16
+	order     binary.ByteOrder
+	fields    []fitField
+	devFields int // bytes of developer fields, skipped
+}
+
+//fitReader decodes the messages of a FIT file
+type fitReader struct {
+	r         *bufio.Reader
+	left      int // bytes of messages left to read
+	defs      [16]*fitDefinition
+	timestamp uint32 // last timestamp read, for compressed timestamp headers
+}
+
+//ReadFIT reads a workout recorded as a FIT activity, one stroke per record
+//message and one split per lap message
+func ReadFIT(r io.Reader) (*Recording, error) {
+	fr := &fitReader{r: bufio.NewReader(r)}
+	if err := fr.header(); err != nil {
+		return nil, err
+	}
+
+	rec := &Recording{}
+	var start uint32
+	for fr.left > 0 {
+		global, values, err := fr.message()
+		if err != nil {
+			return nil, err
+		}
+		if values == nil {
+			continue
+		}
+
+		ts, ok := values[FIT_FIELD_TIMESTAMP]
+		if !ok {
+			continue
+		}
+		if start == 0 {
+			start = uint32(ts)
+		}
+		at := time.Duration(uint32(ts)-start) * time.Second
+
+		switch global {
+		case FIT_MESG_RECORD:
+			s := Sample{
+				Time:       at,
+				Distance:   float64(values[FIT_FIELD_DISTANCE]) / 100,
+				Power:      int(values[FIT_FIELD_POWER]),
+				StrokeRate: int(values[FIT_FIELD_CADENCE]),
+				HeartRate:  int(values[FIT_FIELD_HEART_RATE]),
+			}
+			speed := values[FIT_FIELD_ENHANCED_SPEED]
+			if speed == 0 {
+				speed = values[FIT_FIELD_SPEED]
+			}
+			if speed > 0 {
+				s.Pace = time.Duration(500 * 1000 / float64(speed) * float64(time.Second))
+			}
+			rec.Samples = append(rec.Samples, s)
+		case FIT_MESG_LAP:
+			rec.Splits = append(rec.Splits, at)
+		}
+	}
+	return rec, rec.normalize()
+}
+
+//header reads the file header
+func (fr *fitReader) header() error {
+	size, err := fr.r.ReadByte()
+	if err != nil {
+		return err
+	}
+	if size < 12 {
+		return errFITHeader
+	}
+	h := make([]byte, size-1)
+	if _, err := io.ReadFull(fr.r, h); err != nil {
+		return err
+	}
+	if string(h[7:11]) != ".FIT" {
+		return errFITHeader
+	}
+	fr.left = int(binary.LittleEndian.Uint32(h[3:7]))
+	return nil
+}
+
+//read reads n bytes of messages
+func (fr *fitReader) read(n int) ([]byte, error) {
+	if n > fr.left {
+		return nil, fmt.Errorf("message past the end of the data")
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(fr.r, b); err != nil {
+		return nil, err
+	}
+	fr.left -= n
+	return b, nil
+}
+
+//message reads the next message. Definition messages are kept and return
+//nil values, data messages return the global message number and the values
+//of their invalid-free numeric fields.
+func (fr *fitReader) message() (uint16, map[byte]uint64, error) {
+	b, err := fr.read(1)
+	if err != nil {
+		return 0, nil, err
+	}
+	h := b[0]
+
+	switch {
+	case h&0x80 != 0:
+		// compressed timestamp header, the offset rolls over every 32s
+		local, offset := (h>>5)&0x03, uint32(h&0x1F)
+		ts := fr.timestamp&^0x1F + offset
+		if offset < fr.timestamp&0x1F {
+			ts += 0x20
+		}
+		values, err := fr.data(local)
+		if err != nil || values == nil {
+			return 0, values, err
+		}
+		values[FIT_FIELD_TIMESTAMP] = uint64(ts)
+		fr.timestamp = ts
+		return fr.defs[local].global, values, nil
+	case h&0x40 != 0:
+		return 0, nil, fr.definition(h&0x0F, h&0x20 != 0)
+	default:
+		values, err := fr.data(h & 0x0F)
+		if err != nil || values == nil {
+			return 0, values, err
+		}
+		if ts, ok := values[FIT_FIELD_TIMESTAMP]; ok {
+			fr.timestamp = uint32(ts)
+		}
+		return fr.defs[h&0x0F].global, values, nil
+	}
+}
+
+//definition reads a definition message for a local message type
+func (fr *fitReader) definition(local byte, dev bool) error {
+	b, err := fr.read(5)
+	if err != nil {
+		return err
+	}
+	def := &fitDefinition{order: binary.LittleEndian}
+	if b[1] == 1 {
+		def.order = binary.BigEndian
+	}
+	def.global = def.order.Uint16(b[2:4])
+
+	fields, err := fr.read(3 * int(b[4]))
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(fields); i += 3 {
+		def.fields = append(def.fields, fitField{num: fields[i], size: int(fields[i+1])})
+	}
+
+	if dev {
+		n, err := fr.read(1)
+		if err != nil {
+			return err
+		}
+		devFields, err := fr.read(3 * int(n[0]))
+		if err != nil {
+			return err
+		}
+		for i := 0; i < len(devFields); i += 3 {
+			def.devFields += int(devFields[i+1])
+		}
+	}
+	fr.defs[local] = def
+	return nil
+}
+
+//data reads a data message of a local message type, nil values are returned
+//for the messages that are not read from workout files
+func (fr *fitReader) data(local byte) (map[byte]uint64, error) {
+	def := fr.defs[local]
+	if def == nil {
+		return nil, fmt.Errorf("data message of undefined local type %d", local)
+	}
+
+	size := def.devFields
+	for _, f := range def.fields {
+		size += f.size
+	}
+	b, err := fr.read(size)
+	if err != nil {
+		return nil, err
+	}
+	if def.global != FIT_MESG_RECORD && def.global != FIT_MESG_LAP {
+		return nil, nil
+	}
+
+	values := map[byte]uint64{}
+	for _, f := range def.fields {
+		v, ok := fitValue(b[:f.size], def.order)
+		if ok {
+			values[f.num] = v
+		}
+		b = b[f.size:]
+	}
+	return values, nil
+}
+
+//fitValue decodes an unsigned field of 1, 2 or 4 bytes, false when it holds
+//the invalid value or has another size
+func fitValue(b []byte, order binary.ByteOrder) (uint64, bool) {
+	switch len(b) {
+	case 1:
+		return uint64(b[0]), b[0] != 0xFF
+	case 2:
+		v := order.Uint16(b)
+		return uint64(v), v != 0xFFFF
+	case 4:
+		v := order.Uint32(b)
+		return uint64(v), v != 0xFFFFFFFF
+	}
+	return 0, false
+}
@@ -0,0 +1,186 @@
+package replay
+
+import (
+	"errors"
+	"pm5-emulator/clock"
+	"pm5-emulator/config"
+	"pm5-emulator/sim"
+	"pm5-emulator/sm"
+	"pm5-emulator/user"
+	"pm5-emulator/workout"
+	"sync"
+	"time"
+)
+
+var (
+	errNotPlaying = errors.New("no recording is being played")
+	errNotPaused  = errors.New("the recording is not paused")
+)
+
+//Player plays a recording as the live data of the workout of the emulated
+//PM. The recording starts rowing from READY or IDLE, pauses with the state
+//machine and finishes it once played.
+type Player struct {
+	mu       sync.Mutex
+	rec      *Recording
+	stm      *sm.StateMachine
+	workout  *workout.Workout
+	user     *user.User
+	clock    clock.Clock
+	ticker   clock.Timer
+	elapsed  time.Duration // time played
+	next     int           // next sample to play
+	split    int           // next split to end
+	calories float64       // calories burned, kept unrounded between steps
+	done     bool
+}
+
+//NewPlayer returns a player of rec for the workout w, driven by stm, burning
+//calories according to the profile of u and playing on the clock c
+func NewPlayer(rec *Recording, stm *sm.StateMachine, w *workout.Workout, u *user.User, c clock.Clock) *Player {
+	p := &Player{
+		rec:     rec,
+		stm:     stm,
+		workout: w,
+		user:    u,
+		clock:   c,
+	}
+	stm.OnTransition(p.onTransition)
+	return p
+}
+
+//onTransition restarts the recording when a new workout starts
+func (p *Player) onTransition(from, to string) {
+	if to == config.PM5_STATE_INUSE && from != config.PM5_STATE_PAUSED {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.elapsed, p.next, p.split, p.calories = 0, 0, 0, 0
+		p.workout.SetManualSplits(len(p.rec.Splits) > 0)
+	}
+}
+
+//Start plays the recording every TICK of the clock until Stop is called
+func (p *Player) Start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ticker = clock.Every(p.clock, sim.TICK, func() { p.Step(sim.TICK) })
+}
+
+//Stop stops playing
+func (p *Player) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ticker != nil {
+		p.ticker.Stop()
+	}
+}
+
+//Pause pauses the recording being played, pausing the workout in use
+func (p *Player) Pause() error {
+	if p.stm.GetStateName() != config.PM5_STATE_INUSE {
+		return errNotPlaying
+	}
+	p.stm.SetState(config.PM5_STATE_PAUSED)
+	return nil
+}
+
+//Resume plays the recording again from where Pause paused it
+func (p *Player) Resume() error {
+	if p.stm.GetStateName() != config.PM5_STATE_PAUSED {
+		return errNotPaused
+	}
+	p.stm.SetState(config.PM5_STATE_INUSE)
+	return nil
+}
+
+//Done returns true once the recording has been played to its end
+func (p *Player) Done() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.done
+}
+
+//Step plays dt of the recording
+func (p *Player) Step(dt time.Duration) {
+	switch p.stm.GetStateName() {
+	case config.PM5_STATE_READY, config.PM5_STATE_IDLE:
+		if !p.Done() {
+			p.stm.Update(config.CSAFE_GOINUSE_CMD)
+		}
+		return
+	case config.PM5_STATE_INUSE:
+		p.stm.Active()
+	default:
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.elapsed += dt
+	weight := p.user.Profile().Weight
+	var finished, played bool
+	var curve []float64 // force curve of the last stroke played
+	p.workout.Update(func(m *workout.Metrics) {
+		for p.next < len(p.rec.Samples) && p.rec.Samples[p.next].Time <= p.elapsed {
+			s := p.rec.Samples[p.next]
+			p.play(m, s)
+			p.next++
+			played = true
+		}
+		if played {
+			curve = sim.StrokeForceCurve(m.StrokeRate, m.Power, 0)
+		}
+		m.ElapsedTime = p.elapsed
+		if end := p.rec.Duration(); m.ElapsedTime > end {
+			m.ElapsedTime = end
+		}
+
+		p.calories += sim.CaloriesPerHour(m.Power, weight) * dt.Hours()
+		m.Calories = int(p.calories)
+		finished = p.next == len(p.rec.Samples)
+	})
+
+	if played {
+		p.workout.SetForceCurve(curve)
+	}
+	for p.split < len(p.rec.Splits) && p.rec.Splits[p.split] <= p.elapsed {
+		p.workout.EndSplit()
+		p.split++
+	}
+
+	if finished {
+		p.done = true
+		p.stm.Update(config.CSAFE_GOFINISHED_CMD)
+	}
+}
+
+//play sets the metrics to a recorded stroke
+func (p *Player) play(m *workout.Metrics, s Sample) {
+	prev := workout.Metrics{}
+	if p.next > 0 {
+		prev.ElapsedTime = p.rec.Samples[p.next-1].Time
+		prev.Distance = p.rec.Samples[p.next-1].Distance
+	}
+
+	var speed float64
+	switch {
+	case s.Pace > 0:
+		speed = 500 / s.Pace.Seconds()
+	case s.Power > 0:
+		speed = sim.SpeedForPower(s.Power)
+	case s.Time > prev.ElapsedTime:
+		speed = (s.Distance - prev.Distance) / (s.Time - prev.ElapsedTime).Seconds()
+	}
+	power := s.Power
+	if power == 0 && s.Pace > 0 {
+		power = sim.PowerForPace(s.Pace)
+	}
+
+	m.Distance = s.Distance
+	m.Speed = speed
+	m.Power = power
+	m.StrokeRate = s.StrokeRate
+	m.HeartRate = s.HeartRate
+	m.StrokeCount = p.next + 1
+}
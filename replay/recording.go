@@ -0,0 +1,76 @@
+package replay
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+//Sample holds the values of a recorded workout at the end of one stroke
+type Sample struct {
+	Time       time.Duration // since the start of the workout
+	Distance   float64       // meters since the start of the workout
+	Pace       time.Duration // per 500m, 0 when not recorded
+	Power      int           // watts
+	StrokeRate int           // strokes per minute
+	HeartRate  int           // beats per minute, 0 when not recorded
+}
+
+//Recording is a workout recorded stroke by stroke
+type Recording struct {
+	Samples []Sample
+	Splits  []time.Duration // times the recorded splits ended at, none when not recorded
+}
+
+//Duration returns the length of the recording
+func (r *Recording) Duration() time.Duration {
+	if len(r.Samples) == 0 {
+		return 0
+	}
+	return r.Samples[len(r.Samples)-1].Time
+}
+
+//loaders decode the recordings by file extension
+var loaders = map[string]func(f *os.File) (*Recording, error){
+	".csv": func(f *os.File) (*Recording, error) { return ReadCSV(f) },
+	".tcx": func(f *os.File) (*Recording, error) { return ReadTCX(f) },
+	".fit": func(f *os.File) (*Recording, error) { return ReadFIT(f) },
+}
+
+//Load reads a CSV, TCX or FIT workout file
+func Load(path string) (*Recording, error) {
+	load, ok := loaders[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return nil, fmt.Errorf("replay: unsupported workout file %s", path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	rec, err := load(f)
+	if err != nil {
+		return nil, fmt.Errorf("replay: %s: %v", path, err)
+	}
+	return rec, nil
+}
+
+//normalize sorts the samples and splits and checks the recording holds strokes
+func (r *Recording) normalize() error {
+	if len(r.Samples) == 0 {
+		return fmt.Errorf("no strokes recorded")
+	}
+	sort.SliceStable(r.Samples, func(i, j int) bool { return r.Samples[i].Time < r.Samples[j].Time })
+	sort.Slice(r.Splits, func(i, j int) bool { return r.Splits[i] < r.Splits[j] })
+
+	// the end of the last split is the end of the workout
+	if n := len(r.Splits); n > 0 && r.Splits[n-1] >= r.Duration()-time.Second {
+		r.Splits = r.Splits[:n-1]
+	}
+	return nil
+}
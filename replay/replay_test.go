@@ -0,0 +1,151 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/binary"
+	"pm5-emulator/clock"
+	"pm5-emulator/config"
+	"pm5-emulator/sm"
+	"pm5-emulator/user"
+	"pm5-emulator/workout"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad_CSV(t *testing.T) {
+	rec, err := Load("testdata/strokes.csv")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []Sample{
+		{Time: 2500 * time.Millisecond, Distance: 10.4, Pace: 2 * time.Minute, Power: 203, StrokeRate: 24, HeartRate: 120},
+		{Time: 5 * time.Second, Distance: 20.8, Pace: 2 * time.Minute, Power: 203, StrokeRate: 24, HeartRate: 124},
+		{Time: 7500 * time.Millisecond, Distance: 31.2, Pace: 2 * time.Minute, Power: 203, StrokeRate: 24},
+		{Time: 10 * time.Second, Distance: 41.7, Pace: 2 * time.Minute, Power: 203, StrokeRate: 24, HeartRate: 131},
+	}, rec.Samples)
+	assert.Empty(t, rec.Splits)
+	assert.Equal(t, 10*time.Second, rec.Duration())
+}
+
+func TestLoad_TCX(t *testing.T) {
+	rec, err := Load("testdata/laps.tcx")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []Sample{
+		{Time: 2 * time.Second, Distance: 10, Pace: 100 * time.Second, Power: 350, StrokeRate: 30, HeartRate: 110},
+		{Time: 4 * time.Second, Distance: 20, Pace: 100 * time.Second, Power: 350, StrokeRate: 30, HeartRate: 115},
+		{Time: 6 * time.Second, Distance: 30, Pace: 100 * time.Second, Power: 350, StrokeRate: 28},
+	}, rec.Samples)
+	// the end of the last lap is the end of the workout
+	assert.Equal(t, []time.Duration{4 * time.Second}, rec.Splits)
+}
+
+//fitFile builds a FIT file with a record message per sample and a lap message per split
+func fitFile(samples []Sample, splits []time.Duration) []byte {
+	var data bytes.Buffer
+	const start = 1000000000
+	// record definition on local type 0: timestamp, distance, speed, power, cadence, heart rate
+	data.Write([]byte{0x40, 0, 0, FIT_MESG_RECORD, 0, 6,
+		FIT_FIELD_TIMESTAMP, 4, 0x86, FIT_FIELD_DISTANCE, 4, 0x86, FIT_FIELD_SPEED, 2, 0x84,
+		FIT_FIELD_POWER, 2, 0x84, FIT_FIELD_CADENCE, 1, 0x02, FIT_FIELD_HEART_RATE, 1, 0x02})
+	// lap definition on local type 1, big endian: timestamp
+	data.Write([]byte{0x41, 0, 1, 0, FIT_MESG_LAP, 1, FIT_FIELD_TIMESTAMP, 4, 0x86})
+	for i, s := range samples {
+		hr := byte(s.HeartRate)
+		if hr == 0 {
+			hr = 0xFF
+		}
+		if i == 0 {
+			data.WriteByte(0x00)
+			binary.Write(&data, binary.LittleEndian, uint32(start+s.Time/time.Second))
+		} else {
+			// compressed timestamp header on local type 0
+			data.WriteByte(0x80 | byte(start+s.Time/time.Second)&0x1F)
+			binary.Write(&data, binary.LittleEndian, uint32(0)) // timestamp field still sent, overridden
+		}
+		binary.Write(&data, binary.LittleEndian, uint32(s.Distance*100))
+		binary.Write(&data, binary.LittleEndian, uint16(500*1000/s.Pace.Seconds()))
+		binary.Write(&data, binary.LittleEndian, uint16(s.Power))
+		data.Write([]byte{byte(s.StrokeRate), hr})
+	}
+	for _, at := range splits {
+		data.WriteByte(0x01)
+		binary.Write(&data, binary.BigEndian, uint32(start+at/time.Second))
+	}
+
+	header := []byte{12, 0x10, 0, 0, 0, 0, 0, 0, '.', 'F', 'I', 'T'}
+	binary.LittleEndian.PutUint32(header[4:8], uint32(data.Len()))
+	return append(header, data.Bytes()...)
+}
+
+func TestReadFIT(t *testing.T) {
+	samples := []Sample{
+		{Time: 0, Distance: 10, Pace: 100 * time.Second, Power: 350, StrokeRate: 30, HeartRate: 110},
+		{Time: 2 * time.Second, Distance: 20, Pace: 100 * time.Second, Power: 350, StrokeRate: 30},
+		{Time: 30 * time.Second, Distance: 150, Pace: 100 * time.Second, Power: 350, StrokeRate: 28, HeartRate: 140},
+	}
+	rec, err := ReadFIT(bytes.NewReader(fitFile(samples, []time.Duration{20 * time.Second, 30 * time.Second})))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, samples, rec.Samples)
+	assert.Equal(t, []time.Duration{20 * time.Second}, rec.Splits)
+
+	_, err = ReadFIT(bytes.NewReader([]byte("not a fit file at all")))
+	assert.Error(t, err)
+}
+
+func TestPlayer(t *testing.T) {
+	rec, err := Load("testdata/laps.tcx")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	c := clock.NewManual(time.Time{})
+	stm := sm.NewStateMachine()
+	stm.SetClock(c)
+	stm.Reset()
+	w := workout.New()
+	p := NewPlayer(rec, stm, w, user.New(stm), c)
+	p.Start()
+	defer p.Stop()
+
+	// the recording starts rowing on the first tick
+	c.Advance(100 * time.Millisecond)
+	assert.Equal(t, config.PM5_STATE_INUSE, stm.GetStateName())
+
+	c.Advance(3 * time.Second)
+	m := w.Metrics()
+	assert.Equal(t, 3*time.Second, m.ElapsedTime)
+	assert.Equal(t, 10.0, m.Distance)
+	assert.Equal(t, 350, m.Power)
+	assert.Equal(t, 5.0, m.Speed)
+	assert.Equal(t, 110, m.HeartRate)
+	assert.Equal(t, 1, m.StrokeCount)
+	assert.Empty(t, w.Splits())
+	curve, stroke := w.ForceCurve()
+	assert.Equal(t, 1, stroke)
+	assert.NotEmpty(t, curve)
+
+	// the laps of the recording end the splits
+	c.Advance(4 * time.Second)
+	assert.Equal(t, config.PM5_STATE_FINISHED, stm.GetStateName())
+	assert.True(t, p.Done())
+	m = w.Metrics()
+	assert.Equal(t, 6*time.Second, m.ElapsedTime)
+	assert.Equal(t, 30.0, m.Distance)
+	assert.Equal(t, 3, m.StrokeCount)
+	if splits := w.Splits(); assert.Len(t, splits, 1) {
+		assert.Equal(t, 4*time.Second, splits[0].Time)
+		assert.Equal(t, 20.0, splits[0].Distance)
+		assert.Equal(t, 2, splits[0].StrokeCount)
+	}
+
+	// a recording is played once
+	stm.Reset()
+	c.Advance(time.Second)
+	assert.Equal(t, config.PM5_STATE_READY, stm.GetStateName())
+}
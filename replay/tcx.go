@@ -0,0 +1,62 @@
+package replay
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+//tcx is the part of a Training Center XML file holding the recorded track
+type tcx struct {
+	Laps []struct {
+		StartTime        time.Time `xml:"StartTime,attr"`
+		TotalTimeSeconds float64
+		Track            []struct {
+			Time           time.Time
+			DistanceMeters float64
+			HeartRate      int     `xml:"HeartRateBpm>Value"`
+			Cadence        int     // strokes per minute
+			Speed          float64 `xml:"Extensions>TPX>Speed"`
+			Watts          int     `xml:"Extensions>TPX>Watts"`
+		} `xml:"Track>Trackpoint"`
+	} `xml:"Activities>Activity>Lap"`
+}
+
+//ReadTCX reads a workout exported as Training Center XML, one stroke per
+//trackpoint and one split per lap
+func ReadTCX(r io.Reader) (*Recording, error) {
+	var doc tcx
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	rec := &Recording{}
+	var start time.Time
+	for _, lap := range doc.Laps {
+		// the workout starts with its first lap
+		if start.IsZero() {
+			start = lap.StartTime
+		}
+		for _, p := range lap.Track {
+			if start.IsZero() {
+				start = p.Time
+			}
+			s := Sample{
+				Time:       p.Time.Sub(start),
+				Distance:   p.DistanceMeters,
+				Power:      p.Watts,
+				StrokeRate: p.Cadence,
+				HeartRate:  p.HeartRate,
+			}
+			if p.Speed > 0 {
+				s.Pace = time.Duration(500 / p.Speed * float64(time.Second))
+			}
+			rec.Samples = append(rec.Samples, s)
+		}
+		if !lap.StartTime.IsZero() {
+			end := lap.StartTime.Sub(start) + time.Duration(lap.TotalTimeSeconds*float64(time.Second))
+			rec.Splits = append(rec.Splits, end)
+		}
+	}
+	return rec, rec.normalize()
+}
@@ -0,0 +1,85 @@
+package scenario
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+//Result is the outcome of a step
+type Result struct {
+	Step   int           `json:"step"`
+	At     time.Duration `json:"at"` // scenario time the step ran at
+	Name   string        `json:"name"`
+	Detail string        `json:"detail,omitempty"`
+	Error  string        `json:"error,omitempty"`
+}
+
+//Passed returns true if the step ran as expected
+func (r Result) Passed() bool {
+	return r.Error == ""
+}
+
+//Report lists the outcome of the steps run by a scenario
+type Report struct {
+	Scenario string   `json:"scenario"`
+	Seed     int64    `json:"seed"`
+	Steps    int      `json:"steps"` // number of steps in the scenario
+	Results  []Result `json:"results"`
+	Error    string   `json:"error,omitempty"` // why the scenario was not run
+}
+
+//Passed returns true if every step of the scenario ran as expected
+func (r *Report) Passed() bool {
+	if r.Error != "" || len(r.Results) != r.Steps {
+		return false
+	}
+	for _, res := range r.Results {
+		if !res.Passed() {
+			return false
+		}
+	}
+	return true
+}
+
+//String formats the report with a line per step and a verdict
+func (r *Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "scenario %q, seed %d\n", r.Scenario, r.Seed)
+	if r.Error != "" {
+		fmt.Fprintf(&b, "not run: %s\n", r.Error)
+	}
+	for _, res := range r.Results {
+		status := "PASS"
+		if !res.Passed() {
+			status = "FAIL"
+		}
+		fmt.Fprintf(&b, "%s %3d %10s  %s", status, res.Step, formatTime(res.At), res.Name)
+		if res.Detail != "" {
+			fmt.Fprintf(&b, " (%s)", res.Detail)
+		}
+		if res.Error != "" {
+			fmt.Fprintf(&b, ": %s", res.Error)
+		}
+		b.WriteString("\n")
+	}
+	if skipped := r.Steps - len(r.Results); skipped > 0 {
+		fmt.Fprintf(&b, "%d of %d steps not run\n", skipped, r.Steps)
+	}
+	if r.Passed() {
+		b.WriteString("PASSED\n")
+	} else {
+		b.WriteString("FAILED\n")
+	}
+	return b.String()
+}
+
+//formatTime formats a scenario time as h:mm:ss.t
+func formatTime(d time.Duration) string {
+	d = d.Round(100 * time.Millisecond)
+	h := d / time.Hour
+	m := (d % time.Hour) / time.Minute
+	s := (d % time.Minute) / time.Second
+	t := (d % time.Second) / (100 * time.Millisecond)
+	return fmt.Sprintf("%d:%02d:%02d.%d", h, m, s, t)
+}
@@ -0,0 +1,221 @@
+package scenario
+
+import (
+	"errors"
+	"fmt"
+	"pm5-emulator/clock"
+	"pm5-emulator/emulator"
+	"pm5-emulator/protocol"
+	"pm5-emulator/protocol/csafe"
+	"pm5-emulator/sim"
+	"time"
+)
+
+//START is the time of the clock the scenarios run on
+var START = time.Date(2020, time.January, 1, 8, 0, 0, 0, time.UTC)
+
+//errNotConnected is returned by the steps that need a csafe session when there is none
+var errNotConnected = errors.New("no csafe session is connected")
+
+//metrics are the values a step can expect, by name
+var metrics = map[string]func(em *emulator.Emulator) float64{
+	"elapsed":      func(em *emulator.Emulator) float64 { return em.Metrics().ElapsedTime.Seconds() },
+	"distance":     func(em *emulator.Emulator) float64 { return em.Metrics().Distance },
+	"speed":        func(em *emulator.Emulator) float64 { return em.Metrics().Speed },
+	"pace":         func(em *emulator.Emulator) float64 { return em.Metrics().Pace().Seconds() },
+	"stroke_rate":  func(em *emulator.Emulator) float64 { return float64(em.Metrics().StrokeRate) },
+	"stroke_count": func(em *emulator.Emulator) float64 { return float64(em.Metrics().StrokeCount) },
+	"power":        func(em *emulator.Emulator) float64 { return float64(em.Metrics().Power) },
+	"calories":     func(em *emulator.Emulator) float64 { return float64(em.Metrics().Calories) },
+	"logged":       func(em *emulator.Emulator) float64 { return float64(em.Logged()) },
+}
+
+//runner runs the steps of a scenario against an offline emulator on a
+//manual clock, so that a scenario run with the same seed always ends the same
+type runner struct {
+	scenario Scenario
+	clock    *clock.Manual
+	em       *emulator.Emulator
+	session  protocol.Protocol
+	enc      csafe.Encoder
+	fault    string
+	chance   float64 // probability of the fault
+}
+
+//Run runs a scenario and reports the outcome of its steps. The run stops at
+//the first step whose trigger is never met, an invalid scenario is not run.
+func Run(s Scenario) *Report {
+	report := &Report{Scenario: s.Name, Seed: s.Seed, Steps: len(s.Steps)}
+	if err := s.Validate(); err != nil {
+		report.Error = err.Error()
+		return report
+	}
+
+	c := clock.NewManual(START)
+	em, err := emulator.NewOfflineEmulator(emulator.Config{Clock: c, Seed: s.Seed, Persona: s.Persona})
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	r := &runner{
+		scenario: s,
+		clock:    c,
+		em:       em,
+		fault:    FAULT_NONE,
+	}
+	r.em.Start()
+
+	for i, step := range s.Steps {
+		err := r.wait(step)
+		res := Result{Step: i + 1, At: r.elapsed(), Name: step.String()}
+		if err == nil {
+			res.Detail, err = r.do(step)
+		}
+		if err != nil {
+			res.Error = err.Error()
+		}
+		report.Results = append(report.Results, res)
+
+		if err != nil && errors.Is(err, errTimeout) {
+			break
+		}
+	}
+	return report
+}
+
+//errTimeout is returned when a step waited too long for a state
+var errTimeout = errors.New("timed out")
+
+//elapsed returns the scenario time
+func (r *runner) elapsed() time.Duration {
+	return r.clock.Now().Sub(START)
+}
+
+//wait advances the clock until the trigger of the step is met
+func (r *runner) wait(s Step) error {
+	if at := time.Duration(s.At); at > r.elapsed() {
+		r.clock.Advance(at - r.elapsed())
+	}
+
+	if s.On != "" {
+		timeout := time.Duration(r.scenario.Timeout)
+		if timeout == 0 {
+			timeout = DEFAULT_TIMEOUT
+		}
+		deadline := r.clock.Now().Add(timeout)
+		for r.em.State() != s.On {
+			if !r.clock.Now().Before(deadline) {
+				return fmt.Errorf("%w waiting for state %s", errTimeout, s.On)
+			}
+			r.clock.Advance(sim.TICK)
+		}
+	}
+
+	r.clock.Advance(time.Duration(s.After))
+	return nil
+}
+
+//do runs the action of the step and returns what it observed
+func (r *runner) do(s Step) (string, error) {
+	switch s.Action {
+	case ACTION_CONNECT:
+		if r.session != nil {
+			return "", errors.New("a csafe session is already connected")
+		}
+		r.session = r.em.Connect()
+	case ACTION_DISCONNECT:
+		if r.session == nil {
+			return "", errNotConnected
+		}
+		r.session = nil
+	case ACTION_CSAFE:
+		return r.csafe(s)
+	case ACTION_ROW:
+		if s.Persona != "" {
+			if err := r.em.SetPersona(s.Persona); err != nil {
+				return "", err
+			}
+		}
+		power := s.Power
+		if s.Pace != 0 {
+			power = sim.PowerForPace(time.Duration(s.Pace))
+		}
+		return "", r.em.SetTarget(s.Rate, power)
+	case ACTION_STOP:
+		return "", r.em.StopRowing()
+	case ACTION_RESUME:
+		return "", r.em.ResumeRowing()
+	case ACTION_BUTTON:
+		return "", r.em.PressButton(s.Button)
+	case ACTION_ENTER_ID:
+		return "", r.em.EnterID(s.ID)
+	case ACTION_FAULT:
+		r.fault, r.chance = s.Fault, s.Probability
+		if r.chance == 0 {
+			r.chance = 1
+		}
+	case ACTION_EXPECT:
+		return r.expect(s)
+	}
+	return "", nil
+}
+
+//csafe sends the commands of the step on the session, through the faults
+func (r *runner) csafe(s Step) (string, error) {
+	if r.session == nil {
+		return "", errNotConnected
+	}
+	cmds, _ := s.commands()
+	frame, err := r.enc.Encode(csafe.Packet{Cmds: cmds, JustCmd: true})
+	if err != nil {
+		return "", err
+	}
+
+	detail := ""
+	if r.fault != FAULT_NONE && r.em.Rand().Float64() < r.chance {
+		switch r.fault {
+		case FAULT_DROP:
+			return "dropped", nil
+		case FAULT_CORRUPT:
+			i := r.em.Rand().Intn(len(frame))
+			frame[i] ^= byte(1 + r.em.Rand().Intn(0xFF))
+			detail = fmt.Sprintf("corrupted byte %d", i)
+		}
+	}
+
+	_, err = r.session.ReadPayload(frame)
+	switch {
+	case s.Reject && err == nil:
+		return detail, errors.New("the commands were accepted")
+	case s.Reject:
+		return joinDetail(detail, "rejected: "+err.Error()), nil
+	}
+	return detail, err
+}
+
+//expect checks the state and the metric expected by the step
+func (r *runner) expect(s Step) (string, error) {
+	if s.State != "" {
+		if state := r.em.State(); state != s.State {
+			return "", fmt.Errorf("state is %s", state)
+		}
+	}
+	if s.Metric == "" {
+		return "", nil
+	}
+
+	v := metrics[s.Metric](r.em)
+	detail := fmt.Sprintf("%s %g", s.Metric, v)
+	if (s.Min != nil && v < *s.Min) || (s.Max != nil && v > *s.Max) {
+		return "", fmt.Errorf("%s is out of range", detail)
+	}
+	return detail, nil
+}
+
+//joinDetail joins two observations of a step
+func joinDetail(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + ", " + b
+}
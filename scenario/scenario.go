@@ -0,0 +1,266 @@
+package scenario
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"pm5-emulator/config"
+	"pm5-emulator/emulator"
+	"pm5-emulator/sim"
+	"strings"
+	"time"
+)
+
+//DEFAULT_TIMEOUT is how long a step waits for a state when the scenario sets no timeout
+const DEFAULT_TIMEOUT = time.Hour
+
+//Actions of the scenario steps
+const (
+	ACTION_CONNECT    = "connect"    // open a csafe session
+	ACTION_DISCONNECT = "disconnect" // drop the csafe session
+	ACTION_CSAFE      = "csafe"      // send csafe commands on the session
+	ACTION_ROW        = "row"        // change the stroke rate, pace, power or persona of the athlete
+	ACTION_STOP       = "stop"       // stop rowing, pausing the workout
+	ACTION_RESUME     = "resume"     // row again after stop
+	ACTION_BUTTON     = "button"     // press a button of the PM
+	ACTION_ENTER_ID   = "enter_id"   // type a user ID on the PM keypad
+	ACTION_FAULT      = "fault"      // inject faults in the csafe session
+	ACTION_EXPECT     = "expect"     // check the state or a metric of the emulator
+)
+
+//Faults injected in the frames sent on the csafe session
+const (
+	FAULT_NONE    = "none"    // deliver the frames as sent
+	FAULT_DROP    = "drop"    // lose the frames
+	FAULT_CORRUPT = "corrupt" // change a byte of the frames
+)
+
+//Duration is a time.Duration written as a string such as "1m30s" in scenario files
+type Duration time.Duration
+
+//UnmarshalJSON parses a duration string
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+//MarshalJSON writes a duration string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+//Scenario is a list of steps run against an emulator, each step waits for
+//its trigger then runs its action
+type Scenario struct {
+	Name    string   `json:"name"`
+	Seed    int64    `json:"seed"`
+	Persona string   `json:"persona,omitempty"`
+	Timeout Duration `json:"timeout,omitempty"` // longest wait for a state, DEFAULT_TIMEOUT when 0
+	Steps   []Step   `json:"steps"`
+}
+
+//Step is an action run once its trigger is met. A step waits until the
+//scenario time At, then for the state On, then for After.
+type Step struct {
+	Name string `json:"name,omitempty"`
+
+	At    Duration `json:"at,omitempty"`    // scenario time the step waits for
+	On    string   `json:"on,omitempty"`    // state the step waits for
+	After Duration `json:"after,omitempty"` // time waited after the other triggers
+
+	Action string `json:"action"`
+
+	Data   string `json:"data,omitempty"`   // csafe: commands, in hex
+	Reject bool   `json:"reject,omitempty"` // csafe: the commands are expected to be rejected
+
+	Rate    int      `json:"rate,omitempty"`    // row: strokes per minute
+	Pace    Duration `json:"pace,omitempty"`    // row: time per 500m
+	Power   int      `json:"power,omitempty"`   // row: watts
+	Persona string   `json:"persona,omitempty"` // row: persona name
+
+	Button string `json:"button,omitempty"` // button: name of the button
+	ID     string `json:"id,omitempty"`     // enter_id: user ID
+
+	Fault       string  `json:"fault,omitempty"`       // fault: kind of fault
+	Probability float64 `json:"probability,omitempty"` // fault: chance a frame is hit, 1 when 0
+
+	State  string   `json:"state,omitempty"`  // expect: state of the emulator
+	Metric string   `json:"metric,omitempty"` // expect: name of a metric
+	Min    *float64 `json:"min,omitempty"`    // expect: lowest value of the metric
+	Max    *float64 `json:"max,omitempty"`    // expect: highest value of the metric
+}
+
+//states are the names of the states a step can wait for or expect
+var states = map[string]bool{
+	config.PM5_STATE_READY:    true,
+	config.PM5_STATE_IDLE:     true,
+	config.PM5_STATE_HAVEID:   true,
+	config.PM5_STATE_INUSE:    true,
+	config.PM5_STATE_PAUSED:   true,
+	config.PM5_STATE_FINISHED: true,
+	config.PM5_STATE_MANUAL:   true,
+}
+
+//Load reads a scenario from a JSON file
+func Load(path string) (Scenario, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Scenario{}, err
+	}
+	return Parse(b)
+}
+
+//Parse decodes and checks a JSON scenario
+func Parse(b []byte) (Scenario, error) {
+	var s Scenario
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&s); err != nil {
+		return Scenario{}, fmt.Errorf("scenario: %v", err)
+	}
+	if err := s.Validate(); err != nil {
+		return Scenario{}, err
+	}
+	return s, nil
+}
+
+//Validate returns an error if a step of the scenario cannot be run
+func (s Scenario) Validate() error {
+	if s.Persona != "" {
+		if _, err := sim.LookupPersona(s.Persona); err != nil {
+			return fmt.Errorf("scenario: %v", err)
+		}
+	}
+	for i, step := range s.Steps {
+		if err := step.validate(); err != nil {
+			return fmt.Errorf("scenario: step %d: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+//validate returns an error if the step cannot be run
+func (s Step) validate() error {
+	if s.On != "" && !states[s.On] {
+		return fmt.Errorf("unknown state %q", s.On)
+	}
+
+	switch s.Action {
+	case ACTION_CONNECT, ACTION_DISCONNECT, ACTION_STOP, ACTION_RESUME:
+	case ACTION_CSAFE:
+		if _, err := s.commands(); err != nil {
+			return fmt.Errorf("bad csafe data: %v", err)
+		}
+	case ACTION_ROW:
+		if s.Persona != "" {
+			if _, err := sim.LookupPersona(s.Persona); err != nil {
+				return err
+			}
+		}
+		if s.Pace != 0 && s.Power != 0 {
+			return fmt.Errorf("row sets both a pace and a power")
+		}
+	case ACTION_BUTTON:
+		if s.Button != emulator.BUTTON_MENU && s.Button != emulator.BUTTON_JUST_ROW {
+			return fmt.Errorf("unknown button %q", s.Button)
+		}
+	case ACTION_ENTER_ID:
+		if s.ID == "" {
+			return fmt.Errorf("enter_id without an id")
+		}
+	case ACTION_FAULT:
+		if s.Fault != FAULT_NONE && s.Fault != FAULT_DROP && s.Fault != FAULT_CORRUPT {
+			return fmt.Errorf("unknown fault %q", s.Fault)
+		}
+		if s.Probability < 0 || s.Probability > 1 {
+			return fmt.Errorf("fault probability %v out of [0,1]", s.Probability)
+		}
+	case ACTION_EXPECT:
+		if s.State == "" && s.Metric == "" {
+			return fmt.Errorf("expect without a state or a metric")
+		}
+		if s.State != "" && !states[s.State] {
+			return fmt.Errorf("unknown state %q", s.State)
+		}
+		if s.Metric != "" {
+			if _, ok := metrics[s.Metric]; !ok {
+				return fmt.Errorf("unknown metric %q", s.Metric)
+			}
+			if s.Min == nil && s.Max == nil {
+				return fmt.Errorf("expect metric %s without a min or a max", s.Metric)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown action %q", s.Action)
+	}
+	return nil
+}
+
+//commands returns the csafe commands of the step
+func (s Step) commands() ([]byte, error) {
+	return hex.DecodeString(strings.Join(strings.Fields(s.Data), ""))
+}
+
+//String describes the step in reports
+func (s Step) String() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	switch s.Action {
+	case ACTION_CSAFE:
+		return fmt.Sprintf("csafe %s", s.Data)
+	case ACTION_ROW:
+		var parts []string
+		if s.Rate != 0 {
+			parts = append(parts, fmt.Sprintf("%d spm", s.Rate))
+		}
+		if s.Pace != 0 {
+			parts = append(parts, fmt.Sprintf("%v/500m", time.Duration(s.Pace)))
+		}
+		if s.Power != 0 {
+			parts = append(parts, fmt.Sprintf("%d W", s.Power))
+		}
+		if s.Persona != "" {
+			parts = append(parts, "as "+s.Persona)
+		}
+		return strings.Join(append([]string{"row"}, parts...), " ")
+	case ACTION_BUTTON:
+		return "press " + s.Button
+	case ACTION_ENTER_ID:
+		return "enter id " + s.ID
+	case ACTION_FAULT:
+		return "fault " + s.Fault
+	case ACTION_EXPECT:
+		var parts []string
+		if s.State != "" {
+			parts = append(parts, "state "+s.State)
+		}
+		if s.Metric != "" {
+			parts = append(parts, s.Metric+" in "+bounds(s.Min, s.Max))
+		}
+		return "expect " + strings.Join(parts, ", ")
+	}
+	return s.Action
+}
+
+//bounds describes the range of an expected metric
+func bounds(min, max *float64) string {
+	lo, hi := "-inf", "+inf"
+	if min != nil {
+		lo = fmt.Sprint(*min)
+	}
+	if max != nil {
+		hi = fmt.Sprint(*max)
+	}
+	return "[" + lo + ", " + hi + "]"
+}
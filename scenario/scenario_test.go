@@ -0,0 +1,84 @@
+package scenario
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun_PauseResume(t *testing.T) {
+	s, err := Load("testdata/pause-resume.json")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	report := Run(s)
+	t.Log("\n" + report.String())
+	assert.True(t, report.Passed())
+	// the same seed runs the same scenario
+	assert.Equal(t, report, Run(s))
+}
+
+func TestRun_Failures(t *testing.T) {
+	s, err := Parse([]byte(`{
+		"name": "failures",
+		"timeout": "10s",
+		"steps": [
+			{"action": "csafe", "data": "82"},
+			{"action": "expect", "state": "INUSE"},
+			{"action": "button", "button": "just_row"},
+			{"at": "1m", "action": "expect", "metric": "distance", "min": 1000},
+			{"on": "FINISHED", "action": "stop"},
+			{"action": "expect", "state": "READY"}
+		]
+	}`))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	report := Run(s)
+	assert.False(t, report.Passed())
+	assert.Len(t, report.Results, 5)
+	assert.Equal(t, errNotConnected.Error(), report.Results[0].Error)
+	assert.Equal(t, "state is READY", report.Results[1].Error)
+	assert.True(t, report.Results[2].Passed())
+	assert.Contains(t, report.Results[3].Error, "out of range")
+	assert.Contains(t, report.Results[4].Error, "timed out waiting for state FINISHED")
+	assert.Contains(t, report.String(), "1 of 6 steps not run")
+}
+
+func TestRun_Invalid(t *testing.T) {
+	min := 1.0
+	s := Scenario{Name: "typo", Steps: []Step{{Action: ACTION_EXPECT, Metric: "distnace", Min: &min}}}
+
+	report := Run(s)
+	assert.False(t, report.Passed())
+	assert.Empty(t, report.Results)
+	assert.Contains(t, report.Error, `unknown metric "distnace"`)
+	assert.Contains(t, report.String(), "1 of 1 steps not run")
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+	}{
+		{"Unknown Field", `{"steps": [{"action": "stop", "speed": 3}]}`},
+		{"Unknown Action", `{"steps": [{"action": "jump"}]}`},
+		{"Unknown State", `{"steps": [{"on": "ROWING", "action": "stop"}]}`},
+		{"Unknown Persona", `{"persona": "cox", "steps": []}`},
+		{"Bad Duration", `{"steps": [{"at": "soon", "action": "stop"}]}`},
+		{"Bad Csafe Data", `{"steps": [{"action": "csafe", "data": "8"}]}`},
+		{"Pace And Power", `{"steps": [{"action": "row", "pace": "2m", "power": 200}]}`},
+		{"Unknown Button", `{"steps": [{"action": "button", "button": "units"}]}`},
+		{"Unknown Fault", `{"steps": [{"action": "fault", "fault": "delay"}]}`},
+		{"Unknown Metric", `{"steps": [{"action": "expect", "metric": "heart_rate", "min": 1}]}`},
+		{"Metric Without Range", `{"steps": [{"action": "expect", "metric": "distance"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.json))
+			assert.Error(t, err)
+		})
+	}
+}
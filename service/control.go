@@ -2,10 +2,10 @@ package service
 
 import (
 	"fmt"
-	"pm5-emulator/protocol/csafe"
 	"pm5-emulator/service/decorator"
-	"github.com/sirupsen/logrus"
+
 	"github.com/bettercap/gatt"
+	"github.com/sirupsen/logrus"
 )
 
 /*
@@ -18,29 +18,40 @@ var (
 	attrTransmitCharacteristicsUUID, _ = gatt.ParseUUID(getFullUUID("0022"))
 )
 
-//NewControlService advertises Control service offered by PM5
-func NewControlService() *gatt.Service {
+//ControlCharacteristics returns the UUIDs of the characteristics the control
+//protocol frames are received on and transmitted from
+func ControlCharacteristics() (rx, tx gatt.UUID) {
+	return attrReceiveCharacteristicsUUID, attrTransmitCharacteristicsUUID
+}
+
+//NewControlService advertises Control service offered by PM5. Payloads written
+//to the receive characteristic are read with the protocol session of the
+//central writing them, and answered to that central only.
+func NewControlService(sessions *Sessions) *gatt.Service {
 	controlService := gatt.NewService(attrControlServiceUUID)
 	s := decorator.NewServiceSubscriber(controlService)
 
 	/*
 		C2 PM receive characteristic
 	*/
-	csafeDec := csafe.Decoder{}
-
 	receiveChar := s.AddCharacteristic(attrReceiveCharacteristicsUUID)
 	receiveChar.HandleWriteFunc(func(r gatt.Request, data []byte) (status byte) {
-		pck, err := csafeDec.Decode(data)
+		session := sessions.Get(r.Central)
+		proto := session.protocol()
 
-		str := fmt.Sprintf("[[Control]] Decoded Command: 0x%x Data: [ ", pck.Cmds[0])
-		for i := 0; i < len(pck.Data); i++ {
-			str = fmt.Sprintf("%s0x%x ", str, pck.Data[i])
+		logrus.Info(fmt.Sprintf("[[Control]] Received %s payload: % x", proto.Name(), data))
+		reply, err := proto.ReadPayload(data)
+		if err != nil {
+			logrus.Error("[[Control]] ", err)
+		}
+		if reply == nil {
+			return gatt.StatusSuccess
 		}
-		str = fmt.Sprintf("%s] Error: %v", str, err)
 
-		logrus.Info(str)
-		return 1
-		//return gatt.StatusSuccess
+		if err := session.send(reply); err != nil {
+			logrus.Error("[[Transmit]] ", err)
+		}
+		return gatt.StatusSuccess
 	})
 
 	receiveChar.HandleNotifyFunc(func(r gatt.Request, n gatt.Notifier) {
@@ -54,10 +65,12 @@ func NewControlService() *gatt.Service {
 		C2 PM transmit characteristic
 	*/
 	transmitChar := s.AddCharacteristic(attrTransmitCharacteristicsUUID)
-	transmitChar.HandleNotifyFunc(func(r gatt.Request, n gatt.Notifier) {
-		logrus.Info("[[Transmit]] Notify Signal")
-		n.Write([]byte{0x76, 0x77, 0x7E, 0x7F})
-	})
+	// Responses are pushed from the receive handler, so the notifier is only
+	// kept here instead of going through the subscriber decorator.
+	transmitChar.HandleNotify(gatt.NotifyHandlerFunc(func(r gatt.Request, n gatt.Notifier) {
+		logrus.Info("[[Transmit]] Notify Signal MTU: ", r.Central.MTU())
+		sessions.Get(r.Central).subscribeTx(n, r.Central.MTU())
+	}))
 
 	transmitChar.HandleReadFunc(func(resp gatt.ResponseWriter, req *gatt.ReadRequest) {
 		logrus.Info("[[Transmit]] Transmitting Data")
@@ -65,6 +78,5 @@ func NewControlService() *gatt.Service {
 		resp.Write(data)
 	})
 
-
 	return controlService
 }
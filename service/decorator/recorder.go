@@ -0,0 +1,141 @@
+package decorator
+
+import (
+	"pm5-emulator/capture"
+	"pm5-emulator/clock"
+	"sync"
+	"time"
+
+	"github.com/bettercap/gatt"
+	"github.com/sirupsen/logrus"
+)
+
+/*
+ * Recorder
+ */
+
+// UNSUBSCRIBE_POLL is how often a subscription is checked for having been
+// cancelled, gatt does not report when a central unsubscribes
+const UNSUBSCRIBE_POLL = 250 * time.Millisecond
+
+// Record wraps the handlers of every characteristic of a service so that the
+// reads, writes, subscriptions and notifications of the centrals are passed
+// to sink, timestamped with the clock c. It must be called before the service
+// is added to a device.
+func Record(service *gatt.Service, sink capture.Sink, c clock.Clock) *gatt.Service {
+	for _, ch := range service.Characteristics() {
+		r := &charRecorder{
+			sink:    sink,
+			clock:   c,
+			service: service.UUID().String(),
+			char:    ch.UUID().String(),
+		}
+		r.wrap(ch)
+	}
+	return service
+}
+
+// charRecorder records the interactions with a single characteristic
+type charRecorder struct {
+	sink    capture.Sink
+	clock   clock.Clock
+	service string
+	char    string
+}
+
+// wrap replaces the handlers of the characteristic with recording ones
+func (r *charRecorder) wrap(c *gatt.Characteristic) {
+	if h := c.GetReadHandler(); h != nil {
+		c.HandleReadFunc(func(rsp gatt.ResponseWriter, req *gatt.ReadRequest) {
+			w := &responseRecorder{ResponseWriter: rsp}
+			h.ServeRead(w, req)
+			r.record(capture.EVENT_READ, capture.DIR_OUT, req.Central, w.data)
+		})
+	}
+
+	if h := c.GetWriteHandler(); h != nil {
+		c.HandleWriteFunc(func(req gatt.Request, data []byte) (status byte) {
+			r.record(capture.EVENT_WRITE, capture.DIR_IN, req.Central, data)
+			return h.ServeWrite(req, data)
+		})
+	}
+
+	if h := c.GetNotifyHandler(); h != nil {
+		// gatt keeps the first notify handler once the client characteristic
+		// configuration descriptor is added, so the descriptors are put back
+		// as they were after registering the recording handler
+		cccd, descs := c.Descriptor(), c.Descriptors()
+		c.SetDescriptor(nil)
+		c.HandleNotifyFunc(func(req gatt.Request, n gatt.Notifier) {
+			r.record(capture.EVENT_SUBSCRIBE, capture.DIR_IN, req.Central, nil)
+			r.watch(req.Central, n)
+			h.ServeNotify(req, &notifierRecorder{Notifier: n, r: r, central: req.Central})
+		})
+		c.SetDescriptor(cccd)
+		c.SetDescriptors(descs)
+	}
+}
+
+// watch records the end of the subscription of the notifier n
+func (r *charRecorder) watch(central gatt.Central, n gatt.Notifier) {
+	var mu sync.Mutex
+	mu.Lock()
+	defer mu.Unlock()
+
+	var t clock.Timer
+	t = clock.Every(r.clock, UNSUBSCRIBE_POLL, func() {
+		if !n.Done() {
+			return
+		}
+		r.record(capture.EVENT_UNSUBSCRIBE, capture.DIR_IN, central, nil)
+		mu.Lock()
+		defer mu.Unlock()
+		t.Stop()
+	})
+}
+
+// record writes an interaction with the characteristic to the capture
+func (r *charRecorder) record(event, dir string, central gatt.Central, data []byte) {
+	e := capture.Entry{
+		Time:      r.clock.Now(),
+		Event:     event,
+		Direction: dir,
+		Service:   r.service,
+		Char:      r.char,
+		Data:      data,
+	}
+	if central != nil {
+		e.Central = central.ID()
+	}
+	if err := r.sink.Record(e); err != nil {
+		logrus.Error("[[Capture]] ", err)
+	}
+}
+
+// responseRecorder keeps the data written in response to a read
+type responseRecorder struct {
+	gatt.ResponseWriter
+	data []byte
+}
+
+// Write writes the response and keeps its data
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.data = append(w.data, b...)
+	return w.ResponseWriter.Write(b)
+}
+
+// notifierRecorder records the notifications sent to a subscribed central
+type notifierRecorder struct {
+	gatt.Notifier
+	r       *charRecorder
+	central gatt.Central
+}
+
+// Write sends the notification and records it once sent
+func (n *notifierRecorder) Write(data []byte) (int, error) {
+	l, err := n.Notifier.Write(data)
+	if err == nil {
+		n.r.record(capture.EVENT_NOTIFY, capture.DIR_OUT, n.central, data)
+	}
+	return l, err
+}
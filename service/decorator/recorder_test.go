@@ -0,0 +1,96 @@
+package decorator
+
+import (
+	"bytes"
+	"encoding/json"
+	"pm5-emulator/capture"
+	"pm5-emulator/clock"
+	"testing"
+	"time"
+
+	"github.com/bettercap/gatt"
+	"github.com/stretchr/testify/assert"
+)
+
+type testCentral struct{}
+
+func (testCentral) ID() string   { return "central" }
+func (testCentral) Close() error { return nil }
+func (testCentral) MTU() int     { return 23 }
+
+type testNotifier struct {
+	sent [][]byte
+	done bool
+}
+
+func (n *testNotifier) Write(data []byte) (int, error) {
+	n.sent = append(n.sent, data)
+	return len(data), nil
+}
+func (n *testNotifier) Done() bool { return n.done }
+func (n *testNotifier) Cap() int   { return 20 }
+
+type testResponse struct{ data []byte }
+
+func (r *testResponse) Write(b []byte) (int, error) {
+	r.data = append(r.data, b...)
+	return len(b), nil
+}
+func (r *testResponse) SetStatus(byte) {}
+
+func TestRecord(t *testing.T) {
+	s := gatt.NewService(gatt.MustParseUUID("CE060030-43E5-11E4-916C-0800200C9A66"))
+	c := s.AddCharacteristic(gatt.MustParseUUID("CE060031-43E5-11E4-916C-0800200C9A66"))
+	var written []byte
+	c.HandleReadFunc(func(rsp gatt.ResponseWriter, req *gatt.ReadRequest) {
+		rsp.Write([]byte{0x01, 0x02})
+	})
+	c.HandleWriteFunc(func(r gatt.Request, data []byte) byte {
+		written = data
+		return gatt.StatusSuccess
+	})
+	c.HandleNotifyFunc(func(r gatt.Request, n gatt.Notifier) {
+		n.Write([]byte{0x03})
+	})
+	descs := c.Descriptors()
+
+	tc := clock.NewManual(time.Time{})
+	var b bytes.Buffer
+	rec := capture.NewRecorder(&b, tc)
+	assert.Equal(t, s, Record(s, rec, tc))
+	// the characteristic keeps a single configuration descriptor
+	assert.Equal(t, descs, c.Descriptors())
+
+	req := gatt.Request{Central: testCentral{}}
+	rsp := &testResponse{}
+	c.GetReadHandler().ServeRead(rsp, &gatt.ReadRequest{Request: req})
+	assert.Equal(t, []byte{0x01, 0x02}, rsp.data)
+	c.GetWriteHandler().ServeWrite(req, []byte{0x04})
+	assert.Equal(t, []byte{0x04}, written)
+	n := &testNotifier{}
+	c.GetNotifyHandler().ServeNotify(req, n)
+	assert.Equal(t, [][]byte{{0x03}}, n.sent)
+
+	tc.Advance(time.Second)
+	n.done = true
+	tc.Advance(time.Second)
+	tc.Advance(time.Second)
+
+	var got []capture.Entry
+	dec := json.NewDecoder(&b)
+	for dec.More() {
+		var e capture.Entry
+		assert.NoError(t, dec.Decode(&e))
+		assert.Equal(t, "central", e.Central)
+		assert.Equal(t, c.UUID().String(), e.Char)
+		assert.Equal(t, s.UUID().String(), e.Service)
+		got = append(got, capture.Entry{Event: e.Event, Direction: e.Direction, Data: e.Data})
+	}
+	assert.Equal(t, []capture.Entry{
+		{Event: capture.EVENT_READ, Direction: capture.DIR_OUT, Data: capture.Bytes{0x01, 0x02}},
+		{Event: capture.EVENT_WRITE, Direction: capture.DIR_IN, Data: capture.Bytes{0x04}},
+		{Event: capture.EVENT_SUBSCRIBE, Direction: capture.DIR_IN},
+		{Event: capture.EVENT_NOTIFY, Direction: capture.DIR_OUT, Data: capture.Bytes{0x03}},
+		{Event: capture.EVENT_UNSUBSCRIBE, Direction: capture.DIR_IN},
+	}, got)
+}
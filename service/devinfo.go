@@ -17,8 +17,8 @@ var (
 	attrErgMachineTypeUUID, _   = gatt.ParseUUID(getFullUUID("0016"))
 )
 
-// NewDevInfoService registers a new Device Information service as per PM5 specs
-func NewDevInfoService() *gatt.Service {
+// NewDevInfoService registers a new Device Information service as per PM5 specs, reporting the identity dev
+func NewDevInfoService(dev config.Device) *gatt.Service {
 	s := gatt.NewService(attrDeviceInfoUUID)
 
 	/*
@@ -28,7 +28,7 @@ func NewDevInfoService() *gatt.Service {
 	modelNumChar := s.AddCharacteristic(attrModelNumberUUID)
 	modelNumChar.HandleReadFunc(func(rsp gatt.ResponseWriter, req *gatt.ReadRequest) {
 		logrus.Info("Module Number String Read")
-		rsp.Write([]byte(config.MODEL_NO)) //upto 16 bytes
+		rsp.Write([]byte(dev.ModelNo)) //upto 16 bytes
 	})
 
 	/*
@@ -38,7 +38,7 @@ func NewDevInfoService() *gatt.Service {
 	serialNumberChar := s.AddCharacteristic(attrSerialNumberUUID)
 	serialNumberChar.HandleReadFunc(func(rsp gatt.ResponseWriter, req *gatt.ReadRequest) {
 		logrus.Info("Serial Number String Read")
-		rsp.Write([]byte(config.SERIAL_NO)) //write serial number as response
+		rsp.Write([]byte(dev.SerialNo)) //write serial number as response
 	})
 
 	/*
@@ -48,7 +48,7 @@ func NewDevInfoService() *gatt.Service {
 	hwRevChar := s.AddCharacteristic(attrHardwareRevisionUUID)
 	hwRevChar.HandleReadFunc(func(rsp gatt.ResponseWriter, req *gatt.ReadRequest) {
 		logrus.Info("Hardware Revision String Read")
-		rsp.Write([]byte(config.HARDWARE_VERSION)) //upto 3 bytes
+		rsp.Write([]byte(dev.HardwareVersion)) //upto 3 bytes
 	})
 
 	/*
@@ -58,7 +58,7 @@ func NewDevInfoService() *gatt.Service {
 	fwRevChar := s.AddCharacteristic(attrFirmwareRevisionUUID)
 	fwRevChar.HandleReadFunc(func(rsp gatt.ResponseWriter, req *gatt.ReadRequest) {
 		logrus.Info("Firmware Revision String Read")
-		rsp.Write([]byte(config.FIRMWARE_VERSION)) //upto 20bytes
+		rsp.Write([]byte(dev.FirmwareVersion)) //upto 20bytes
 	})
 
 	/*
@@ -68,7 +68,7 @@ func NewDevInfoService() *gatt.Service {
 	manuNameChar := s.AddCharacteristic(attrManufacturerNameUUID)
 	manuNameChar.HandleReadFunc(func(rsp gatt.ResponseWriter, req *gatt.ReadRequest) {
 		logrus.Info("Manufacturer Name String Read")
-		rsp.Write([]byte(config.MANUFACTURER_NAME)) //upto 16 bytes
+		rsp.Write([]byte(dev.Manufacturer)) //upto 16 bytes
 	})
 
 	/*
@@ -78,7 +78,7 @@ func NewDevInfoService() *gatt.Service {
 	ergMachineTypeChar := s.AddCharacteristic(attrErgMachineTypeUUID)
 	ergMachineTypeChar.HandleReadFunc(func(rsp gatt.ResponseWriter, req *gatt.ReadRequest) {
 		logrus.Info("Erg Machine Type Read")
-		rsp.Write([]byte(config.ERG_MACHINE_TYPE)) //upto 1 byte
+		rsp.Write([]byte(dev.MachineType)) //upto 1 byte
 	})
 
 	return s
@@ -18,7 +18,11 @@ var PM5MultiplexedData = map[string]map[string]int{
 	"Mux_0x31": mux0x31,
 	"Mux_0x32": mux0x32,
 	"Mux_0x33": mux0x33,
-	"Mux_0x34": mux0x35,
+	"Mux_0x35": mux0x35,
+	"Mux_0x36": mux0x36,
+	"Mux_0x37": mux0x37,
+	"Mux_0x38": mux0x38,
+	"Mux_0x39": mux0x39,
 }
 
 //0x31 C2 rowing general status characteristic
@@ -109,3 +113,92 @@ var mux0x35 = map[string]int{
 	"Stroke_Count_Lo":         17,
 	"Stroke_Count_Hi":         18,
 }
+
+//0x36 C2 rowing additional stroke data characteristic
+var mux0x36 = map[string]int{
+	"Elapsed_Time_Lo":             1,
+	"Elapsed_Time_Mid":            2,
+	"Elapsed_Time_High":           3,
+	"Stroke_Power_Lo":             4,
+	"Stroke_Power_Hi":             5,
+	"Stroke_Calories_Lo":          6,
+	"Stroke_Calories_Hi":          7,
+	"Stroke_Count_Lo":             8,
+	"Stroke_Count_Hi":             9,
+	"Projected_Work_Time_Lo":      10,
+	"Projected_Work_Time_Mid":     11,
+	"Projected_Work_Time_Hi":      12,
+	"Projected_Work_Distance_Lo":  13,
+	"Projected_Work_Distance_Mid": 14,
+	"Projected_Work_Distance_Hi":  15,
+	"Work_Per_Stroke_Lo":          16,
+	"Work_Per_Stroke_Hi":          17,
+}
+
+//0x37 C2 rowing split/interval data characteristic
+var mux0x37 = map[string]int{
+	"Elapsed_Time_Lo":             1,
+	"Elapsed_Time_Mid":            2,
+	"Elapsed_Time_High":           3,
+	"Distance_Lo":                 4,
+	"Distance_Mid":                5,
+	"Distance_High":               6,
+	"Split_Interval_Time_Lo":      7,
+	"Split_Interval_Time_Mid":     8,
+	"Split_Interval_Time_High":    9,
+	"Split_Interval_Distance_Lo":  10,
+	"Split_Interval_Distance_Mid": 11,
+	"Split_Interval_Distance_Hi":  12,
+	"Interval_Rest_Time_Lo":       13,
+	"Interval_Rest_Time_Hi":       14,
+	"Interval_Rest_Distance_Lo":   15,
+	"Interval_Rest_Distance_Hi":   16,
+	"Split_Interval_Type":         17,
+	"Split_Interval_Number":       18,
+}
+
+//0x38 C2 rowing additional split/interval data characteristic
+var mux0x38 = map[string]int{
+	"Elapsed_Time_Lo":                  1,
+	"Elapsed_Time_Mid":                 2,
+	"Elapsed_Time_High":                3,
+	"Split_Interval_Avg_Stroke_Rate":   4,
+	"Split_Interval_Work_Heartrate":    5,
+	"Split_Interval_Rest_Heartrate":    6,
+	"Split_Interval_Avg_Pace_Lo":       7,
+	"Split_Interval_Avg_Pace_Hi":       8,
+	"Split_Interval_Total_Calories_Lo": 9,
+	"Split_Interval_Total_Calories_Hi": 10,
+	"Split_Interval_Avg_Calories_Lo":   11,
+	"Split_Interval_Avg_Calories_Hi":   12,
+	"Split_Interval_Speed_Lo":          13,
+	"Split_Interval_Speed_Hi":          14,
+	"Split_Interval_Power_Lo":          15,
+	"Split_Interval_Power_Hi":          16,
+	"Split_Avg_Drag_Factor":            17,
+	"Split_Interval_Number":            18,
+}
+
+//0x39 C2 rowing end of workout summary data characteristic
+var mux0x39 = map[string]int{
+	"Log_Entry_Date_Lo":   1,
+	"Log_Entry_Date_Hi":   2,
+	"Log_Entry_Time_Lo":   3,
+	"Log_Entry_Time_Hi":   4,
+	"Elapsed_Time_Lo":     5,
+	"Elapsed_Time_Mid":    6,
+	"Elapsed_Time_High":   7,
+	"Distance_Lo":         8,
+	"Distance_Mid":        9,
+	"Distance_High":       10,
+	"Average_Stroke_Rate": 11,
+	"Ending_Heartrate":    12,
+	"Average_Heartrate":   13,
+	"Min_Heartrate":       14,
+	"Max_Heartrate":       15,
+	"Drag_Factor_Average": 16,
+	"Recovery_Heart_Rate": 17,
+	"Workout_Type":        18,
+	"Average_Pace_Lo":     19,
+	"Average_Pace_Hi":     20,
+}
@@ -0,0 +1,128 @@
+package mux
+
+import (
+	"fmt"
+	"strings"
+)
+
+//MULTIPLEXED is the identifier of the multiplexed characteristic, whose
+//notifications are prefixed with the identifier of the characteristic they carry
+const MULTIPLEXED = 0x80
+
+//suffixes name the bytes of the fields spread over several bytes, low byte first
+var suffixes = []string{"_Lo", "_Mid", "_Hi", "_High"}
+
+//Payload builds the data of a rowing characteristic from its fields, named
+//as in PM5MultiplexedData
+type Payload struct {
+	fields map[string]int
+	data   []byte
+}
+
+//NewPayload returns a payload with all the fields of a multiplexed
+//characteristic set to zero
+func NewPayload(id int) *Payload {
+	fields := PM5MultiplexedData[fmt.Sprintf("Mux_0x%X", id)]
+
+	size := 0
+	for _, offset := range fields {
+		if offset > size {
+			size = offset
+		}
+	}
+	data := make([]byte, size+1)
+	data[0] = byte(id)
+	return &Payload{fields: fields, data: data}
+}
+
+//ParsePayload returns the payload of the data notified on the characteristic
+//id, nil when the fields of the characteristic are unknown
+func ParsePayload(id int, data []byte) *Payload {
+	fields, ok := PM5MultiplexedData[fmt.Sprintf("Mux_0x%X", id)]
+	if !ok {
+		return nil
+	}
+	return &Payload{fields: fields, data: append([]byte{byte(id)}, data...)}
+}
+
+//ParseNotification returns the payload of the data notified on the
+//characteristic id, or on the multiplexed characteristic, nil when the fields
+//of the characteristic are unknown
+func ParseNotification(id int, data []byte) *Payload {
+	if id == MULTIPLEXED {
+		if len(data) == 0 {
+			return nil
+		}
+		return ParsePayload(int(data[0]), data[1:])
+	}
+	return ParsePayload(id, data)
+}
+
+//Fields returns the values of the fields of the payload. A value spread over
+//several bytes is returned under the name its _Lo, _Mid and _Hi or _High
+//fields share, the fields missing from the data are left out.
+func (p *Payload) Fields() map[string]int {
+	values := make(map[string]int)
+	for name := range p.fields {
+		base := name
+		for _, suffix := range suffixes {
+			base = strings.TrimSuffix(base, suffix)
+		}
+		if _, ok := values[base]; ok {
+			continue
+		}
+		if v, ok := p.get(base); ok {
+			values[base] = v
+		}
+	}
+	return values
+}
+
+//get returns the value of a field, false when missing from the data
+func (p *Payload) get(name string) (int, bool) {
+	if offset, ok := p.fields[name]; ok {
+		if offset >= len(p.data) {
+			return 0, false
+		}
+		return int(p.data[offset]), true
+	}
+	value, shift := 0, uint(0)
+	for _, suffix := range suffixes {
+		if offset, ok := p.fields[name+suffix]; ok {
+			if offset >= len(p.data) {
+				return 0, false
+			}
+			value |= int(p.data[offset]) << shift
+			shift += 8
+		}
+	}
+	return value, shift > 0
+}
+
+//Set sets a field of the payload. A value spread over several bytes is set
+//by the name its _Lo, _Mid and _Hi or _High fields share.
+func (p *Payload) Set(name string, value int) {
+	if offset, ok := p.fields[name]; ok {
+		if offset < len(p.data) {
+			p.data[offset] = byte(value)
+		}
+		return
+	}
+	for _, suffix := range suffixes {
+		if offset, ok := p.fields[name+suffix]; ok && offset < len(p.data) {
+			p.data[offset] = byte(value)
+			value >>= 8
+		}
+	}
+}
+
+//Bytes returns the data notified on the characteristic itself
+func (p *Payload) Bytes() []byte {
+	return p.data[1:]
+}
+
+//Multiplexed returns the data notified on the multiplexed characteristic,
+//prefixed with the characteristic identifier
+func (p *Payload) Multiplexed() []byte {
+	return p.data
+}
@@ -0,0 +1,62 @@
+package mux
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPayload_Set(t *testing.T) {
+	p := NewPayload(Rowing_Additional_0x33)
+	p.Set("Elapsed_Time", 0x030201)
+	p.Set("Interval_Count", 4)
+	p.Set("Total_Calories", 0x0605)
+	p.Set("Unknown_Field", 0xFF)
+
+	want := make([]byte, 18)
+	copy(want, []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06})
+	assert.Equal(t, want, p.Bytes())
+	assert.Equal(t, append([]byte{0x33}, want...), p.Multiplexed())
+}
+
+func TestParsePayload(t *testing.T) {
+	p := ParsePayload(Rowing_Additional_0x33, []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06})
+	p.Set("Elapsed_Time", 0)
+	// fields past the end of the data are left out
+	p.Set("Split_Int_Avg_Power", 0xFFFF)
+	assert.Equal(t, []byte{0x00, 0x00, 0x00, 0x04, 0x05, 0x06}, p.Bytes())
+
+	assert.Nil(t, ParsePayload(0x30, nil))
+}
+
+func TestPayload_Fields(t *testing.T) {
+	p := NewPayload(Rowing_Additional_0x32)
+	p.Set("Elapsed_Time", 0x030201)
+	p.Set("Speed", 0x0504)
+	p.Set("Stroke_Rate", 24)
+	fields := p.Fields()
+	assert.Equal(t, 0x030201, fields["Elapsed_Time"])
+	assert.Equal(t, 0x0504, fields["Speed"])
+	assert.Equal(t, 24, fields["Stroke_Rate"])
+	assert.Equal(t, 0, fields["Average_Power"])
+	assert.Len(t, fields, 9)
+
+	tests := []struct {
+		name string
+		id   int
+		data []byte
+		want map[string]int
+	}{
+		{"Characteristic", Rowing_Additional_0x33, []byte{0x01, 0x02, 0x03, 0x04, 0x05},
+			map[string]int{"Elapsed_Time": 0x030201, "Interval_Count": 4}},
+		{"Multiplexed", MULTIPLEXED, []byte{0x33, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06},
+			map[string]int{"Elapsed_Time": 0x030201, "Interval_Count": 4, "Total_Calories": 0x0605}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ParseNotification(tt.id, tt.data).Fields())
+		})
+	}
+	assert.Nil(t, ParseNotification(MULTIPLEXED, nil))
+	assert.Nil(t, ParseNotification(0x3A, []byte{0x01}))
+}
@@ -1,11 +1,19 @@
 package service
 
 import (
+	"math"
+	"pm5-emulator/clock"
+	"pm5-emulator/config"
+	"pm5-emulator/logbook"
 	"pm5-emulator/service/mux"
-	"github.com/sirupsen/logrus"
-	"github.com/bettercap/gatt"
+	"pm5-emulator/sim"
+	"pm5-emulator/user"
+	"pm5-emulator/workout"
+	"sync"
 	"time"
-	"crypto/rand"
+
+	"github.com/bettercap/gatt"
+	"github.com/sirupsen/logrus"
 )
 
 /*
@@ -30,8 +38,23 @@ var (
 	attrMultiplexedInfoCharacteristicsUUID, _                   = gatt.ParseUUID(getFullUUID("0080"))
 )
 
-//NewRowingService advertises rowing service defined by PM5 device
-func NewRowingService() *gatt.Service {
+//LOG_BASE_YEAR is the year the log entry dates of the summaries count from
+const LOG_BASE_YEAR = 2000
+
+//HEART_RATE_INVALID is sent for the heart rate when no belt is connected
+const HEART_RATE_INVALID = 255
+
+//Force curve notifications
+const (
+	FORCE_CURVE_WORDS         = 9  // points of the curve in a notification
+	FORCE_CURVE_NOTIFICATIONS = 15 // notifications of a curve at most
+)
+
+//NewRowingService advertises rowing service defined by PM5 device, notifying
+//the live data of the workout w of the user u and the summaries of the
+//workouts added to lb at the cadence of the clock c. The subscriptions and the sample rate of each
+//central are kept in its session.
+func NewRowingService(w *workout.Workout, u *user.User, lb *logbook.Logbook, c clock.Clock, sessions *Sessions) *gatt.Service {
 	s := gatt.NewService(attrRowingServiceUUID)
 
 	/*
@@ -39,52 +62,37 @@ func NewRowingService() *gatt.Service {
 	*/
 	rowingGenStatusChar := s.AddCharacteristic(attrGeneralStatusCharacteristicsUUID)
 
-	rowingGenStatusChar.HandleNotifyFunc(
-		func(r gatt.Request, n gatt.Notifier) {
-			logrus.Info("General Status Char Notify Request - launching goroutine")
-			go func() {
-				for true {
-					logrus.Info("Sending General Status Char Notification from goroutine")
-					byteArray := make([]byte, 1)
-					rand.Read(byteArray)		
-					// 19 bytes		
-					n.Write([]byte{byteArray[0], 0x5, 0x5, 0x5, 0x5, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x5, 0x5, 0x5, 0x5})
-					time.Sleep(500 * time.Millisecond)
-				}
-			}()
-		})	
+	rowingGenStatusChar.HandleNotifyFunc(sessions.notify(rowingGenStatusChar.UUID(), func(session *Session, n gatt.Notifier) clock.Timer {
+		logrus.Info("General Status Char Notify Request")
+		return notifyAt(c, session.sampleInterval, n, func() []byte {
+			logrus.Info("Sending General Status Char Notification")
+			return generalStatus(w.Metrics(), w.Goal()).Bytes()
+		})
+	}))
 
 	/*
 		C2 rowing additional status 1 characteristic
 	*/
 	additionalStatus1Char := s.AddCharacteristic(attrAdditionalStatus1CharacteristicsUUID)
-	additionalStatus1Char.HandleNotifyFunc(func(r gatt.Request, n gatt.Notifier) {
-		logrus.Info("Additional Status 1 Char Notify Request - launching goroutine")
-		go func() {
-			for true {
-				logrus.Info("Sending Additional Status 1 Notification from goroutine")				
-				n.Write([]byte{0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0xff, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0xb8, 0xb, 0x0, 0x0, 0x0})
-				time.Sleep(500 * time.Millisecond)
-			}
-		}()	
-	})
+	additionalStatus1Char.HandleNotifyFunc(sessions.notify(additionalStatus1Char.UUID(), func(session *Session, n gatt.Notifier) clock.Timer {
+		logrus.Info("Additional Status 1 Char Notify Request")
+		return notifyAt(c, session.sampleInterval, n, func() []byte {
+			logrus.Info("Sending Additional Status 1 Notification")
+			return additionalStatus1(w.Metrics()).Bytes()
+		})
+	}))
 
 	/*
 		C2 rowing additional status 2 characteristic
 	*/
 	additionalStatus2Char := s.AddCharacteristic(attrAdditionalStatus2CharacteristicsUUID)
-	additionalStatus2Char.HandleNotifyFunc(func(r gatt.Request, n gatt.Notifier) {
-		logrus.Info("Additional Status 2 Char Notify Request - launching goroutine")
-		go func() {
-			for true {
-				logrus.Info("Sending Additional Status 2 Notification from goroutine")
-				byteArray := make([]byte, 1)
-				rand.Read(byteArray)				
-				n.Write([]byte{byteArray[0], 0x1, 0x2, 0x3, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0})
-				time.Sleep(500 * time.Millisecond)
-			}
-		}()	
-	})
+	additionalStatus2Char.HandleNotifyFunc(sessions.notify(additionalStatus2Char.UUID(), func(session *Session, n gatt.Notifier) clock.Timer {
+		logrus.Info("Additional Status 2 Char Notify Request")
+		return notifyAt(c, session.sampleInterval, n, func() []byte {
+			logrus.Info("Sending Additional Status 2 Notification")
+			return additionalStatus2(w.Metrics(), w.CurrentSplit()).Bytes()
+		})
+	}))
 
 	/*
 		C2 rowing general status and additional status sample rate characteristic 0x0034
@@ -92,14 +100,18 @@ func NewRowingService() *gatt.Service {
 	sampleRateChar := s.AddCharacteristic(attrSampleRateCharacteristicsUUID)
 	sampleRateChar.HandleReadFunc(func(rsp gatt.ResponseWriter, req *gatt.ReadRequest) {
 		logrus.Info("Sample Rate Char Read Request")
-		data := make([]byte, 1)
-		rsp.Write(data)
+		rsp.Write([]byte{sessions.Get(req.Central).SampleRate()})
 	})
 
 	sampleRateChar.HandleWriteFunc(func(req gatt.Request, data []byte) (status byte) {
-		logrus.Info("Sample Rate Char Write Request: ", string(data))
-		if (len(data) > 1){
-			logrus.Error("Sample Rate Char Write Request received more than one byte")
+		logrus.Info("Sample Rate Char Write Request: ", data)
+		if len(data) != 1 {
+			logrus.Error("Sample Rate Char Write Request expects one byte")
+			return gatt.StatusUnexpectedError
+		}
+		if err := sessions.Get(req.Central).SetSampleRate(data[0]); err != nil {
+			logrus.Error("Sample Rate Char Write Request: ", err)
+			return gatt.StatusUnexpectedError
 		}
 		return gatt.StatusSuccess
 	})
@@ -108,124 +120,133 @@ func NewRowingService() *gatt.Service {
 		C2 rowing stroke data  characteristic 0x0035
 	*/
 	strokeDataChar := s.AddCharacteristic(attrStrokeDataCharacteristicsUUID)
-	strokeDataChar.HandleNotifyFunc(func(r gatt.Request, n gatt.Notifier) {
-		logrus.Info("Stroke Data Char Notify Request - launching goroutine")
-		go func() {
-			for true {
-				logrus.Info("Stroke Data Notification from goroutine")
-				n.Write([]byte{0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0})
-				time.Sleep(1000 * time.Millisecond)
-			}
-		}()	
-	})
+	strokeDataChar.HandleNotifyFunc(sessions.notify(strokeDataChar.UUID(), func(session *Session, n gatt.Notifier) clock.Timer {
+		logrus.Info("Stroke Data Char Notify Request")
+		return notifyEvery(c, 100*time.Millisecond, n, onStroke(w, func(m workout.Metrics) []byte {
+			logrus.Info("Stroke Data Notification")
+			return strokeData(m).Bytes()
+		}))
+	}))
 
 	/*
 		C2 rowing additional stroke data characteristic 0x0036
 	*/
 	additionalStrokeDataChar := s.AddCharacteristic(attrAdditionalStrokeDataCharacteristicsUUID)
-	additionalStrokeDataChar.HandleNotifyFunc(func(r gatt.Request, n gatt.Notifier) {
-		logrus.Info("Additional Stroke Data Char Notify Request - launching goroutine")
-		go func() {
-			for true {
-				logrus.Info("Stroke Data Notification from goroutine")
-				n.Write([]byte{0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0xff})
-				time.Sleep(1000 * time.Millisecond)
-			}
-		}()	
-	})
+	additionalStrokeDataChar.HandleNotifyFunc(sessions.notify(additionalStrokeDataChar.UUID(), func(session *Session, n gatt.Notifier) clock.Timer {
+		logrus.Info("Additional Stroke Data Char Notify Request")
+		return notifyEvery(c, 100*time.Millisecond, n, onStroke(w, func(m workout.Metrics) []byte {
+			logrus.Info("Additional Stroke Data Notification")
+			return additionalStrokeData(m, w.Goal(), u.Profile().Weight).Bytes()
+		}))
+	}))
 
 	/*
 		C2 rowing split/interval data characteristic
 	*/
 	splitIntervalDataChar := s.AddCharacteristic(attrSplitIntervalDataCharacteristicsUUID)
-	splitIntervalDataChar.HandleNotifyFunc(func(r gatt.Request, n gatt.Notifier) {
-		logrus.Info("Split/Interval Data Char Notify Request - launching goroutine")
-		go func() {
-			for true {
-				logrus.Info("Split/Interval Data Notification from goroutine")
-				n.Write(make([]byte, 18))
-				time.Sleep(50000 * time.Millisecond)
+	splitIntervalDataChar.HandleNotifyFunc(sessions.notify(splitIntervalDataChar.UUID(), func(session *Session, n gatt.Notifier) clock.Timer {
+		logrus.Info("Split/Interval Data Char Notify Request")
+		// notify the splits ended once subscribed
+		count := len(w.Splits())
+		return notifyEvery(c, 500*time.Millisecond, n, func() []byte {
+			splits := w.Splits()
+			if len(splits) <= count {
+				return nil
 			}
-		}()	
-	})
+			count = len(splits)
+			logrus.Info("Split/Interval Data Notification")
+			return splitData(w.Metrics(), w.Goal(), splits).Bytes()
+		})
+	}))
 
 
 	/*
 		C2 rowing additional split/interval data characteristic
 	*/
 	additionalSplitIntervalDataChar := s.AddCharacteristic(attrAdditionalSplitIntervalDataCharacteristicsUUID)
-	additionalSplitIntervalDataChar.HandleNotifyFunc(func(r gatt.Request, n gatt.Notifier) {
-		logrus.Info("Additional Split/Interval Data Char Notify Request - launching goroutine")
-		go func() {
-			for true {
-				logrus.Info("Additional Split/Interval Data Notification from goroutine")
-				n.Write(make([]byte, 18))
-				time.Sleep(50000 * time.Millisecond)
+	additionalSplitIntervalDataChar.HandleNotifyFunc(sessions.notify(additionalSplitIntervalDataChar.UUID(), func(session *Session, n gatt.Notifier) clock.Timer {
+		logrus.Info("Additional Split/Interval Data Char Notify Request")
+		// notify the splits ended once subscribed
+		count := len(w.Splits())
+		return notifyEvery(c, 500*time.Millisecond, n, func() []byte {
+			splits := w.Splits()
+			if len(splits) <= count {
+				return nil
 			}
-		}()	
-	})
+			count = len(splits)
+			logrus.Info("Additional Split/Interval Data Notification")
+			return additionalSplitData(w.Metrics(), splits).Bytes()
+		})
+	}))
 
 	/*
 		C2 rowing end of workout summary data characteristic
 	*/
 	endOfWorkoutSummaryDataChar := s.AddCharacteristic(attrEndOfWorkoutSummaryDataCharacteristicsUUID)
-	endOfWorkoutSummaryDataChar.HandleNotifyFunc(func(r gatt.Request, n gatt.Notifier) {
-		logrus.Info("End of workout summary Data Char Notify Request - launching goroutine")
-		go func() {
-			for true {
-				time.Sleep(200000 * time.Millisecond)
-				logrus.Info("End of workout summary Data Notification from goroutine")
-				n.Write(make([]byte, 20))
+	endOfWorkoutSummaryDataChar.HandleNotifyFunc(sessions.notify(endOfWorkoutSummaryDataChar.UUID(), func(session *Session, n gatt.Notifier) clock.Timer {
+		logrus.Info("End of workout summary Data Char Notify Request")
+		// notify the workouts finished once subscribed
+		count := lb.Count()
+		return notifyEvery(c, 500*time.Millisecond, n, func() []byte {
+			if lb.Count() == count {
+				return nil
 			}
-		}()	
-	})
+			count = lb.Count()
+			_, e, ok := lb.Last()
+			if !ok {
+				return nil
+			}
+			logrus.Info("End of workout summary Data Notification")
+			return endOfWorkoutSummary(e).Bytes()
+		})
+	}))
 
 	/*
 		C2 rowing end of workout additional summary data characteristic
 	*/
 	additionalEndOfWorkoutSummaryDataChar := s.AddCharacteristic(attrAdditionalEndOfWorkoutSummaryDataCharacteristicsUUID)
-	additionalEndOfWorkoutSummaryDataChar.HandleNotifyFunc(func(r gatt.Request, n gatt.Notifier) {
-		logrus.Info("End of workout Additional summary Data Char Notify Request - launching goroutine")
-		go func() {
-			for true {
-				time.Sleep(200000 * time.Millisecond)
-				logrus.Info("End of workout Additional summary Data Notification from goroutine")
-				n.Write(make([]byte, 20))
-			}
-		}()	
-	})
+	additionalEndOfWorkoutSummaryDataChar.HandleNotifyFunc(sessions.notify(additionalEndOfWorkoutSummaryDataChar.UUID(), func(session *Session, n gatt.Notifier) clock.Timer {
+		logrus.Info("End of workout Additional summary Data Char Notify Request")
+		return notifyEvery(c, 200000*time.Millisecond, n, func() []byte {
+			logrus.Info("End of workout Additional summary Data Notification")
+			return make([]byte, 20)
+		})
+	}))
 
 
 	/*
 		C2 rowing heart rate belt information characteristic
 	*/
 	heartRateBeltInfoChar := s.AddCharacteristic(attrHeartRateBeltInfoCharacteristicsUUID)
-	heartRateBeltInfoChar.HandleNotifyFunc(func(r gatt.Request, n gatt.Notifier) {
-		logrus.Info("Heart Rate Belt Info Char Notify Request - launching goroutine")
-		go func() {
-			for true {
-				logrus.Info("Heart Rate Belt Data Notification from goroutine")
-				n.Write(make([]byte, 6))
-				time.Sleep(100000 * time.Millisecond)
-
-			}
-		}()	
-	})
+	heartRateBeltInfoChar.HandleNotifyFunc(sessions.notify(heartRateBeltInfoChar.UUID(), func(session *Session, n gatt.Notifier) clock.Timer {
+		logrus.Info("Heart Rate Belt Info Char Notify Request")
+		return notifyEvery(c, 100000*time.Millisecond, n, func() []byte {
+			logrus.Info("Heart Rate Belt Data Notification")
+			return make([]byte, 6)
+		})
+	}))
 
 	/*
 		C2 force curve data characteristic
 	*/
 	forceCurveDataChar := s.AddCharacteristic(attrForceCurveDataCharacteristicsUUID)
-	forceCurveDataChar.HandleNotifyFunc(func(r gatt.Request, n gatt.Notifier) {
-		logrus.Info("Force Curve Data Char Notify Request - launching goroutine")
-		go func() {
-			for true {
-				logrus.Info("Force Curve Data Notification from goroutine")
-				n.Write([]byte{0b000101001, 0x1, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0})
-				time.Sleep(1000 * time.Millisecond)
+	forceCurveDataChar.HandleNotifyFunc(sessions.notify(forceCurveDataChar.UUID(), func(session *Session, n gatt.Notifier) clock.Timer {
+		logrus.Info("Force Curve Data Char Notify Request")
+		// notify the curves of the strokes finished once subscribed
+		_, stroke := w.ForceCurve()
+		return notifyEvery(c, 100*time.Millisecond, n, func() []byte {
+			curve, last := w.ForceCurve()
+			if last == stroke || curve == nil {
+				return nil
 			}
-		}()	
-	})
+			stroke = last
+			logrus.Info("Force Curve Data Notification")
+			for _, data := range forceCurveData(curve) {
+				n.Write(data)
+			}
+			return nil
+		})
+	}))
 
 	/*
 		C2 multiplexed information 	characteristic
@@ -237,10 +258,252 @@ func NewRowingService() *gatt.Service {
 	multiplexedInfoChar.HandleNotifyFunc(func(r gatt.Request, n gatt.Notifier) {
 		logrus.Info("Multiplex Info Char Notify Func")
 		//generate a rowing general status payload here
-		m:=mux.Multiplexer{}
+		m := mux.Multiplexer{}
 		n.Write(m.HandleC2RowingGeneralStatus([]byte{}))
 	})
 
-
 	return s
 }
+
+//notifyEvery writes the data returned by f to n now and then every d of the
+//clock c, until the central unsubscribes or the returned timer is stopped.
+//Nothing is written when f returns nil.
+func notifyEvery(c clock.Clock, d time.Duration, n gatt.Notifier, f func() []byte) clock.Timer {
+	return notifyAt(c, func() time.Duration { return d }, n, f)
+}
+
+//onStroke returns the data f returns from the metrics of the workout w when a
+//stroke finished since the last call and nil otherwise, for each stroke
+//finished once subscribed to be notified once
+func onStroke(w *workout.Workout, f func(m workout.Metrics) []byte) func() []byte {
+	count := w.Metrics().StrokeCount
+	return func() []byte {
+		m := w.Metrics()
+		if m.StrokeCount == count {
+			return nil
+		}
+		count = m.StrokeCount
+		// no stroke finished when a new workout is started
+		if count == 0 {
+			return nil
+		}
+		return f(m)
+	}
+}
+
+//notifyAt is notifyEvery at an interval that can change between notifications
+func notifyAt(c clock.Clock, interval func() time.Duration, n gatt.Notifier, f func() []byte) clock.Timer {
+	var mu sync.Mutex
+	var t clock.Timer
+	notify := func() {
+		if n.Done() {
+			mu.Lock()
+			defer mu.Unlock()
+			t.Stop()
+			return
+		}
+		if data := f(); data != nil {
+			n.Write(data)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if data := f(); data != nil {
+		n.Write(data)
+	}
+	t = clock.EveryFunc(c, interval, notify)
+	return t
+}
+
+//generalStatus returns the general status data of the workout metrics rowed towards goal
+func generalStatus(m workout.Metrics, goal workout.Goal) *mux.Payload {
+	p := mux.NewPayload(mux.Rowing_General_0x31)
+	p.Set("Elapsed_Time", int(m.ElapsedTime/(10*time.Millisecond)))
+	p.Set("Distance", int(math.Round(m.Distance*10)))
+	p.Set("Workout_Type", int(goal.WorkoutType()))
+	p.Set("Interval_Type", config.INTERVALTYPE_NONE)
+
+	switch {
+	case m.ElapsedTime == 0:
+		p.Set("Workout_State", config.WORKOUTSTATE_WAITTOBEGIN)
+	case goal.Reached(m):
+		p.Set("Workout_State", config.WORKOUTSTATE_WORKOUTEND)
+	default:
+		p.Set("Workout_State", config.WORKOUTSTATE_WORKOUTROW)
+	}
+	if m.Speed > 0 {
+		p.Set("Rowing_State", config.ROWINGSTATE_ACTIVE)
+		p.Set("Stroke_State", config.STROKESTATE_RECOVERY_STATE)
+	} else {
+		p.Set("Rowing_State", config.ROWINGSTATE_INACTIVE)
+		p.Set("Stroke_State", config.STROKESTATE_WAITING_FOR_WHEEL_TO_REACH_MIN_SPEED_STATE)
+	}
+	p.Set("Total_Work_Distance", int(m.Distance))
+
+	switch {
+	case goal.Time > 0:
+		p.Set("Workout_Duration", int(goal.Time/(10*time.Millisecond)))
+		p.Set("Workout_Duration_Type", config.CSAFE_TIME_DURATION)
+	case goal.Distance > 0:
+		p.Set("Workout_Duration", int(goal.Distance))
+		p.Set("Workout_Duration_Type", config.CSAFE_DISTANCE_DURATION)
+	case goal.Calories > 0:
+		p.Set("Workout_Duration", goal.Calories)
+		p.Set("Workout_Duration_Type", config.CSAFE_CALORIES_DURATION)
+	}
+	return p
+}
+
+//additionalStatus1 returns the additional status 1 data of the workout metrics
+func additionalStatus1(m workout.Metrics) *mux.Payload {
+	p := mux.NewPayload(mux.Rowing_Additional_0x32)
+	p.Set("Elapsed_Time", int(m.ElapsedTime/(10*time.Millisecond)))
+	p.Set("Speed", int(math.Round(m.Speed*1000)))
+	p.Set("Stroke_Rate", m.StrokeRate)
+	if m.HeartRate > 0 {
+		p.Set("Heartrate", m.HeartRate)
+	} else {
+		p.Set("Heartrate", HEART_RATE_INVALID)
+	}
+	p.Set("Current_Pace", int(m.Pace()/(10*time.Millisecond)))
+	p.Set("Average_Pace", int(m.AvgPace()/(10*time.Millisecond)))
+	p.Set("Average_Power", m.AvgPower())
+	return p
+}
+
+//additionalStatus2 returns the additional status 2 data of the workout
+//metrics and of the split s being rowed
+func additionalStatus2(m workout.Metrics, s workout.Split) *mux.Payload {
+	p := mux.NewPayload(mux.Rowing_Additional_0x33)
+	p.Set("Elapsed_Time", int(m.ElapsedTime/(10*time.Millisecond)))
+	p.Set("Total_Calories", m.Calories)
+	p.Set("Split_Int_Avg_Pace", int(s.AvgPace()/(10*time.Millisecond)))
+	p.Set("Split_Int_Avg_Power", s.AvgPower())
+	return p
+}
+
+//strokeData returns the stroke data of the workout metrics
+func strokeData(m workout.Metrics) *mux.Payload {
+	p := mux.NewPayload(mux.Stroke_Data_0x35)
+	p.Set("Elapsed_Time", int(m.ElapsedTime/(10*time.Millisecond)))
+	p.Set("Distance", int(math.Round(m.Distance*10)))
+	p.Set("Drive_Time", int(m.DriveTime/(10*time.Millisecond)))
+	p.Set("Stroke_Recovery_Time", int(m.RecoveryTime/(10*time.Millisecond)))
+	if m.StrokeRate > 0 {
+		stroke := m.Speed * 60 / float64(m.StrokeRate)
+		p.Set("Stroke_Distance", int(math.Round(stroke*100)))
+	}
+	p.Set("Stroke_Count", m.StrokeCount)
+	return p
+}
+
+//additionalStrokeData returns the additional stroke data of the workout
+//metrics rowed towards goal by a user weighing weight kilograms, the time and
+//distance of the goal projected at the current speed
+func additionalStrokeData(m workout.Metrics, goal workout.Goal, weight float64) *mux.Payload {
+	p := mux.NewPayload(mux.Stroke_Data_0x36)
+	p.Set("Elapsed_Time", int(m.ElapsedTime/(10*time.Millisecond)))
+	p.Set("Stroke_Power", m.Power)
+	if m.Power > 0 {
+		p.Set("Stroke_Calories", int(math.Round(sim.CaloriesPerHour(m.Power, weight))))
+	}
+	p.Set("Stroke_Count", m.StrokeCount)
+	if m.StrokeRate > 0 {
+		work := float64(m.Power) * 60 / float64(m.StrokeRate) // joules
+		p.Set("Work_Per_Stroke", int(math.Round(work*10)))
+	}
+
+	switch {
+	case goal.Time > 0:
+		left := (goal.Time - m.ElapsedTime).Seconds()
+		p.Set("Projected_Work_Time", int(goal.Time.Seconds()))
+		p.Set("Projected_Work_Distance", int(math.Round(m.Distance+m.Speed*math.Max(0, left))))
+	case goal.Distance > 0 && m.Speed > 0:
+		left := math.Max(0, goal.Distance-m.Distance) / m.Speed
+		p.Set("Projected_Work_Time", int(math.Round(m.ElapsedTime.Seconds()+left)))
+		p.Set("Projected_Work_Distance", int(goal.Distance))
+	}
+	return p
+}
+
+//splitData returns the split data of the last of the splits of the workout
+//metrics rowed towards goal
+func splitData(m workout.Metrics, goal workout.Goal, splits []workout.Split) *mux.Payload {
+	p := mux.NewPayload(mux.Split_Interval_0x37)
+	p.Set("Elapsed_Time", int(m.ElapsedTime/(10*time.Millisecond)))
+	p.Set("Distance", int(math.Round(m.Distance*10)))
+	if goal.Time > 0 {
+		p.Set("Split_Interval_Type", config.INTERVALTYPE_TIME)
+	} else {
+		p.Set("Split_Interval_Type", config.INTERVALTYPE_DIST)
+	}
+	if len(splits) > 0 {
+		s := splits[len(splits)-1]
+		p.Set("Split_Interval_Time", int(s.Time/(100*time.Millisecond)))
+		p.Set("Split_Interval_Distance", int(math.Round(s.Distance)))
+	}
+	p.Set("Split_Interval_Number", len(splits))
+	return p
+}
+
+//forceCurveData returns the successive notifications of the force curve,
+//the points in pounds-force. The points beyond what the notifications can
+//hold are dropped.
+func forceCurveData(curve []float64) [][]byte {
+	if len(curve) > FORCE_CURVE_WORDS*FORCE_CURVE_NOTIFICATIONS {
+		curve = curve[:FORCE_CURVE_WORDS*FORCE_CURVE_NOTIFICATIONS]
+	}
+	count := (len(curve) + FORCE_CURVE_WORDS - 1) / FORCE_CURVE_WORDS
+	var list [][]byte
+	for i := 0; i < count; i++ {
+		words := curve[i*FORCE_CURVE_WORDS:]
+		if len(words) > FORCE_CURVE_WORDS {
+			words = words[:FORCE_CURVE_WORDS]
+		}
+		data := []byte{byte(count<<4 | len(words)), byte(i)}
+		for _, f := range words {
+			v := int(math.Round(math.Max(0, f)))
+			data = append(data, byte(v), byte(v>>8))
+		}
+		list = append(list, data)
+	}
+	return list
+}
+
+//additionalSplitData returns the additional split data of the last of the
+//splits of the workout metrics
+func additionalSplitData(m workout.Metrics, splits []workout.Split) *mux.Payload {
+	p := mux.NewPayload(mux.Split_Interval_0x38)
+	p.Set("Elapsed_Time", int(m.ElapsedTime/(10*time.Millisecond)))
+	if len(splits) > 0 {
+		s := splits[len(splits)-1]
+		p.Set("Split_Interval_Avg_Stroke_Rate", s.AvgStrokeRate())
+		p.Set("Split_Interval_Work_Heartrate", m.HeartRate)
+		p.Set("Split_Interval_Avg_Pace", int(s.AvgPace()/(100*time.Millisecond)))
+		p.Set("Split_Interval_Total_Calories", s.Calories)
+		if s.Time > 0 {
+			p.Set("Split_Interval_Avg_Calories", int(math.Round(float64(s.Calories)/s.Time.Hours())))
+			p.Set("Split_Interval_Speed", int(math.Round(s.Distance/s.Time.Seconds()*1000)))
+		}
+		p.Set("Split_Interval_Power", s.AvgPower())
+	}
+	p.Set("Split_Interval_Number", len(splits))
+	return p
+}
+
+//endOfWorkoutSummary returns the end of workout summary data of a logbook entry
+func endOfWorkoutSummary(e logbook.Entry) *mux.Payload {
+	p := mux.NewPayload(mux.Workout_Summary_0x39)
+	p.Set("Log_Entry_Date", int(e.Date.Month())|e.Date.Day()<<4|(e.Date.Year()-LOG_BASE_YEAR)<<9)
+	p.Set("Log_Entry_Time", e.Date.Minute()|e.Date.Hour()<<8)
+	p.Set("Elapsed_Time", int(e.ElapsedTime/(10*time.Millisecond)))
+	p.Set("Distance", int(math.Round(e.Distance*10)))
+	p.Set("Average_Stroke_Rate", e.AvgStrokeRate())
+	p.Set("Workout_Type", int(e.WorkoutType))
+	if e.Distance > 0 {
+		pace := e.ElapsedTime.Seconds() * 500 / e.Distance
+		p.Set("Average_Pace", int(math.Round(pace*10)))
+	}
+	return p
+}
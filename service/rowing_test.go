@@ -0,0 +1,257 @@
+package service
+
+import (
+	"pm5-emulator/clock"
+	"pm5-emulator/config"
+	"pm5-emulator/logbook"
+	"pm5-emulator/user"
+	"pm5-emulator/workout"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdditionalStatus2(t *testing.T) {
+	m := workout.Metrics{ElapsedTime: 83*time.Second + 450*time.Millisecond, Speed: 5, Power: 350, Calories: 300}
+
+	assert.Equal(t, []byte{
+		0x99, 0x20, 0x00, // elapsed time 83.45s
+		0x00,       // interval count
+		0x2C, 0x01, // 300 calories
+		0x10, 0x27, // 1:40.00 pace
+		0x5E, 0x01, // 350 watts
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}, additionalStatus2(m, workout.Split{Time: 100 * time.Second, Distance: 500}).Bytes())
+}
+
+func TestEndOfWorkoutSummary(t *testing.T) {
+	e := logbook.NewEntry(time.Date(2021, time.March, 14, 9, 26, 53, 0, time.UTC), workout.Goal{Distance: 2000},
+		workout.Metrics{ElapsedTime: 8 * time.Minute, Distance: 2000, StrokeCount: 192}, nil)
+
+	assert.Equal(t, []byte{
+		0xE3, 0x2A, // 03/14/2021
+		0x1A, 0x09, // 09:26
+		0x80, 0xBB, 0x00, // elapsed time 480.00s
+		0x20, 0x4E, 0x00, // 2000.0m
+		24,                                 // average stroke rate
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // heart rate and drag factor
+		config.WORKOUTTYPE_FIXEDDIST_NOSPLITS,
+		0xB0, 0x04, // 2:00.0 average pace
+	}, endOfWorkoutSummary(e).Bytes())
+}
+
+func TestStrokeData(t *testing.T) {
+	m := workout.Metrics{ElapsedTime: 83*time.Second + 450*time.Millisecond, Distance: 417.25, Speed: 5, StrokeRate: 30,
+		StrokeCount: 41, DriveTime: 700 * time.Millisecond, RecoveryTime: 1300 * time.Millisecond}
+
+	assert.Equal(t, []byte{
+		0x99, 0x20, 0x00, // elapsed time 83.45s
+		0x4D, 0x10, 0x00, // 417.3m
+		0x00,       // drive length
+		70,         // drive time 0.70s
+		0x82, 0x00, // recovery time 1.30s
+		0xE8, 0x03, // stroke distance 10.00m
+		0x00, 0x00, 0x00, 0x00, // drive forces
+		0x29, 0x00, // 41 strokes
+	}, strokeData(m).Bytes())
+}
+
+func TestGeneralStatus(t *testing.T) {
+	goal := workout.Goal{Distance: 2000}
+
+	assert.Equal(t, []byte{
+		0x00, 0x00, 0x00, // elapsed time
+		0x00, 0x00, 0x00, // distance
+		config.WORKOUTTYPE_FIXEDDIST_NOSPLITS,
+		config.INTERVALTYPE_NONE,
+		config.WORKOUTSTATE_WAITTOBEGIN,
+		config.ROWINGSTATE_INACTIVE,
+		config.STROKESTATE_WAITING_FOR_WHEEL_TO_REACH_MIN_SPEED_STATE,
+		0x00, 0x00, 0x00, // total work distance
+		0xD0, 0x07, 0x00, // 2000m
+		config.CSAFE_DISTANCE_DURATION,
+		0x00, // drag factor
+	}, generalStatus(workout.Metrics{}, goal).Bytes())
+
+	m := workout.Metrics{ElapsedTime: 83*time.Second + 450*time.Millisecond, Distance: 417.25, Speed: 5}
+	assert.Equal(t, []byte{
+		0x99, 0x20, 0x00, // elapsed time 83.45s
+		0x4D, 0x10, 0x00, // 417.3m
+		config.WORKOUTTYPE_FIXEDDIST_NOSPLITS,
+		config.INTERVALTYPE_NONE,
+		config.WORKOUTSTATE_WORKOUTROW,
+		config.ROWINGSTATE_ACTIVE,
+		config.STROKESTATE_RECOVERY_STATE,
+		0xA1, 0x01, 0x00, // 417m
+		0xD0, 0x07, 0x00, // 2000m
+		config.CSAFE_DISTANCE_DURATION,
+		0x00, // drag factor
+	}, generalStatus(m, goal).Bytes())
+
+	m.Distance = 2000
+	assert.Equal(t, byte(config.WORKOUTSTATE_WORKOUTEND), generalStatus(m, goal).Bytes()[8])
+}
+
+func TestAdditionalStatus1(t *testing.T) {
+	m := workout.Metrics{ElapsedTime: 100 * time.Second, Distance: 400, Speed: 5, StrokeRate: 28, Power: 350}
+
+	assert.Equal(t, []byte{
+		0x10, 0x27, 0x00, // elapsed time 100.00s
+		0x88, 0x13, // 5.000 m/s
+		28,         // stroke rate
+		0xFF,       // no heart rate belt
+		0x10, 0x27, // 1:40.00 current pace
+		0xD4, 0x30, // 2:05.00 average pace
+		0x00, 0x00, // rest distance
+		0x00, 0x00, 0x00, // rest time
+		0xB3, 0x00, // 179 watts on average
+	}, additionalStatus1(m).Bytes())
+
+	m.HeartRate = 150
+	assert.Equal(t, byte(150), additionalStatus1(m).Bytes()[6])
+}
+
+func TestAdditionalStrokeData(t *testing.T) {
+	m := workout.Metrics{ElapsedTime: 83*time.Second + 450*time.Millisecond, Distance: 417.25, Speed: 5, StrokeRate: 30,
+		StrokeCount: 41, Power: 350}
+
+	assert.Equal(t, []byte{
+		0x99, 0x20, 0x00, // elapsed time 83.45s
+		0x5E, 0x01, // 350 watts
+		0xE1, 0x05, // 1505 cal/hr
+		0x29, 0x00, // 41 strokes
+		0x90, 0x01, 0x00, // 400s projected
+		0xD0, 0x07, 0x00, // 2000m projected
+		0x58, 0x1B, // 700.0J per stroke
+	}, additionalStrokeData(m, workout.Goal{Distance: 2000}, user.DEFAULT_WEIGHT).Bytes())
+
+	// a time goal projects the distance
+	p := additionalStrokeData(m, workout.Goal{Time: 100 * time.Second}, user.DEFAULT_WEIGHT).Fields()
+	assert.Equal(t, 1505, p["Stroke_Calories"])
+	// the basal burn scales with the weight
+	assert.Equal(t, 1805, additionalStrokeData(m, workout.Goal{}, 2*user.DEFAULT_WEIGHT).Fields()["Stroke_Calories"])
+	assert.Equal(t, 100, p["Projected_Work_Time"])
+	assert.Equal(t, 500, p["Projected_Work_Distance"])
+}
+
+func TestAdditionalSplitData(t *testing.T) {
+	m := workout.Metrics{ElapsedTime: 250 * time.Second, Distance: 1000, HeartRate: 150}
+	splits := []workout.Split{
+		{Time: 2 * time.Minute, Distance: 500},
+		{Time: 130 * time.Second, Distance: 500, Calories: 30, StrokeCount: 52},
+	}
+
+	assert.Equal(t, []byte{
+		0xA8, 0x61, 0x00, // elapsed time 250.00s
+		24,        // stroke rate
+		150, 0x00, // work and rest heart rate
+		0x14, 0x05, // 2:10.0 pace
+		0x1E, 0x00, // 30 calories
+		0x3F, 0x03, // 831 cal/hr
+		0x06, 0x0F, // 3.846 m/s
+		0x9F, 0x00, // 159 watts
+		0x00, // drag factor
+		2,    // split number
+	}, additionalSplitData(m, splits).Bytes())
+}
+
+func TestSplitData(t *testing.T) {
+	m := workout.Metrics{ElapsedTime: 250 * time.Second, Distance: 1000}
+	splits := []workout.Split{
+		{Time: 2 * time.Minute, Distance: 500},
+		{Time: 130 * time.Second, Distance: 500},
+	}
+
+	assert.Equal(t, []byte{
+		0xA8, 0x61, 0x00, // elapsed time 250.00s
+		0x10, 0x27, 0x00, // 1000.0m
+		0x14, 0x05, 0x00, // split time 130.0s
+		0xF4, 0x01, 0x00, // split distance 500m
+		0x00, 0x00, // rest time
+		0x00, 0x00, // rest distance
+		config.INTERVALTYPE_DIST,
+		2, // split number
+	}, splitData(m, workout.Goal{}, splits).Bytes())
+}
+
+type testNotifier struct {
+	sent [][]byte
+	done bool
+}
+
+func (n *testNotifier) Write(data []byte) (int, error) {
+	n.sent = append(n.sent, data)
+	return len(data), nil
+}
+func (n *testNotifier) Done() bool { return n.done }
+func (n *testNotifier) Cap() int   { return 20 }
+
+func TestForceCurveData(t *testing.T) {
+	curve := make([]float64, 20)
+	for i := range curve {
+		curve[i] = float64(i)
+	}
+	curve[0] = 300.4
+
+	data := forceCurveData(curve)
+	assert.Len(t, data, 3)
+	assert.Equal(t, []byte{0x39, 0x00, 0x2C, 0x01, 0x01, 0x00}, data[0][:6]) // 3 notifications of 9 points
+	assert.Len(t, data[0], 20)
+	assert.Equal(t, []byte{0x39, 0x01}, data[1][:2])
+	assert.Equal(t, []byte{0x32, 0x02, 18, 0, 19, 0}, data[2])
+
+	// the points beyond 15 notifications are dropped
+	data = forceCurveData(make([]float64, 200))
+	assert.Len(t, data, FORCE_CURVE_NOTIFICATIONS)
+	assert.Equal(t, byte(0xF9), data[14][0])
+	assert.Empty(t, forceCurveData(nil))
+}
+
+func TestNotifyEvery(t *testing.T) {
+	c := clock.NewManual(time.Time{})
+	n := &testNotifier{}
+	count := 0
+	notifyEvery(c, time.Second, n, func() []byte {
+		count++
+		if count%2 == 0 {
+			return nil
+		}
+		return []byte{byte(count)}
+	})
+	assert.Equal(t, [][]byte{{1}}, n.sent)
+
+	c.Advance(3 * time.Second)
+	assert.Equal(t, [][]byte{{1}, {3}}, n.sent)
+
+	// stops once the central unsubscribed
+	n.done = true
+	c.Advance(3 * time.Second)
+	assert.Equal(t, 4, count)
+}
+
+func TestOnStroke(t *testing.T) {
+	c := clock.NewManual(time.Time{})
+	n := &testNotifier{}
+	w := workout.New()
+	w.Update(func(m *workout.Metrics) { m.StrokeCount = 5 })
+
+	// the stroke finished before subscribing is not notified
+	notifyEvery(c, 100*time.Millisecond, n, onStroke(w, func(m workout.Metrics) []byte {
+		return []byte{byte(m.StrokeCount)}
+	}))
+	c.Advance(time.Second)
+	assert.Empty(t, n.sent)
+
+	// every stroke is notified once however long it takes
+	w.Update(func(m *workout.Metrics) { m.StrokeCount = 6 })
+	c.Advance(3 * time.Second)
+	w.Update(func(m *workout.Metrics) { m.StrokeCount = 7 })
+	c.Advance(100 * time.Millisecond)
+	assert.Equal(t, [][]byte{{6}, {7}}, n.sent)
+
+	// nor is the reset of the workout
+	w.Reset()
+	c.Advance(time.Second)
+	assert.Equal(t, [][]byte{{6}, {7}}, n.sent)
+}
@@ -0,0 +1,241 @@
+package service
+
+import (
+	"fmt"
+	"pm5-emulator/clock"
+	"pm5-emulator/protocol"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bettercap/gatt"
+)
+
+//SAMPLE_RATES are the intervals of the status notifications by the sample
+//rate written to the sample rate characteristic
+var SAMPLE_RATES = []time.Duration{
+	time.Second,
+	500 * time.Millisecond,
+	250 * time.Millisecond,
+	100 * time.Millisecond,
+}
+
+//DEFAULT_SAMPLE_RATE is the sample rate of a central until it writes one
+const DEFAULT_SAMPLE_RATE = 1
+
+//Session is the state of the connection of a central to the emulated PM: its
+//MTU, its csafe protocol session, its subscriptions and the sample rate of
+//its status notifications. The sessions share the emulated machine.
+type Session struct {
+	mu            sync.Mutex
+	id            string
+	mtu           int
+	proto         protocol.Protocol
+	newProto      func() protocol.Protocol
+	tx            gatt.Notifier           // notifier of the csafe responses, when subscribed
+	rate          byte                    // sample rate of the status notifications
+	subscriptions map[string]subscription // by characteristic
+}
+
+//subscription is a subscription of a central to the notifications of a characteristic
+type subscription struct {
+	n gatt.Notifier
+	t clock.Timer // runs the notifications
+}
+
+//Sessions are the sessions of the centrals connected to the emulated PM
+type Sessions struct {
+	mu       sync.Mutex
+	factory  protocol.Factory
+	handler  protocol.Handler
+	sessions map[string]*Session // by central ID
+}
+
+//NewSessions returns the sessions of the centrals, whose csafe frames are read
+//with a protocol created by factory and answered by h
+func NewSessions(factory protocol.Factory, h protocol.Handler) *Sessions {
+	return &Sessions{factory: factory, handler: h, sessions: make(map[string]*Session)}
+}
+
+//Open opens a session for the central c connecting, replacing the one left
+//by a previous connection
+func (s *Sessions) Open(c gatt.Central) *Session {
+	s.mu.Lock()
+	old := s.sessions[c.ID()]
+	session := s.open(c)
+	s.mu.Unlock()
+	if old != nil {
+		old.close()
+	}
+	return session
+}
+
+//open opens a session for the central c, the lock must be held
+func (s *Sessions) open(c gatt.Central) *Session {
+	newProto := func() protocol.Protocol { return s.factory(s.handler) }
+	session := &Session{
+		id:            c.ID(),
+		mtu:           c.MTU(),
+		proto:         newProto(),
+		newProto:      newProto,
+		rate:          DEFAULT_SAMPLE_RATE,
+		subscriptions: make(map[string]subscription),
+	}
+	s.sessions[c.ID()] = session
+	return session
+}
+
+//Close closes the session of the central id disconnecting, stopping its notifications
+func (s *Sessions) Close(id string) {
+	s.mu.Lock()
+	session, ok := s.sessions[id]
+	delete(s.sessions, id)
+	s.mu.Unlock()
+	if ok {
+		session.close()
+	}
+}
+
+//Get returns the session of the central c, opened on its first request when
+//its connection was not reported, as for the centrals connected in process
+func (s *Sessions) Get(c gatt.Central) *Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if session, ok := s.sessions[c.ID()]; ok {
+		return session
+	}
+	return s.open(c)
+}
+
+//notify returns the notify handler of the characteristic u running the
+//notifications started by f, kept in the session of the central subscribing
+func (s *Sessions) notify(u gatt.UUID, f func(session *Session, n gatt.Notifier) clock.Timer) func(gatt.Request, gatt.Notifier) {
+	return func(r gatt.Request, n gatt.Notifier) {
+		session := s.Get(r.Central)
+		session.subscribed(u, n, f(session, n))
+	}
+}
+
+//IDs returns the IDs of the centrals with a session, sorted
+func (s *Sessions) IDs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]string, 0, len(s.sessions))
+	for id := range s.sessions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+//ID returns the ID of the central of the session
+func (s *Session) ID() string {
+	return s.id
+}
+
+//MTU returns the MTU of the connection
+func (s *Session) MTU() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.mtu
+}
+
+//protocol returns the csafe protocol session of the central
+func (s *Session) protocol() protocol.Protocol {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.proto
+}
+
+//subscribeTx sets the notifier and MTU of the csafe responses and starts a
+//new protocol session
+func (s *Session) subscribeTx(n gatt.Notifier, mtu int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tx = n
+	s.mtu = mtu
+	s.proto = s.newProto()
+}
+
+//send splits the frame into MTU sized notifications and writes them in order
+//to the central of the session
+func (s *Session) send(frame []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tx == nil || s.tx.Done() {
+		return fmt.Errorf("central %s is not subscribed to transmit characteristic", s.id)
+	}
+
+	chunks, err := protocol.Fragment(frame, s.mtu)
+	if err != nil {
+		return err
+	}
+	for _, chunk := range chunks {
+		if _, err := s.tx.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//SampleRate returns the sample rate of the status notifications
+func (s *Session) SampleRate() byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rate
+}
+
+//SetSampleRate sets the sample rate of the status notifications, one of the
+//indexes of SAMPLE_RATES
+func (s *Session) SetSampleRate(rate byte) error {
+	if int(rate) >= len(SAMPLE_RATES) {
+		return fmt.Errorf("invalid sample rate %d", rate)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rate = rate
+	return nil
+}
+
+//sampleInterval returns the interval of the status notifications
+func (s *Session) sampleInterval() time.Duration {
+	return SAMPLE_RATES[s.SampleRate()]
+}
+
+//subscribed keeps the subscription to the characteristic u notified by n
+//with the timer t, stopping the notifications of a previous subscription
+func (s *Session) subscribed(u gatt.UUID, n gatt.Notifier, t clock.Timer) {
+	s.mu.Lock()
+	old, ok := s.subscriptions[u.String()]
+	s.subscriptions[u.String()] = subscription{n: n, t: t}
+	s.mu.Unlock()
+	if ok {
+		old.t.Stop()
+	}
+}
+
+//Subscriptions returns the characteristics the central is subscribed to, sorted
+func (s *Session) Subscriptions() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	chars := make([]string, 0, len(s.subscriptions))
+	for char, sub := range s.subscriptions {
+		if !sub.n.Done() {
+			chars = append(chars, char)
+		}
+	}
+	sort.Strings(chars)
+	return chars
+}
+
+//close stops the notifications of the session
+func (s *Session) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sub := range s.subscriptions {
+		sub.t.Stop()
+	}
+	s.subscriptions = make(map[string]subscription)
+	s.tx = nil
+}
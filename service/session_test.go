@@ -0,0 +1,76 @@
+package service
+
+import (
+	"pm5-emulator/clock"
+	"pm5-emulator/logbook"
+	"pm5-emulator/protocol"
+	"pm5-emulator/sm"
+	"pm5-emulator/transport"
+	"pm5-emulator/user"
+	"pm5-emulator/workout"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//countingProtocol answers each payload with the number of payloads it read
+type countingProtocol struct {
+	count byte
+}
+
+func (p *countingProtocol) Name() string { return "counting" }
+
+func (p *countingProtocol) ReadPayload(payload []byte) ([]byte, error) {
+	p.count++
+	return []byte{p.count}, nil
+}
+
+func (p *countingProtocol) WritePayload(payload []byte) ([]byte, error) { return payload, nil }
+
+func TestSessions(t *testing.T) {
+	c := clock.NewManual(time.Time{})
+	sessions := NewSessions(func(protocol.Handler) protocol.Protocol { return &countingProtocol{} }, nil)
+	l := transport.NewLoopback(NewControlService(sessions), NewRowingService(workout.New(), user.New(sm.NewStateMachine()), logbook.New(), c, sessions))
+	app := l.Connect("app", 185)
+	tool := l.Connect("tool", 23)
+	sessions.Open(app)
+	sessions.Open(tool)
+	assert.Equal(t, []string{"app", "tool"}, sessions.IDs())
+
+	// each central has its own csafe session, answered to it only
+	rx, tx := ControlCharacteristics()
+	var appReplies, toolReplies [][]byte
+	assert.NoError(t, app.Subscribe(tx, func(data []byte) { appReplies = append(appReplies, data) }))
+	assert.NoError(t, tool.Subscribe(tx, func(data []byte) { toolReplies = append(toolReplies, data) }))
+	assert.NoError(t, app.Write(rx, []byte{0x01}))
+	assert.NoError(t, app.Write(rx, []byte{0x01}))
+	assert.NoError(t, tool.Write(rx, []byte{0x01}))
+	assert.Equal(t, [][]byte{{1}, {2}}, appReplies)
+	assert.Equal(t, [][]byte{{1}}, toolReplies)
+
+	// each central has its own sample rate
+	assert.NoError(t, app.Write(attrSampleRateCharacteristicsUUID, []byte{3}))
+	assert.Error(t, tool.Write(attrSampleRateCharacteristicsUUID, []byte{4}))
+	rate, err := app.Read(attrSampleRateCharacteristicsUUID)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{3}, rate)
+	rate, err = tool.Read(attrSampleRateCharacteristicsUUID)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{DEFAULT_SAMPLE_RATE}, rate)
+
+	appStatus, toolStatus := 0, 0
+	assert.NoError(t, app.Subscribe(attrGeneralStatusCharacteristicsUUID, func([]byte) { appStatus++ }))
+	assert.NoError(t, tool.Subscribe(attrGeneralStatusCharacteristicsUUID, func([]byte) { toolStatus++ }))
+	c.Advance(time.Second)
+	assert.Equal(t, 11, appStatus)
+	assert.Equal(t, 3, toolStatus)
+	assert.Equal(t, []string{attrGeneralStatusCharacteristicsUUID.String()}, sessions.Get(tool).Subscriptions())
+
+	// closing a session stops its notifications only
+	sessions.Close("app")
+	c.Advance(time.Second)
+	assert.Equal(t, 11, appStatus)
+	assert.Equal(t, 5, toolStatus)
+	assert.Equal(t, []string{"tool"}, sessions.IDs())
+}
@@ -0,0 +1,169 @@
+package sim
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+//DEFAULT_PERSONA rows the same stroke at the default rate and power
+const DEFAULT_PERSONA = "steady"
+
+//Persona describes how a simulated athlete rows
+type Persona struct {
+	Name           string  `json:"name"`
+	MinStrokeRate  int     `json:"min_stroke_rate"`  // strokes per minute
+	MaxStrokeRate  int     `json:"max_stroke_rate"`  // strokes per minute
+	MinPower       int     `json:"min_power"`        // watts rowed at the minimum stroke rate
+	MaxPower       int     `json:"max_power"`        // watts rowed at the maximum stroke rate
+	DriveRatio     float64 `json:"drive_ratio"`      // drive time over recovery time
+	Fatigue        float64 `json:"fatigue"`          // fraction of the power lost per hour of rowing
+	Variability    float64 `json:"variability"`      // standard deviation of the stroke rate and power, as a fraction
+	RestHeartRate  int     `json:"rest_heart_rate"`  // beats per minute at rest, 0 when rowing without a heart rate belt
+	HeartRateNoise float64 `json:"heart_rate_noise"` // standard deviation of the heart rate, in beats per minute
+}
+
+//HEART_RATE_LAG is the time constant of the heart rate of the athletes
+//following the effort, the heart rate is two thirds of the way to its
+//target after rowing for HEART_RATE_LAG at the same power
+const HEART_RATE_LAG = 30 * time.Second
+
+//MaxHeartRate returns the maximum heart rate of an athlete of age years,
+//220 minus the age
+func MaxHeartRate(age int) int {
+	return 220 - age
+}
+
+//personas are the built in athletes
+var personas = map[string]Persona{
+	DEFAULT_PERSONA: {
+		Name: DEFAULT_PERSONA, MinStrokeRate: DEFAULT_STROKE_RATE, MaxStrokeRate: DEFAULT_STROKE_RATE,
+		MinPower: DEFAULT_POWER, MaxPower: DEFAULT_POWER, DriveRatio: 0.5, RestHeartRate: 60,
+	},
+	"novice": {
+		Name: "novice", MinStrokeRate: 18, MaxStrokeRate: 26, MinPower: 70, MaxPower: 130,
+		DriveRatio: 0.8, Fatigue: 0.3, Variability: 0.08, RestHeartRate: 75, HeartRateNoise: 3,
+	},
+	"club": {
+		Name: "club", MinStrokeRate: 20, MaxStrokeRate: 30, MinPower: 140, MaxPower: 250,
+		DriveRatio: 0.5, Fatigue: 0.1, Variability: 0.04, RestHeartRate: 60, HeartRateNoise: 2,
+	},
+	"elite": {
+		Name: "elite", MinStrokeRate: 22, MaxStrokeRate: 38, MinPower: 260, MaxPower: 480,
+		DriveRatio: 0.45, Fatigue: 0.04, Variability: 0.02, RestHeartRate: 45, HeartRateNoise: 1,
+	},
+	"para": {
+		Name: "para", MinStrokeRate: 24, MaxStrokeRate: 34, MinPower: 50, MaxPower: 110,
+		DriveRatio: 0.7, Fatigue: 0.15, Variability: 0.05, RestHeartRate: 70, HeartRateNoise: 2,
+	},
+	"erratic": {
+		Name: "erratic", MinStrokeRate: 16, MaxStrokeRate: 34, MinPower: 80, MaxPower: 220,
+		DriveRatio: 1, Fatigue: 0.2, Variability: 0.25, RestHeartRate: 80, HeartRateNoise: 6,
+	},
+}
+
+//LookupPersona returns a built in persona
+func LookupPersona(name string) (Persona, error) {
+	p, ok := personas[name]
+	if !ok {
+		return Persona{}, fmt.Errorf("sim: unknown persona %q", name)
+	}
+	return p, nil
+}
+
+//Personas returns a sorted list of the names of the built in personas
+func Personas() []string {
+	var list []string
+	for name := range personas {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
+
+//target returns the stroke rate and power the persona rows at, fresh, when
+//asked to row at rate strokes per minute and power watts. A zero rate is
+//found on the power curve, at the middle of the range when power is 0 too.
+func (p Persona) target(rate, power int) (float64, float64) {
+	minRate, maxRate := float64(p.MinStrokeRate), float64(p.MaxStrokeRate)
+	minPower, maxPower := float64(p.MinPower), float64(p.MaxPower)
+
+	switch {
+	case rate > 0 && power > 0:
+		return float64(rate), float64(power)
+	case rate > 0:
+		return float64(rate), p.powerAt(float64(rate))
+	}
+	if power <= 0 {
+		spm := (minRate + maxRate) / 2
+		return spm, p.powerAt(spm)
+	}
+	if maxPower <= minPower {
+		return minRate, float64(power)
+	}
+	spm := minRate + (float64(power)-minPower)/(maxPower-minPower)*(maxRate-minRate)
+	return math.Max(minRate, math.Min(maxRate, spm)), float64(power)
+}
+
+//powerAt returns the power rowed at a stroke rate on the power curve of the persona
+func (p Persona) powerAt(rate float64) float64 {
+	if p.MaxStrokeRate <= p.MinStrokeRate {
+		return float64(p.MinPower)
+	}
+	f := (rate - float64(p.MinStrokeRate)) / float64(p.MaxStrokeRate-p.MinStrokeRate)
+	return float64(p.MinPower) + f*float64(p.MaxPower-p.MinPower)
+}
+
+//heartRate returns the heart rate the persona tends to when rowing at power
+//watts with a maximum heart rate of max, from a third of the way between the
+//rest and maximum heart rates to the maximum at the top of its power range
+func (p Persona) heartRate(power, max int) float64 {
+	if p.RestHeartRate <= 0 {
+		return 0
+	}
+	effort := 1.0
+	if p.MaxPower > 0 {
+		effort = math.Min(1, 1.0/3+2.0/3*float64(power)/float64(p.MaxPower))
+	}
+	return float64(p.RestHeartRate) + effort*float64(max-p.RestHeartRate)
+}
+
+//stroke is a single stroke of a simulated athlete
+type stroke struct {
+	rate      int
+	power     int
+	duration  time.Duration
+	drive     time.Duration
+	rowed     time.Duration // time rowed into the stroke
+	heartRate float64       // beats per minute the heart rate is off its trend during the stroke
+}
+
+//stroke returns the next stroke of the persona asked to row at rate and
+//power after rowing for elapsed, varied with normally distributed numbers
+//drawn by norm
+func (p Persona) stroke(rate, power int, elapsed time.Duration, norm func() float64) stroke {
+	spm, watts := p.target(rate, power)
+	watts *= math.Max(0, 1-p.Fatigue*elapsed.Hours())
+	if p.Variability > 0 {
+		spm *= 1 + p.Variability*norm()
+		watts *= 1 + p.Variability*norm()
+	}
+	var heartRate float64
+	if p.HeartRateNoise > 0 {
+		heartRate = p.HeartRateNoise * norm()
+	}
+	if rate <= 0 {
+		spm = math.Max(float64(p.MinStrokeRate), math.Min(float64(p.MaxStrokeRate), spm))
+	}
+	spm = math.Max(1, spm)
+
+	s := stroke{
+		rate:      int(math.Round(spm)),
+		power:     int(math.Max(1, math.Round(watts))),
+		duration:  time.Duration(float64(time.Minute) / spm),
+		heartRate: heartRate,
+	}
+	s.drive = time.Duration(float64(s.duration) * p.DriveRatio / (1 + p.DriveRatio))
+	return s
+}
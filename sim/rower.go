@@ -0,0 +1,306 @@
+package sim
+
+import (
+	"errors"
+	"math"
+	"pm5-emulator/clock"
+	"pm5-emulator/config"
+	"pm5-emulator/random"
+	"pm5-emulator/sm"
+	"pm5-emulator/user"
+	"pm5-emulator/workout"
+	"sync"
+	"time"
+)
+
+//Defaults of the simulated rower when the workout has no target
+const (
+	DEFAULT_STROKE_RATE = 24  // strokes per minute
+	DEFAULT_POWER       = 150 // watts
+)
+
+//Errors returned when pausing or resuming in the wrong state
+var (
+	errNotRowing = errors.New("no workout is being rowed")
+	errNotPaused = errors.New("the workout is not paused")
+)
+
+//TICK is the interval of the clock at which the simulation advances
+const TICK = 100 * time.Millisecond
+
+//PowerForPace returns the power in watts needed to row 500m in pace
+func PowerForPace(pace time.Duration) int {
+	if pace <= 0 {
+		return 0
+	}
+	return int(math.Round(2.80 / math.Pow(pace.Seconds()/500, 3)))
+}
+
+//SpeedForPower returns the boat speed in m/s for a power in watts, using the
+//Concept2 relation watts = 2.80 / pace^3 with pace in seconds per meter
+func SpeedForPower(watts int) float64 {
+	if watts <= 0 {
+		return 0
+	}
+	return math.Cbrt(float64(watts) / 2.80)
+}
+
+//CaloriesPerHour returns the calories burned per hour at a power in watts
+//by a user weighing weight kilograms. Concept2 monitors add 300 cal/hr of
+//basal burn for a 175 lb user, which is scaled to the user weight.
+func CaloriesPerHour(watts int, weight float64) float64 {
+	return 4*float64(watts)*0.8604 + 300*weight/user.DEFAULT_WEIGHT
+}
+
+//Drive of the force curves
+const (
+	DRIVE_LENGTH          = 1.4                   // meters the handle travels during the drive
+	NEWTONS_PER_LB        = 4.44822               // newtons in a pound-force
+	FORCE_SAMPLE_INTERVAL = 20 * time.Millisecond // time between the points of a force curve
+)
+
+//ForceCurve returns the force on the handle, in pounds-force as plotted by the
+//PM, at points evenly spaced along the drive of a stroke rowed at rate strokes
+//per minute and power watts. The curve is a half sine whose area is the work
+//of the stroke, nil when not rowing.
+func ForceCurve(rate, power, points int) []float64 {
+	if rate <= 0 || power <= 0 || points <= 0 {
+		return nil
+	}
+	work := float64(power) * 60 / float64(rate) // joules per stroke
+	peak := work / DRIVE_LENGTH * math.Pi / 2 / NEWTONS_PER_LB
+	curve := make([]float64, points)
+	for i := range curve {
+		curve[i] = peak * math.Sin(math.Pi*(float64(i)+0.5)/float64(points))
+	}
+	return curve
+}
+
+//StrokeForceCurve returns the force curve of a stroke rowed at rate strokes
+//per minute and power watts, with a point every FORCE_SAMPLE_INTERVAL of a
+//drive lasting drive, a third of the stroke when 0
+func StrokeForceCurve(rate, power int, drive time.Duration) []float64 {
+	if rate <= 0 {
+		return nil
+	}
+	if drive <= 0 {
+		drive = time.Minute / time.Duration(rate) / 3
+	}
+	points := int(drive / FORCE_SAMPLE_INTERVAL)
+	if points < 1 {
+		points = 1
+	}
+	return ForceCurve(rate, power, points)
+}
+
+//Rower simulates an athlete rowing the workout of the emulated PM. The
+//workout starts when the state machine goes in use and finishes the state
+//machine once its goal is reached.
+type Rower struct {
+	mu       sync.Mutex
+	stm      *sm.StateMachine
+	workout  *workout.Workout
+	user     *user.User
+	clock    clock.Clock
+	rand     *random.Rand
+	ticker   clock.Timer
+	persona  Persona
+	rate     int           // stroke rate asked for, 0 to row as the persona
+	power    int           // power asked for, 0 to row the workout target
+	stroke   stroke        // stroke being rowed
+	calories float64       // calories burned, kept unrounded between steps
+	heart    float64       // heart rate trend, kept unrounded between steps
+	stop     chan struct{} // closed by Stop
+	once     sync.Once
+}
+
+//NewRower returns a rower for the workout w, driven by stm, burning
+//calories according to the profile of u and rowing on the clock c. The
+//rower rows as the default persona, varying its strokes with rnd.
+func NewRower(stm *sm.StateMachine, w *workout.Workout, u *user.User, c clock.Clock, rnd *random.Rand) *Rower {
+	r := &Rower{
+		stm:     stm,
+		workout: w,
+		user:    u,
+		clock:   c,
+		rand:    rnd,
+		persona: personas[DEFAULT_PERSONA],
+		stop:    make(chan struct{}),
+	}
+	stm.OnTransition(r.onTransition)
+	return r
+}
+
+//onTransition starts and clears the workout following the state machine
+func (r *Rower) onTransition(from, to string) {
+	switch {
+	case to == config.PM5_STATE_INUSE && from != config.PM5_STATE_PAUSED:
+		r.mu.Lock()
+		r.calories = 0
+		r.heart = 0
+		r.stroke = stroke{}
+		r.mu.Unlock()
+		r.workout.Start()
+	case to == config.PM5_STATE_READY || from == config.PM5_STATE_FINISHED:
+		r.workout.Reset()
+	}
+}
+
+//Persona returns the persona the rower rows as
+func (r *Rower) Persona() Persona {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.persona
+}
+
+//SetPersona changes the persona the rower rows as, from the next stroke
+func (r *Rower) SetPersona(p Persona) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.persona = p
+}
+
+//SetTarget asks the rower to row at rate strokes per minute and power
+//watts from the next stroke, 0 leaves them to the persona and the workout
+func (r *Rower) SetTarget(rate, power int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rate = rate
+	r.power = power
+}
+
+//Target returns the stroke rate and power the rower was asked to row at
+func (r *Rower) Target() (rate, power int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rate, r.power
+}
+
+//Pause stops rowing, pausing the workout in use
+func (r *Rower) Pause() error {
+	if r.stm.GetStateName() != config.PM5_STATE_INUSE {
+		return errNotRowing
+	}
+	r.stm.SetState(config.PM5_STATE_PAUSED)
+	return nil
+}
+
+//Resume rows again after Pause
+func (r *Rower) Resume() error {
+	if r.stm.GetStateName() != config.PM5_STATE_PAUSED {
+		return errNotPaused
+	}
+	r.stm.SetState(config.PM5_STATE_INUSE)
+	return nil
+}
+
+//Start advances the simulation every TICK of the clock until Stop is called
+func (r *Rower) Start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ticker = clock.Every(r.clock, TICK, func() { r.Step(TICK) })
+}
+
+//Run starts the simulation and blocks until Stop is called
+func (r *Rower) Run() {
+	r.Start()
+	<-r.stop
+}
+
+//Stop ends the simulation, it can be called more than once
+func (r *Rower) Stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ticker != nil {
+		r.ticker.Stop()
+	}
+	r.once.Do(func() { close(r.stop) })
+}
+
+//Step advances the workout by dt when the state machine is in use
+func (r *Rower) Step(dt time.Duration) {
+	if r.stm.GetStateName() != config.PM5_STATE_INUSE {
+		return
+	}
+	r.stm.Active()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	goal := r.workout.Goal()
+	profile := r.user.Profile()
+	weight, maxHeartRate := profile.Weight, MaxHeartRate(profile.Age)
+	power := goal.Power
+	if r.power > 0 {
+		power = r.power
+	}
+
+	var reached bool
+	var last *stroke // last stroke finished during the step
+	r.workout.Update(func(m *workout.Metrics) {
+		if r.stroke.duration == 0 {
+			r.stroke = r.persona.stroke(r.rate, power, m.ElapsedTime, r.rand.NormFloat64)
+		}
+		s := r.stroke
+		speed := SpeedForPower(s.power)
+
+		m.ElapsedTime += dt
+		m.Distance += speed * dt.Seconds()
+		m.Speed = speed
+		m.Power = s.power
+		m.StrokeRate = s.rate
+		m.DriveTime = s.drive
+		m.RecoveryTime = s.duration - s.drive
+
+		r.calories += CaloriesPerHour(s.power, weight) * dt.Hours()
+		m.Calories = int(r.calories)
+
+		// the heart rate follows the effort from rest, noisy from stroke to stroke
+		if target := r.persona.heartRate(s.power, maxHeartRate); target > 0 {
+			if r.heart == 0 {
+				r.heart = float64(r.persona.RestHeartRate)
+			}
+			r.heart += (target - r.heart) * (1 - math.Exp(-float64(dt)/float64(HEART_RATE_LAG)))
+			m.HeartRate = int(math.Round(r.heart + s.heartRate))
+		} else {
+			r.heart = 0
+			m.HeartRate = 0
+		}
+
+		// the strokes finished during the step
+		r.stroke.rowed += dt
+		for r.stroke.rowed >= r.stroke.duration {
+			m.StrokeCount++
+			finished := r.stroke
+			last = &finished
+			rowed := r.stroke.rowed - r.stroke.duration
+			r.stroke = r.persona.stroke(r.rate, power, m.ElapsedTime, r.rand.NormFloat64)
+			r.stroke.rowed = rowed
+		}
+
+		if reached = goal.Reached(*m); reached {
+			finish(m, goal)
+		}
+	})
+
+	if last != nil {
+		r.workout.SetForceCurve(StrokeForceCurve(last.rate, last.power, last.drive))
+	}
+	if reached {
+		r.stm.Update(config.CSAFE_GOFINISHED_CMD)
+	}
+}
+
+//finish trims the time or distance rowed past the goal in the last step
+func finish(m *workout.Metrics, goal workout.Goal) {
+	switch {
+	case goal.Time > 0:
+		over := m.ElapsedTime - goal.Time
+		m.Distance -= m.Speed * over.Seconds()
+		m.ElapsedTime = goal.Time
+	case goal.Distance > 0:
+		over := time.Duration((m.Distance - goal.Distance) / m.Speed * float64(time.Second))
+		m.ElapsedTime -= over
+		m.Distance = goal.Distance
+	}
+}
@@ -0,0 +1,293 @@
+package sim
+
+import (
+	"math"
+	"pm5-emulator/clock"
+	"pm5-emulator/config"
+	"pm5-emulator/random"
+	"pm5-emulator/sm"
+	"pm5-emulator/user"
+	"pm5-emulator/workout"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRower() (*Rower, *sm.StateMachine, *workout.Workout) {
+	stm := sm.NewStateMachine()
+	stm.Reset()
+	w := workout.New()
+	return NewRower(stm, w, user.New(stm), clock.Real{}, random.New(1)), stm, w
+}
+
+func TestSpeedForPower(t *testing.T) {
+	// 2:00/500m is rowed at about 203W
+	assert.InDelta(t, 500.0/120, SpeedForPower(203), 0.01)
+	assert.Equal(t, 0.0, SpeedForPower(0))
+}
+
+func TestCaloriesPerHour(t *testing.T) {
+	// Concept2 formula for a 175 lb user
+	assert.InDelta(t, 4*150*0.8604+300, CaloriesPerHour(150, user.DEFAULT_WEIGHT), 0.001)
+	// basal burn scales with the weight
+	assert.InDelta(t, 4*150*0.8604+600, CaloriesPerHour(150, 2*user.DEFAULT_WEIGHT), 0.001)
+}
+
+func TestRower_Step(t *testing.T) {
+	r, stm, w := newTestRower()
+
+	// Nothing happens before the workout is in use
+	r.Step(time.Second)
+	assert.Equal(t, workout.Metrics{}, w.Metrics())
+
+	stm.Update(config.CSAFE_GOIDLE_CMD)
+	w.SetGoal(func(g *workout.Goal) {
+		g.Distance = 100
+		g.Power = 203
+	})
+	stm.Update(config.CSAFE_GOINUSE_CMD)
+
+	for i := 0; i < 10; i++ {
+		r.Step(time.Second)
+	}
+	m := w.Metrics()
+	assert.Equal(t, 10*time.Second, m.ElapsedTime)
+	assert.InDelta(t, 41.7, m.Distance, 0.1)
+	assert.Equal(t, 203, m.Power)
+	assert.Equal(t, DEFAULT_STROKE_RATE, m.StrokeRate)
+	assert.Equal(t, 4, m.StrokeCount)
+	assert.Equal(t, config.PM5_STATE_INUSE, stm.GetStateName())
+	// the force curve of the last stroke finished
+	curve, stroke := w.ForceCurve()
+	assert.Equal(t, 4, stroke)
+	assert.NotEmpty(t, curve)
+
+	// Goal reached
+	for i := 0; i < 20; i++ {
+		r.Step(time.Second)
+	}
+	m = w.Metrics()
+	assert.Equal(t, 100.0, m.Distance)
+	assert.InDelta(t, float64(24*time.Second), float64(m.ElapsedTime), float64(100*time.Millisecond))
+	assert.Equal(t, config.PM5_STATE_FINISHED, stm.GetStateName())
+
+	// Results stay until the state machine leaves FINISHED
+	assert.Equal(t, 100.0, w.Metrics().Distance)
+	stm.Update(config.CSAFE_GOIDLE_CMD)
+	assert.Equal(t, workout.Metrics{}, w.Metrics())
+	assert.Equal(t, workout.Goal{}, w.Goal())
+}
+
+func TestRower_TimeGoal(t *testing.T) {
+	r, stm, w := newTestRower()
+
+	stm.Update(config.CSAFE_GOIDLE_CMD)
+	w.SetGoal(func(g *workout.Goal) { g.Time = 90 * time.Second })
+	stm.Update(config.CSAFE_GOINUSE_CMD)
+
+	for i := 0; i < 4; i++ {
+		r.Step(time.Minute)
+	}
+	m := w.Metrics()
+	assert.Equal(t, 90*time.Second, m.ElapsedTime)
+	assert.InDelta(t, SpeedForPower(DEFAULT_POWER)*90, m.Distance, 0.001)
+	assert.Equal(t, int(CaloriesPerHour(DEFAULT_POWER, user.DEFAULT_WEIGHT)*2/60), m.Calories)
+	assert.Equal(t, config.PM5_STATE_FINISHED, stm.GetStateName())
+}
+
+func TestRower_CalorieGoalWeight(t *testing.T) {
+	// rows a 50 calorie goal for a user weighing weight kilograms, returning
+	// the calories burned after a minute and the time to reach the goal
+	row := func(weight float64) (int, time.Duration) {
+		r, stm, w := newTestRower()
+		p := user.DefaultProfile()
+		p.Weight = weight
+		r.user.SetProfile(p)
+
+		stm.Update(config.CSAFE_GOIDLE_CMD)
+		w.SetGoal(func(g *workout.Goal) { g.Calories = 50 })
+		stm.Update(config.CSAFE_GOINUSE_CMD)
+
+		calories := 0
+		for stm.GetStateName() == config.PM5_STATE_INUSE {
+			r.Step(time.Second)
+			if w.Metrics().ElapsedTime == time.Minute {
+				calories = w.Metrics().Calories
+			}
+		}
+		m := w.Metrics()
+		assert.Equal(t, 50, m.Calories)
+		want := 50 / CaloriesPerHour(DEFAULT_POWER, weight) * 3600
+		assert.InDelta(t, want, m.ElapsedTime.Seconds(), 1)
+		return calories, m.ElapsedTime
+	}
+
+	lightCalories, lightTime := row(50)
+	heavyCalories, heavyTime := row(120)
+	// the heavier user burns more and reaches the goal sooner
+	assert.Equal(t, int(CaloriesPerHour(DEFAULT_POWER, 50)/60), lightCalories)
+	assert.Equal(t, int(CaloriesPerHour(DEFAULT_POWER, 120)/60), heavyCalories)
+	assert.Less(t, lightCalories, heavyCalories)
+	assert.Greater(t, int64(lightTime-heavyTime), int64(10*time.Second))
+}
+
+func TestRower_Start(t *testing.T) {
+	c := clock.NewManual(time.Time{})
+	stm := sm.NewStateMachine()
+	stm.SetClock(c)
+	stm.Reset()
+	w := workout.New()
+	r := NewRower(stm, w, user.New(stm), c, random.New(1))
+	r.Start()
+	defer r.Stop()
+
+	stm.Update(config.CSAFE_GOIDLE_CMD)
+	w.SetGoal(func(g *workout.Goal) {
+		g.Time = time.Minute
+	})
+	stm.Update(config.CSAFE_GOINUSE_CMD)
+
+	// the workout follows the clock, tick by tick
+	c.Advance(30*time.Second + TICK/2)
+	assert.Equal(t, 30*time.Second, w.Metrics().ElapsedTime)
+	assert.Equal(t, config.PM5_STATE_INUSE, stm.GetStateName())
+
+	c.Advance(time.Minute)
+	assert.Equal(t, time.Minute, w.Metrics().ElapsedTime)
+	assert.Equal(t, config.PM5_STATE_FINISHED, stm.GetStateName())
+
+	// stopping again does nothing
+	r.Stop()
+	assert.NotPanics(t, r.Stop)
+}
+
+func TestRower_Personas(t *testing.T) {
+	for _, name := range Personas() {
+		t.Run(name, func(t *testing.T) {
+			p, err := LookupPersona(name)
+			assert.NoError(t, err)
+
+			row := func(seed int64) workout.Metrics {
+				r, stm, w := newTestRower()
+				r.rand = random.New(seed)
+				r.SetPersona(p)
+				stm.Update(config.CSAFE_GOIDLE_CMD)
+				stm.Update(config.CSAFE_GOINUSE_CMD)
+				for i := 0; i < 600; i++ {
+					r.Step(TICK)
+				}
+				return w.Metrics()
+			}
+
+			m := row(7)
+			// the same seed rows the same workout
+			assert.Equal(t, m, row(7))
+
+			assert.Equal(t, time.Minute, m.ElapsedTime)
+			assert.True(t, m.StrokeRate >= p.MinStrokeRate && m.StrokeRate <= p.MaxStrokeRate, "stroke rate %d", m.StrokeRate)
+			assert.InDelta(t, (p.MinStrokeRate+p.MaxStrokeRate)/2, m.StrokeCount, float64(p.MaxStrokeRate-p.MinStrokeRate)/2+1)
+			assert.True(t, m.Power > 0)
+			assert.True(t, m.HeartRate > p.RestHeartRate/2 && m.HeartRate <= MaxHeartRate(user.DEFAULT_AGE)+int(3*p.HeartRateNoise), "heart rate %d", m.HeartRate)
+			ratio := float64(m.DriveTime) / float64(m.RecoveryTime)
+			assert.InDelta(t, p.DriveRatio, ratio, 0.01)
+		})
+	}
+
+	_, err := LookupPersona("cox")
+	assert.Error(t, err)
+}
+
+func TestRower_HeartRate(t *testing.T) {
+	r, stm, w := newTestRower()
+	stm.Update(config.CSAFE_GOIDLE_CMD)
+	stm.Update(config.CSAFE_GOINUSE_CMD)
+
+	// the steady athlete rows at the top of its power range, the heart rate
+	// rises from rest to its maximum
+	max := MaxHeartRate(user.DEFAULT_AGE)
+	r.Step(TICK)
+	assert.InDelta(t, 60, w.Metrics().HeartRate, 1)
+	for i := 0; i < 300; i++ {
+		r.Step(TICK)
+	}
+	assert.InDelta(t, 60+(1-math.Exp(-1))*float64(max-60), w.Metrics().HeartRate, 1)
+	r.Step(10 * time.Minute)
+	assert.Equal(t, max, w.Metrics().HeartRate)
+
+	// no heart rate without a belt
+	p := r.Persona()
+	p.RestHeartRate = 0
+	r.SetPersona(p)
+	r.Step(TICK)
+	assert.Equal(t, 0, w.Metrics().HeartRate)
+}
+
+func TestPersona_Fatigue(t *testing.T) {
+	p, _ := LookupPersona("novice")
+	p.Variability = 0
+	norm := func() float64 { return 0 }
+
+	fresh := p.stroke(0, 0, 0, norm)
+	tired := p.stroke(0, 0, time.Hour, norm)
+	assert.Equal(t, fresh.rate, tired.rate)
+	assert.InDelta(t, float64(fresh.power)*(1-p.Fatigue), tired.power, 1)
+
+	// a power target sets the stroke rate on the power curve
+	assert.Equal(t, p.MinStrokeRate, p.stroke(0, p.MinPower, 0, norm).rate)
+	assert.Equal(t, p.MaxStrokeRate, p.stroke(0, 2*p.MaxPower, 0, norm).rate)
+}
+
+func TestPowerForPace(t *testing.T) {
+	assert.Equal(t, 203, PowerForPace(2*time.Minute))
+	assert.Equal(t, 0, PowerForPace(0))
+}
+
+func TestRower_PauseResume(t *testing.T) {
+	r, stm, w := newTestRower()
+	assert.Error(t, r.Pause())
+
+	stm.Update(config.CSAFE_GOIDLE_CMD)
+	stm.Update(config.CSAFE_GOINUSE_CMD)
+	r.SetTarget(30, 300)
+	r.Step(10 * time.Second)
+	m := w.Metrics()
+	assert.Equal(t, 30, m.StrokeRate)
+	assert.Equal(t, 300, m.Power)
+
+	assert.NoError(t, r.Pause())
+	assert.Equal(t, config.PM5_STATE_PAUSED, stm.GetStateName())
+	r.Step(10 * time.Second)
+	assert.Equal(t, m, w.Metrics())
+
+	assert.NoError(t, r.Resume())
+	assert.Error(t, r.Resume())
+	r.Step(10 * time.Second)
+	assert.Equal(t, 20*time.Second, w.Metrics().ElapsedTime)
+}
+
+func TestForceCurve(t *testing.T) {
+	curve := ForceCurve(24, 200, 32)
+	assert.Len(t, curve, 32)
+	// the work along the drive is the work of the stroke
+	work := 0.0
+	for _, f := range curve {
+		work += f * NEWTONS_PER_LB * DRIVE_LENGTH / 32
+	}
+	assert.InDelta(t, 200*60/24, work, 1)
+	// peaking mid drive
+	assert.InDelta(t, curve[15], curve[16], 1e-9)
+	assert.True(t, curve[0] < curve[8] && curve[8] < curve[15])
+
+	assert.Nil(t, ForceCurve(0, 200, 32))
+	assert.Nil(t, ForceCurve(24, 0, 32))
+}
+
+func TestStrokeForceCurve(t *testing.T) {
+	// a point every 20ms of the drive
+	assert.Len(t, StrokeForceCurve(24, 200, 800*time.Millisecond), 40)
+	// a third of the stroke by default
+	assert.Len(t, StrokeForceCurve(20, 200, 0), 50)
+	assert.Len(t, StrokeForceCurve(24, 200, time.Millisecond), 1)
+	assert.Nil(t, StrokeForceCurve(0, 200, 0))
+}
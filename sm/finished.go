@@ -15,7 +15,8 @@ func (r finishedState) getStateName() string {
 
 func (r finishedState) update(command byte) error {
 	if command == config.CSAFE_GOIDLE_CMD {
-		r.statemachine.SetState(config.PM5_STATE_IDLE)
+		r.statemachine.setState(config.PM5_STATE_IDLE)
+		return nil
 	}
 	//todo: handle timeout case
 	return fmt.Errorf("undefined command type %v", command)
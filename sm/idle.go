@@ -15,10 +15,10 @@ func (r idleState) getStateName() string {
 
 func (r idleState) update(command byte) error {
 	if command == config.CSAFE_GOINUSE_CMD {
-		r.statemachine.SetState(config.PM5_STATE_INUSE)
+		r.statemachine.setState(config.PM5_STATE_INUSE)
 		return nil
 	} else if command == config.CSAFE_GOHAVEID_CMD {
-		r.statemachine.SetState(config.PM5_STATE_HAVEID)
+		r.statemachine.setState(config.PM5_STATE_HAVEID)
 		return nil
 	}
 	//todo: goto paused state due to timeout
@@ -15,9 +15,9 @@ func (r inUseState) getStateName() string {
 
 func (r inUseState) update(command byte) error {
 	if command == config.CSAFE_GOFINISHED_CMD {
-		r.statemachine.SetState(config.PM5_STATE_FINISHED)
+		r.statemachine.setState(config.PM5_STATE_FINISHED)
 		return nil
 	}
-	//todo: handle workout cancel and timeout issues
+	//todo: handle workout cancel
 	return fmt.Errorf("undefined command type %v", command)
 }
@@ -15,7 +15,7 @@ func (m manualState) getStateName() string {
 
 func (m manualState) update(command byte) error {
 	if command == config.CSAFE_GOIDLE_CMD {
-		m.statemachine.SetState(config.PM5_STATE_IDLE)
+		m.statemachine.setState(config.PM5_STATE_IDLE)
 		return nil
 	}
 	//todo: handle timeout case
@@ -15,9 +15,8 @@ func (r pausedState) getStateName() string {
 
 func (r pausedState) update(command byte) error {
 	if command == config.CSAFE_GOFINISHED_CMD {
-		r.statemachine.SetState(config.PM5_STATE_FINISHED)
+		r.statemachine.setState(config.PM5_STATE_FINISHED)
 		return nil
 	}
-	//TODO: timeout also causes transition from Paused to Finished state
 	return fmt.Errorf("undefined command type %v", command)
 }
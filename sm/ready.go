@@ -15,10 +15,10 @@ func (r readyState) getStateName() string {
 
 func (r readyState) update(command byte) error {
 	if command == config.CSAFE_GOIDLE_CMD {
-		r.statemachine.SetState(config.PM5_STATE_IDLE)
+		r.statemachine.setState(config.PM5_STATE_IDLE)
 		return nil
 	} else if command == config.CSAFE_GOINUSE_CMD {
-		r.statemachine.SetState(config.PM5_STATE_INUSE)
+		r.statemachine.setState(config.PM5_STATE_INUSE)
 		return nil
 	}
 	return fmt.Errorf("undefined command")
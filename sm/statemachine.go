@@ -1,6 +1,26 @@
 package sm
 
-import "pm5-emulator/config"
+import (
+	"pm5-emulator/clock"
+	"pm5-emulator/config"
+	"pm5-emulator/protocol/csafe"
+	"sync"
+	"time"
+)
+
+//timeout is the state a state is left for when nothing happens for a while
+type timeout struct {
+	after time.Duration
+	to    string
+}
+
+//timeouts are the states the PM leaves on its own, a workout in use pauses
+//when the athlete stops rowing
+var timeouts = map[string]timeout{
+	config.PM5_STATE_HAVEID: {csafe.DEFAULT_SLAVESTATE_TIMEOUT * time.Second, config.PM5_STATE_IDLE},
+	config.PM5_STATE_INUSE:  {csafe.INUSE_SLAVESTATE_TIMEOUT * time.Second, config.PM5_STATE_PAUSED},
+	config.PM5_STATE_PAUSED: {csafe.PAUSED_SLAVESTATE_TIMEOUT * time.Second, config.PM5_STATE_FINISHED},
+}
 
 //state
 type state interface {
@@ -19,12 +39,17 @@ type StateMachine struct {
 	HAVEID   state
 	PAUSED   state
 
+	mu           sync.Mutex
 	currentState state
+	listeners    []func(from, to string)
+	clock        clock.Clock
+	timer        clock.Timer // timeout of the current state
+	entered      int         // number of state changes, telling stale timeouts apart
 }
 
 //NewStateMachine returns statemachine instance
 func NewStateMachine() *StateMachine {
-	pm := &StateMachine{}
+	pm := &StateMachine{clock: clock.Real{}}
 
 	pm.READY = &readyState{statemachine: pm}
 	pm.IDLE = &idleState{statemachine: pm}
@@ -39,16 +64,99 @@ func NewStateMachine() *StateMachine {
 
 //GetStateName returns current state name
 func (sm *StateMachine) GetStateName() string {
-	return sm.currentState.getStateName()
+	return sm.GetState().getStateName()
 }
 
 //GetState returns state interface
 func (sm *StateMachine) GetState() state {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
 	return sm.currentState
 }
 
+//OnTransition registers a function called with the names of the previous and
+//the new state whenever the state changes
+func (sm *StateMachine) OnTransition(f func(from, to string)) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.listeners = append(sm.listeners, f)
+}
+
+//SetClock sets the clock the state timeouts run on
+func (sm *StateMachine) SetClock(c clock.Clock) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.clock = c
+}
+
+//Active tells the state machine the athlete is rowing, restarting the
+//timeout of the current state
+func (sm *StateMachine) Active() {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if sm.currentState != nil {
+		sm.armTimeout(sm.currentState.getStateName())
+	}
+}
+
 //SetState sets state of StateMachine
 func (sm *StateMachine) SetState(s string) {
+	sm.transition(func() error {
+		sm.setState(s)
+		return nil
+	})
+}
+
+//transition runs f while holding the lock and notifies the listeners if f changed the state
+func (sm *StateMachine) transition(f func() error) error {
+	sm.mu.Lock()
+	from := sm.currentState
+	err := f()
+	to := sm.currentState
+	listeners := sm.listeners
+	if from != to {
+		sm.armTimeout(to.getStateName())
+	}
+	sm.mu.Unlock()
+
+	if from != to {
+		fromName := ""
+		if from != nil {
+			fromName = from.getStateName()
+		}
+		for _, l := range listeners {
+			l(fromName, to.getStateName())
+		}
+	}
+	return err
+}
+
+//armTimeout schedules the timeout of the state just entered, the lock must be held
+func (sm *StateMachine) armTimeout(name string) {
+	if sm.timer != nil {
+		sm.timer.Stop()
+		sm.timer = nil
+	}
+	sm.entered++
+
+	t, ok := timeouts[name]
+	if !ok {
+		return
+	}
+	entered := sm.entered
+	sm.timer = sm.clock.AfterFunc(t.after, func() {
+		sm.transition(func() error {
+			// the state may have changed while the timeout was firing
+			if sm.entered == entered {
+				sm.setState(t.to)
+			}
+			return nil
+		})
+	})
+}
+
+//setState sets state of StateMachine, the lock must be held
+func (sm *StateMachine) setState(s string) {
 	switch s {
 	case config.PM5_STATE_IDLE:
 		sm.currentState = sm.IDLE
@@ -74,11 +182,13 @@ func (sm *StateMachine) Reset() {
 
 //Update changes the state of machine based on command
 func (sm *StateMachine) Update(command byte) error {
-	if command == config.CSAFE_RESET_CMD {
-		sm.Reset()
-		return nil
-	}
-	return sm.currentState.update(command)
+	return sm.transition(func() error {
+		if command == config.CSAFE_RESET_CMD {
+			sm.setState(config.PM5_STATE_READY)
+			return nil
+		}
+		return sm.currentState.update(command)
+	})
 }
 
 //IsIdle returns true if statemachine is in IDLE state otherwise false
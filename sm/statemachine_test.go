@@ -2,8 +2,10 @@ package sm
 
 import (
 	"fmt"
+	"pm5-emulator/clock"
 	"pm5-emulator/config"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -52,6 +54,8 @@ func TestUpdate(t *testing.T) {
 	}{
 		{"ready2idle", config.CSAFE_GOIDLE_CMD},
 		{"idle2haveID", config.CSAFE_GOHAVEID_CMD},
+		{"haveID2Idle", config.CSAFE_BADID_CMD},
+		{"idle2haveIDAgain", config.CSAFE_GOHAVEID_CMD},
 		{"haveID2InUse", config.CSAFE_GOINUSE_CMD},
 		{"inUse2Finished", config.CSAFE_GOFINISHED_CMD},
 	}
@@ -99,3 +103,70 @@ func TestIsReady(t *testing.T) {
 		t.Errorf("statemachine ready state, got %v, want %s", sm.IsReady(), "true")
 	}
 }
+
+func TestOnTransition(t *testing.T) {
+	sm := NewStateMachine()
+	sm.Reset()
+
+	var transitions []string
+	sm.OnTransition(func(from, to string) {
+		transitions = append(transitions, from+">"+to)
+	})
+
+	sm.Update(config.CSAFE_GOIDLE_CMD)
+	sm.Update(config.CSAFE_GOFINISHED_CMD) //not accepted in IDLE
+	sm.Update(config.CSAFE_GOINUSE_CMD)
+	sm.SetState(config.PM5_STATE_INUSE) //same state
+	sm.Update(config.CSAFE_RESET_CMD)
+
+	assert.Equal(t, []string{
+		config.PM5_STATE_READY + ">" + config.PM5_STATE_IDLE,
+		config.PM5_STATE_IDLE + ">" + config.PM5_STATE_INUSE,
+		config.PM5_STATE_INUSE + ">" + config.PM5_STATE_READY,
+	}, transitions)
+}
+
+func TestTimeouts(t *testing.T) {
+	tests := []struct {
+		name  string
+		state string
+		after time.Duration
+		want  string
+	}{
+		{"haveID2Idle", config.PM5_STATE_HAVEID, 20 * time.Second, config.PM5_STATE_IDLE},
+		{"paused2Finished", config.PM5_STATE_PAUSED, 220 * time.Second, config.PM5_STATE_FINISHED},
+		{"inUse2Paused", config.PM5_STATE_INUSE, 6 * time.Second, config.PM5_STATE_PAUSED},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := clock.NewManual(time.Time{})
+			sm := NewStateMachine()
+			sm.SetClock(c)
+			sm.SetState(test.state)
+
+			c.Advance(test.after - time.Second)
+			assert.Equal(t, test.state, sm.GetStateName())
+			c.Advance(time.Second)
+			assert.Equal(t, test.want, sm.GetStateName())
+		})
+	}
+}
+
+func TestTimeoutCancelled(t *testing.T) {
+	c := clock.NewManual(time.Time{})
+	sm := NewStateMachine()
+	sm.SetClock(c)
+	sm.SetState(config.PM5_STATE_HAVEID)
+
+	c.Advance(10 * time.Second)
+	assert.NoError(t, sm.Update(config.CSAFE_GOINUSE_CMD))
+	// the workout stays in use while the athlete rows
+	for i := 0; i < 12; i++ {
+		c.Advance(5 * time.Second)
+		sm.Active()
+	}
+	assert.Equal(t, config.PM5_STATE_INUSE, sm.GetStateName())
+	c.Advance(6 * time.Second)
+	assert.Equal(t, config.PM5_STATE_PAUSED, sm.GetStateName())
+}
@@ -0,0 +1,227 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/bettercap/gatt"
+)
+
+//DEFAULT_MTU is the MTU of the centrals connected without one
+const DEFAULT_MTU = 23
+
+//errClosed is returned by the operations of a central once disconnected
+var errClosed = errors.New("transport: central disconnected")
+
+//Loopback connects centrals to GATT services in process, without a Bluetooth
+//device. Unlike gatt, it serves the subscriptions in the goroutine of the
+//central, so that services notifying on a manual clock behave the same on
+//every run.
+type Loopback struct {
+	chars map[string]*gatt.Characteristic // by UUID
+}
+
+//NewLoopback returns a transport to the characteristics of services
+func NewLoopback(services ...*gatt.Service) *Loopback {
+	l := &Loopback{chars: make(map[string]*gatt.Characteristic)}
+	for _, s := range services {
+		for _, c := range s.Characteristics() {
+			l.chars[c.UUID().String()] = c
+		}
+	}
+	return l
+}
+
+//Connect connects a central identified by id with the given MTU,
+//DEFAULT_MTU when 0
+func (l *Loopback) Connect(id string, mtu int) *Central {
+	if mtu == 0 {
+		mtu = DEFAULT_MTU
+	}
+	return &Central{l: l, id: id, mtu: mtu, notifiers: make(map[string]*notifier)}
+}
+
+//characteristic returns the characteristic identified by u
+func (l *Loopback) characteristic(u gatt.UUID) (*gatt.Characteristic, error) {
+	c, ok := l.chars[u.String()]
+	if !ok {
+		return nil, fmt.Errorf("transport: unknown characteristic %s", u)
+	}
+	return c, nil
+}
+
+//Central is a central connected through a loopback transport
+type Central struct {
+	l         *Loopback
+	id        string
+	mtu       int
+	mu        sync.Mutex
+	closed    bool
+	notifiers map[string]*notifier // subscriptions by characteristic UUID
+}
+
+//ID returns the identifier of the central
+func (c *Central) ID() string {
+	return c.id
+}
+
+//MTU returns the MTU of the connection
+func (c *Central) MTU() int {
+	return c.mtu
+}
+
+//Close disconnects the central, ending its subscriptions
+func (c *Central) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return errClosed
+	}
+	c.closed = true
+	for u, n := range c.notifiers {
+		n.stop()
+		delete(c.notifiers, u)
+	}
+	return nil
+}
+
+//Read reads the value of a characteristic
+func (c *Central) Read(u gatt.UUID) ([]byte, error) {
+	ch, err := c.open(u)
+	if err != nil {
+		return nil, err
+	}
+	h := ch.GetReadHandler()
+	if h == nil {
+		return nil, fmt.Errorf("transport: characteristic %s is not readable", u)
+	}
+	rsp := &response{status: gatt.StatusSuccess}
+	h.ServeRead(rsp, &gatt.ReadRequest{Request: gatt.Request{Central: c}, Cap: c.mtu - 1})
+	if rsp.status != gatt.StatusSuccess {
+		return nil, fmt.Errorf("transport: read of %s failed with status 0x%x", u, rsp.status)
+	}
+	return rsp.data, nil
+}
+
+//Write writes data to a characteristic
+func (c *Central) Write(u gatt.UUID, data []byte) error {
+	ch, err := c.open(u)
+	if err != nil {
+		return err
+	}
+	h := ch.GetWriteHandler()
+	if h == nil {
+		return fmt.Errorf("transport: characteristic %s is not writable", u)
+	}
+	if status := h.ServeWrite(gatt.Request{Central: c}, data); status != gatt.StatusSuccess {
+		return fmt.Errorf("transport: write to %s failed with status 0x%x", u, status)
+	}
+	return nil
+}
+
+//Subscribe subscribes to the notifications of a characteristic, passed to f
+func (c *Central) Subscribe(u gatt.UUID, f func(data []byte)) error {
+	ch, err := c.open(u)
+	if err != nil {
+		return err
+	}
+	h := ch.GetNotifyHandler()
+	if h == nil {
+		return fmt.Errorf("transport: characteristic %s does not notify", u)
+	}
+
+	c.mu.Lock()
+	if _, ok := c.notifiers[u.String()]; ok {
+		c.mu.Unlock()
+		return fmt.Errorf("transport: already subscribed to %s", u)
+	}
+	n := &notifier{cap: c.mtu - 3, f: f}
+	c.notifiers[u.String()] = n
+	c.mu.Unlock()
+
+	h.ServeNotify(gatt.Request{Central: c}, n)
+	return nil
+}
+
+//Unsubscribe ends the subscription to a characteristic
+func (c *Central) Unsubscribe(u gatt.UUID) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n, ok := c.notifiers[u.String()]
+	if !ok {
+		return fmt.Errorf("transport: not subscribed to %s", u)
+	}
+	n.stop()
+	delete(c.notifiers, u.String())
+	return nil
+}
+
+//open returns the characteristic identified by u while connected
+func (c *Central) open(u gatt.UUID) (*gatt.Characteristic, error) {
+	c.mu.Lock()
+	closed := c.closed
+	c.mu.Unlock()
+	if closed {
+		return nil, errClosed
+	}
+	return c.l.characteristic(u)
+}
+
+//response collects the data written in response to a read
+type response struct {
+	data   []byte
+	status byte
+}
+
+//Write appends to the response data
+func (r *response) Write(b []byte) (int, error) {
+	r.data = append(r.data, b...)
+	return len(b), nil
+}
+
+//SetStatus sets the status of the read
+func (r *response) SetStatus(status byte) {
+	r.status = status
+}
+
+//notifier passes the notifications of a subscription to a function
+type notifier struct {
+	mu   sync.Mutex
+	cap  int
+	f    func(data []byte)
+	done bool
+}
+
+//Write passes data to the function of the subscription
+func (n *notifier) Write(data []byte) (int, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.done {
+		return 0, errors.New("transport: central stopped notifications")
+	}
+	if len(data) > n.cap {
+		return 0, fmt.Errorf("transport: notification of %d bytes exceeds %d", len(data), n.cap)
+	}
+	n.f(data)
+	return len(data), nil
+}
+
+//Done reports whether the subscription ended
+func (n *notifier) Done() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.done
+}
+
+//Cap returns the maximum size of a notification
+func (n *notifier) Cap() int {
+	return n.cap
+}
+
+//stop ends the subscription
+func (n *notifier) stop() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.done = true
+}
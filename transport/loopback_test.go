@@ -0,0 +1,56 @@
+package transport
+
+import (
+	"testing"
+
+	"github.com/bettercap/gatt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoopback(t *testing.T) {
+	s := gatt.NewService(gatt.MustParseUUID("CE060030-43E5-11E4-916C-0800200C9A66"))
+	rw := s.AddCharacteristic(gatt.MustParseUUID("CE060034-43E5-11E4-916C-0800200C9A66"))
+	value := []byte{0x01}
+	rw.HandleReadFunc(func(rsp gatt.ResponseWriter, req *gatt.ReadRequest) {
+		rsp.Write(value)
+	})
+	rw.HandleWriteFunc(func(r gatt.Request, data []byte) byte {
+		value = data
+		return gatt.StatusSuccess
+	})
+	var notifier gatt.Notifier
+	n := s.AddCharacteristic(gatt.MustParseUUID("CE060031-43E5-11E4-916C-0800200C9A66"))
+	n.HandleNotifyFunc(func(r gatt.Request, nt gatt.Notifier) {
+		assert.Equal(t, "central", r.Central.ID())
+		notifier = nt
+		nt.Write([]byte{0x02})
+	})
+
+	c := NewLoopback(s).Connect("central", 0)
+	assert.Equal(t, DEFAULT_MTU, c.MTU())
+
+	data, err := c.Read(rw.UUID())
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x01}, data)
+	assert.NoError(t, c.Write(rw.UUID(), []byte{0x03}))
+	data, _ = c.Read(rw.UUID())
+	assert.Equal(t, []byte{0x03}, data)
+	_, err = c.Read(n.UUID())
+	assert.Error(t, err)
+
+	var notified [][]byte
+	assert.NoError(t, c.Subscribe(n.UUID(), func(data []byte) { notified = append(notified, data) }))
+	assert.Error(t, c.Subscribe(n.UUID(), func([]byte) {}))
+	_, err = notifier.Write(make([]byte, notifier.Cap()+1))
+	assert.Error(t, err)
+	assert.NoError(t, c.Unsubscribe(n.UUID()))
+	assert.True(t, notifier.Done())
+	_, err = notifier.Write([]byte{0x04})
+	assert.Error(t, err)
+	assert.Equal(t, [][]byte{{0x02}}, notified)
+
+	assert.NoError(t, c.Subscribe(n.UUID(), func([]byte) {}))
+	assert.NoError(t, c.Close())
+	assert.True(t, notifier.Done())
+	assert.Error(t, c.Write(rw.UUID(), []byte{0x05}))
+}
@@ -0,0 +1,203 @@
+package user
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"pm5-emulator/protocol/csafe"
+)
+
+//MAX_USERS is the number of users the PM keeps an ID for, user number 0
+//being the current user
+const MAX_USERS = 5
+
+//Genders of a user profile
+const (
+	GENDER_NONE   = 0
+	GENDER_MALE   = 1
+	GENDER_FEMALE = 2
+)
+
+//Defaults of a profile, the Concept2 calorie formula is based on a 175 lb user
+const (
+	DEFAULT_WEIGHT = 175 * csafe.LBS_TO_KG // kilograms
+	DEFAULT_AGE    = 30
+)
+
+//ErrUserNumber is returned for a user number the PM does not keep
+var ErrUserNumber = errors.New("user number out of range")
+
+//Profile holds the physical data of a user
+type Profile struct {
+	Weight float64 // kilograms
+	Age    int
+	Gender byte
+	Units  byte // weight units specifier the user enters the weight with
+}
+
+//DefaultProfile returns the profile of a user that never set one
+func DefaultProfile() Profile {
+	return Profile{
+		Weight: DEFAULT_WEIGHT,
+		Age:    DEFAULT_AGE,
+		Gender: GENDER_NONE,
+		Units:  csafe.WEIGHT_LBS_0_0,
+	}
+}
+
+//profileFile is the JSON a profile is read from, the fields left out keep
+//their default
+type profileFile struct {
+	Weight float64 `json:"weight"`
+	Unit   string  `json:"unit"` // of the weight, kg or lb
+	Age    int     `json:"age"`
+	Gender string  `json:"gender"` // male, female or none
+}
+
+//genders are the genders of a profile file
+var genders = map[string]byte{
+	"":       GENDER_NONE,
+	"none":   GENDER_NONE,
+	"male":   GENDER_MALE,
+	"female": GENDER_FEMALE,
+}
+
+//ParseProfile parses a JSON profile such as {"weight": 80, "unit": "kg", "age": 35, "gender": "female"}
+func ParseProfile(b []byte) (Profile, error) {
+	var f profileFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return Profile{}, err
+	}
+	p := DefaultProfile()
+	switch f.Unit {
+	case "kg":
+		p.Units = csafe.WEIGHT_KG_0_0
+		if f.Weight != 0 {
+			p.Weight = f.Weight
+		}
+	case "lb", "":
+		if f.Weight != 0 {
+			p.Weight = f.Weight * csafe.LBS_TO_KG
+		}
+	default:
+		return Profile{}, fmt.Errorf("unknown weight unit %q", f.Unit)
+	}
+	if f.Weight < 0 || f.Age < 0 {
+		return Profile{}, errors.New("negative weight or age")
+	}
+	if f.Age != 0 {
+		p.Age = f.Age
+	}
+	gender, ok := genders[f.Gender]
+	if !ok {
+		return Profile{}, fmt.Errorf("unknown gender %q", f.Gender)
+	}
+	p.Gender = gender
+	return p, nil
+}
+
+//LoadProfile reads the JSON profile file at path
+func LoadProfile(path string) (Profile, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Profile{}, err
+	}
+	p, err := ParseProfile(b)
+	if err != nil {
+		return Profile{}, fmt.Errorf("%s: %v", path, err)
+	}
+	return p, nil
+}
+
+//SetDefaultProfile sets the profile of the users that never set one
+func (u *User) SetDefaultProfile(p Profile) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.fallback = p
+}
+
+//Profile returns the profile of the current user
+func (u *User) Profile() Profile {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.profile(u.id)
+}
+
+//SetProfile sets the profile of the current user
+func (u *User) SetProfile(p Profile) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.profiles[u.id] = p
+}
+
+//UserID returns the ID of a user number
+func (u *User) UserID(number int) (int, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	id, err := u.userID(number)
+	if err != nil {
+		return 0, err
+	}
+	return *id, nil
+}
+
+//SetUserID sets the ID of a user number, setting user number 0 logs the
+//user in without entering the ID on the keypad
+func (u *User) SetUserID(number, id int) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	p, err := u.userID(number)
+	if err != nil {
+		return err
+	}
+	*p = id
+	return nil
+}
+
+//UserProfile returns the profile of a user number
+func (u *User) UserProfile(number int) (Profile, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	id, err := u.userID(number)
+	if err != nil {
+		return Profile{}, err
+	}
+	return u.profile(*id), nil
+}
+
+//SetUserProfile sets the profile of a user number
+func (u *User) SetUserProfile(number int, p Profile) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	id, err := u.userID(number)
+	if err != nil {
+		return err
+	}
+	u.profiles[*id] = p
+	return nil
+}
+
+//userID returns the ID of a user number, the lock must be held
+func (u *User) userID(number int) (*int, error) {
+	switch {
+	case number == 0:
+		return &u.id, nil
+	case number > 0 && number < MAX_USERS:
+		return &u.numbers[number-1], nil
+	default:
+		return nil, ErrUserNumber
+	}
+}
+
+//profile returns the profile of a user ID, the lock must be held
+func (u *User) profile(id int) Profile {
+	if p, ok := u.profiles[id]; ok {
+		return p
+	}
+	return u.fallback
+}
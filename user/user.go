@@ -0,0 +1,120 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"pm5-emulator/config"
+	"pm5-emulator/protocol/csafe"
+	"pm5-emulator/sm"
+	"strconv"
+	"sync"
+)
+
+//Errors returned when entering a user ID
+var (
+	ErrIDDigits = fmt.Errorf("id digits must be between %d and %d", csafe.IDDIGITS_MIN, csafe.IDDIGITS_MAX)
+	ErrIDFormat = errors.New("user id must only contain digits")
+	ErrIDLength = errors.New("user id does not have the configured number of digits")
+	ErrNotIdle  = errors.New("user id can only be entered in IDLE state")
+)
+
+//User holds the identity of the user of the emulated PM and the profiles of
+//the users it knows. The ID is entered on the PM, standing in for its
+//keypad, and validated by the host which either starts the workout or
+//answers BADID.
+type User struct {
+	mu       sync.Mutex
+	stm      *sm.StateMachine
+	digits   int
+	id       int
+	numbers  [MAX_USERS - 1]int // IDs of the other user numbers known to the PM
+	profiles map[int]Profile    // profiles by user ID
+	fallback Profile            // profile of the users that never set one
+}
+
+//New returns a user without an ID, driven by stm
+func New(stm *sm.StateMachine) *User {
+	u := &User{
+		stm:      stm,
+		digits:   csafe.DEFAULT_IDDIGITS,
+		id:       csafe.DEFAULT_ID,
+		profiles: make(map[int]Profile),
+		fallback: DefaultProfile(),
+	}
+	stm.OnTransition(u.onTransition)
+	return u
+}
+
+//onTransition clears the ID when it is rejected or the PM is reset
+func (u *User) onTransition(from, to string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	switch {
+	case to == config.PM5_STATE_MANUAL:
+		u.id = csafe.MANUAL_ID
+	case to == config.PM5_STATE_READY,
+		from == config.PM5_STATE_HAVEID && to == config.PM5_STATE_IDLE:
+		u.id = csafe.DEFAULT_ID
+	}
+}
+
+//Digits returns the number of digits of a user ID
+func (u *User) Digits() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.digits
+}
+
+//SetDigits sets the number of digits of a user ID and clears the entered ID
+func (u *User) SetDigits(n int) error {
+	if n < csafe.IDDIGITS_MIN || n > csafe.IDDIGITS_MAX {
+		return ErrIDDigits
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.digits = n
+	u.id = csafe.DEFAULT_ID
+	return nil
+}
+
+//ID returns the user ID, DEFAULT_ID when none was entered
+func (u *User) ID() int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.id
+}
+
+//IDString returns the user ID formatted with the configured number of digits
+func (u *User) IDString() string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	s := fmt.Sprintf("%0*d", u.digits, u.id)
+	// MANUAL_ID is wider than any ID and keeps its last digits
+	return s[len(s)-u.digits:]
+}
+
+//EnterID enters the user ID as typed on the keypad, then the PM waits in
+//HAVEID state for the host to validate it
+func (u *User) EnterID(id string) error {
+	if !u.stm.IsIdle() {
+		return ErrNotIdle
+	}
+	for _, c := range id {
+		if c < '0' || c > '9' {
+			return ErrIDFormat
+		}
+	}
+
+	u.mu.Lock()
+	if len(id) != u.digits {
+		u.mu.Unlock()
+		return ErrIDLength
+	}
+	u.id, _ = strconv.Atoi(id)
+	u.mu.Unlock()
+
+	return u.stm.Update(config.CSAFE_GOHAVEID_CMD)
+}
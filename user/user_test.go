@@ -0,0 +1,159 @@
+package user
+
+import (
+	"pm5-emulator/config"
+	"pm5-emulator/protocol/csafe"
+	"pm5-emulator/sm"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newIdleUser() (*User, *sm.StateMachine) {
+	stm := sm.NewStateMachine()
+	stm.SetState(config.PM5_STATE_IDLE)
+	return New(stm), stm
+}
+
+func TestUser_EnterID(t *testing.T) {
+	tests := []struct {
+		name    string
+		digits  int
+		id      string
+		wantErr error
+		want    string
+	}{
+		{"Default Digits", csafe.DEFAULT_IDDIGITS, "01234", nil, "01234"},
+		{"Min Digits", csafe.IDDIGITS_MIN, "42", nil, "42"},
+		{"Too Short", csafe.DEFAULT_IDDIGITS, "1234", ErrIDLength, "00000"},
+		{"Too Long", csafe.IDDIGITS_MIN, "123", ErrIDLength, "00"},
+		{"Not Digits", csafe.IDDIGITS_MIN, "1a", ErrIDFormat, "00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, stm := newIdleUser()
+			assert.NoError(t, u.SetDigits(tt.digits))
+
+			err := u.EnterID(tt.id)
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.want, u.IDString())
+			if tt.wantErr == nil {
+				assert.Equal(t, config.PM5_STATE_HAVEID, stm.GetStateName())
+			} else {
+				assert.Equal(t, config.PM5_STATE_IDLE, stm.GetStateName())
+			}
+		})
+	}
+}
+
+func TestUser_EnterIDNotIdle(t *testing.T) {
+	stm := sm.NewStateMachine()
+	stm.Reset()
+	u := New(stm)
+
+	assert.Equal(t, ErrNotIdle, u.EnterID("12345"))
+	assert.Equal(t, csafe.DEFAULT_ID, u.ID())
+}
+
+func TestUser_SetDigits(t *testing.T) {
+	u, _ := newIdleUser()
+
+	assert.Equal(t, ErrIDDigits, u.SetDigits(csafe.IDDIGITS_MIN-1))
+	assert.Equal(t, ErrIDDigits, u.SetDigits(csafe.IDDIGITS_MAX+1))
+	assert.Equal(t, csafe.DEFAULT_IDDIGITS, u.Digits())
+
+	assert.NoError(t, u.EnterID("12345"))
+	assert.NoError(t, u.SetDigits(3))
+	assert.Equal(t, csafe.DEFAULT_ID, u.ID())
+}
+
+func TestUser_Transitions(t *testing.T) {
+	u, stm := newIdleUser()
+
+	// rejected by the host
+	assert.NoError(t, u.EnterID("12345"))
+	assert.NoError(t, stm.Update(config.CSAFE_BADID_CMD))
+	assert.Equal(t, config.PM5_STATE_IDLE, stm.GetStateName())
+	assert.Equal(t, csafe.DEFAULT_ID, u.ID())
+
+	// accepted by the host, kept for the workout
+	assert.NoError(t, u.EnterID("54321"))
+	assert.NoError(t, stm.Update(config.CSAFE_GOINUSE_CMD))
+	assert.NoError(t, stm.Update(config.CSAFE_GOFINISHED_CMD))
+	assert.Equal(t, 54321, u.ID())
+
+	// cleared by a reset
+	assert.NoError(t, stm.Update(config.CSAFE_RESET_CMD))
+	assert.Equal(t, csafe.DEFAULT_ID, u.ID())
+
+	// rowing without an ID
+	stm.SetState(config.PM5_STATE_MANUAL)
+	assert.Equal(t, csafe.MANUAL_ID, u.ID())
+	assert.Equal(t, "99999", u.IDString())
+}
+
+func TestUser_Profiles(t *testing.T) {
+	u, stm := newIdleUser()
+
+	assert.Equal(t, DefaultProfile(), u.Profile())
+
+	// the profile follows the user ID
+	p := Profile{Weight: 90, Age: 52, Gender: GENDER_MALE, Units: csafe.WEIGHT_KG_0_0}
+	assert.NoError(t, u.EnterID("00042"))
+	u.SetProfile(p)
+	assert.Equal(t, p, u.Profile())
+
+	assert.NoError(t, stm.Update(config.CSAFE_BADID_CMD))
+	assert.Equal(t, DefaultProfile(), u.Profile())
+
+	assert.NoError(t, u.SetUserID(3, 42))
+	got, err := u.UserProfile(3)
+	assert.NoError(t, err)
+	assert.Equal(t, p, got)
+
+	_, err = u.UserID(MAX_USERS)
+	assert.Equal(t, ErrUserNumber, err)
+	assert.Equal(t, ErrUserNumber, u.SetUserProfile(-1, p))
+}
+
+func TestParseProfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		want    Profile
+		wantErr bool
+	}{
+		{"Empty", `{}`, DefaultProfile(), false},
+		{"Kilograms", `{"weight": 80, "unit": "kg", "age": 35, "gender": "female"}`,
+			Profile{Weight: 80, Age: 35, Gender: GENDER_FEMALE, Units: csafe.WEIGHT_KG_0_0}, false},
+		{"Pounds", `{"weight": 175, "gender": "male"}`,
+			Profile{Weight: DEFAULT_WEIGHT, Age: DEFAULT_AGE, Gender: GENDER_MALE, Units: csafe.WEIGHT_LBS_0_0}, false},
+		{"Unknown Unit", `{"weight": 12, "unit": "stone"}`, Profile{}, true},
+		{"Unknown Gender", `{"gender": "x"}`, Profile{}, true},
+		{"Negative Age", `{"age": -1}`, Profile{}, true},
+		{"Not JSON", `weight: 80`, Profile{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseProfile([]byte(tt.json))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.InDelta(t, tt.want.Weight, got.Weight, 1e-9)
+			got.Weight = tt.want.Weight
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestUser_SetDefaultProfile(t *testing.T) {
+	u, _ := newIdleUser()
+	p := Profile{Weight: 60, Age: 25, Gender: GENDER_FEMALE, Units: csafe.WEIGHT_KG_0_0}
+	u.SetDefaultProfile(p)
+	assert.Equal(t, p, u.Profile())
+	// users entering an ID without a profile row with it too
+	assert.NoError(t, u.EnterID("00042"))
+	assert.Equal(t, p, u.Profile())
+}
@@ -0,0 +1,19 @@
+package workout
+
+//ForceCurve returns the force on the handle during the drive of the last
+//stroke, in pounds-force, and the number of that stroke, nil before the
+//first stroke is finished
+func (w *Workout) ForceCurve() ([]float64, int) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return append([]float64(nil), w.force...), w.forceStroke
+}
+
+//SetForceCurve sets the force curve of the stroke just finished, whose number
+//is the stroke count of the live workout values
+func (w *Workout) SetForceCurve(curve []float64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.force = append([]float64(nil), curve...)
+	w.forceStroke = w.metrics.StrokeCount
+}
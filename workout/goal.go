@@ -0,0 +1,58 @@
+package workout
+
+import (
+	"pm5-emulator/config"
+	"time"
+)
+
+//Goal is the target of a workout set by the host before the workout starts.
+//At most one of Time, Distance and Calories is set, a workout without any of
+//them is a just row workout.
+type Goal struct {
+	Time     time.Duration
+	Distance float64 // meters
+	Calories int
+	Power    int // target watts, 0 when not set
+}
+
+//WorkoutType returns the WORKOUTTYPE_* matching the goal
+func (g Goal) WorkoutType() byte {
+	switch {
+	case g.Time > 0:
+		return config.WORKOUTTYPE_FIXEDTIME_NOSPLITS
+	case g.Distance > 0:
+		return config.WORKOUTTYPE_FIXEDDIST_NOSPLITS
+	case g.Calories > 0:
+		return config.WORKOUTTYPE_FIXED_CALORIE
+	default:
+		return config.WORKOUTTYPE_JUSTROW_NOSPLITS
+	}
+}
+
+//Reached returns true when the metrics meet the goal, a just row workout is never reached
+func (g Goal) Reached(m Metrics) bool {
+	switch {
+	case g.Time > 0:
+		return m.ElapsedTime >= g.Time
+	case g.Distance > 0:
+		return m.Distance >= g.Distance
+	case g.Calories > 0:
+		return m.Calories >= g.Calories
+	default:
+		return false
+	}
+}
+
+//Goal returns the goal of the workout
+func (w *Workout) Goal() Goal {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.goal
+}
+
+//SetGoal changes the goal of the workout with f
+func (w *Workout) SetGoal(f func(g *Goal)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	f(&w.goal)
+}
@@ -0,0 +1,111 @@
+package workout
+
+import (
+	"math"
+	"time"
+)
+
+//DEFAULT_SPLIT_DISTANCE is the split length of a just row workout in meters
+const DEFAULT_SPLIT_DISTANCE = 500
+
+//SPLITS is the number of splits a workout with a goal is divided in
+const SPLITS = 5
+
+//Split holds the values rowed during one split of a workout
+type Split struct {
+	Time        time.Duration
+	Distance    float64 // meters
+	Calories    int
+	StrokeCount int
+}
+
+//AvgPace returns the average time to row 500m during the split
+func (s Split) AvgPace() time.Duration {
+	return avgPace(s.Time, s.Distance)
+}
+
+//AvgPower returns the average power of the split in watts, derived from its
+//average pace
+func (s Split) AvgPower() int {
+	return AvgPower(s.Time, s.Distance)
+}
+
+//AvgStrokeRate returns the average stroke rate of the split
+func (s Split) AvgStrokeRate() int {
+	if s.Time <= 0 {
+		return 0
+	}
+	return int(math.Round(float64(s.StrokeCount) / s.Time.Minutes()))
+}
+
+//progress returns how far the metrics are towards the goal and the length
+//of a split, in the units of the goal
+func (g Goal) progress(m Metrics) (float64, float64) {
+	switch {
+	case g.Time > 0:
+		return m.ElapsedTime.Seconds(), g.Time.Seconds() / SPLITS
+	case g.Distance > 0:
+		return m.Distance, g.Distance / SPLITS
+	case g.Calories > 0:
+		return float64(m.Calories), float64(g.Calories) / SPLITS
+	default:
+		return m.Distance, DEFAULT_SPLIT_DISTANCE
+	}
+}
+
+//Splits returns the splits completed so far
+func (w *Workout) Splits() []Split {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return append([]Split(nil), w.splits...)
+}
+
+//CurrentSplit returns the values rowed since the last split ended
+func (w *Workout) CurrentSplit() Split {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.current()
+}
+
+//SetManualSplits stops splitting the workout by its goal when on, leaving
+//the splits to EndSplit, until the workout is reset
+func (w *Workout) SetManualSplits(on bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.manualSplits = on
+}
+
+//EndSplit ends the current split at the live workout values
+func (w *Workout) EndSplit() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.endSplit()
+}
+
+//recordSplits appends the splits completed by the metrics, the lock must be held
+func (w *Workout) recordSplits() {
+	if w.manualSplits {
+		return
+	}
+	done, length := w.goal.progress(w.metrics)
+	// tolerate the rounding of a split length that does not divide the goal
+	for done >= float64(len(w.splits)+1)*length-1e-9 {
+		w.endSplit()
+	}
+}
+
+//endSplit appends the split ending at the metrics, the lock must be held
+func (w *Workout) endSplit() {
+	w.splits = append(w.splits, w.current())
+	w.lastSplit = w.metrics
+}
+
+//current returns the split rowed since the last one ended, the lock must be held
+func (w *Workout) current() Split {
+	return Split{
+		Time:        w.metrics.ElapsedTime - w.lastSplit.ElapsedTime,
+		Distance:    w.metrics.Distance - w.lastSplit.Distance,
+		Calories:    w.metrics.Calories - w.lastSplit.Calories,
+		StrokeCount: w.metrics.StrokeCount - w.lastSplit.StrokeCount,
+	}
+}
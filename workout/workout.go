@@ -0,0 +1,120 @@
+package workout
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+//Metrics holds the live values of a workout
+type Metrics struct {
+	ElapsedTime time.Duration
+	Distance    float64 // meters
+	Speed       float64 // meters per second
+	StrokeRate  int     // strokes per minute
+	StrokeCount int
+	Power       int // watts
+	Calories    int // kcal
+	HeartRate   int // beats per minute, 0 when no heart rate belt is connected
+
+	DriveTime    time.Duration // of the last stroke
+	RecoveryTime time.Duration // of the last stroke
+}
+
+//Pace returns the time needed to row 500m at the current speed, zero when not rowing
+func (m Metrics) Pace() time.Duration {
+	if m.Speed <= 0 {
+		return 0
+	}
+	return time.Duration(500 / m.Speed * float64(time.Second))
+}
+
+//AvgPace returns the average time to row 500m over the workout, zero before
+//any distance is rowed
+func (m Metrics) AvgPace() time.Duration {
+	return avgPace(m.ElapsedTime, m.Distance)
+}
+
+//AvgPower returns the average power of the workout in watts, derived from
+//the average pace as the PM does
+func (m Metrics) AvgPower() int {
+	return AvgPower(m.ElapsedTime, m.Distance)
+}
+
+//avgPace returns the time to row 500m at the average pace of distance meters
+//rowed in t, zero when no distance is rowed
+func avgPace(t time.Duration, distance float64) time.Duration {
+	if distance <= 0 {
+		return 0
+	}
+	return time.Duration(float64(t) * 500 / distance)
+}
+
+//AvgPower returns the power in watts of rowing distance meters in t at an
+//even pace, with the Concept2 relation watts = 2.80 / pace^3
+func AvgPower(t time.Duration, distance float64) int {
+	if t <= 0 || distance <= 0 {
+		return 0
+	}
+	pace := t.Seconds() / distance // seconds per meter
+	return int(math.Round(2.80 / (pace * pace * pace)))
+}
+
+//Workout holds the state of the workout rowed on the emulated PM
+type Workout struct {
+	mu        sync.RWMutex
+	metrics   Metrics
+	goal      Goal
+	splits    []Split
+	lastSplit Metrics // metrics at the end of the last split
+
+	force       []float64 // force curve of the last stroke
+	forceStroke int       // number of the stroke of the force curve
+
+	manualSplits bool // splits are ended with EndSplit rather than by the goal
+}
+
+//New returns an empty workout
+func New() *Workout {
+	return &Workout{}
+}
+
+//Metrics returns a snapshot of the live workout values
+func (w *Workout) Metrics() Metrics {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.metrics
+}
+
+//Update changes the live workout values with f and records the splits they complete
+func (w *Workout) Update(f func(m *Metrics)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	f(&w.metrics)
+	w.recordSplits()
+}
+
+//Start clears the live workout values to row a new workout towards the same goal
+func (w *Workout) Start() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.clear()
+}
+
+//Reset clears the live workout values and the goal
+func (w *Workout) Reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.clear()
+	w.goal = Goal{}
+	w.manualSplits = false
+}
+
+//clear clears the live workout values and splits, the lock must be held
+func (w *Workout) clear() {
+	w.metrics = Metrics{}
+	w.splits = nil
+	w.lastSplit = Metrics{}
+	w.force = nil
+	w.forceStroke = 0
+}
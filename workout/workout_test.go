@@ -0,0 +1,187 @@
+package workout
+
+import (
+	"pm5-emulator/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics_Pace(t *testing.T) {
+	tests := []struct {
+		name  string
+		speed float64
+		want  time.Duration
+	}{
+		{"Not Rowing", 0, 0},
+		{"2:00 Pace", 500.0 / 120, 2 * time.Minute},
+		{"1:40 Pace", 5, 100 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Metrics{Speed: tt.speed}.Pace()
+			assert.InDelta(t, float64(tt.want), float64(got), float64(time.Millisecond))
+		})
+	}
+}
+
+func TestMetrics_Averages(t *testing.T) {
+	// 2:00/500m on average is rowed at about 203W
+	m := Metrics{ElapsedTime: 4 * time.Minute, Distance: 1000, Speed: 5, Power: 350}
+	assert.Equal(t, 2*time.Minute, m.AvgPace())
+	assert.Equal(t, 203, m.AvgPower())
+
+	assert.Equal(t, time.Duration(0), Metrics{}.AvgPace())
+	assert.Equal(t, 0, Metrics{}.AvgPower())
+
+	s := Split{Time: 2 * time.Minute, Distance: 500, StrokeCount: 48}
+	assert.Equal(t, 2*time.Minute, s.AvgPace())
+	assert.Equal(t, 203, s.AvgPower())
+	assert.Equal(t, 24, s.AvgStrokeRate())
+	assert.Equal(t, 0, Split{}.AvgStrokeRate())
+}
+
+func TestWorkout_Update(t *testing.T) {
+	w := New()
+	w.Update(func(m *Metrics) {
+		m.Distance = 100
+		m.StrokeCount = 10
+	})
+	assert.Equal(t, Metrics{Distance: 100, StrokeCount: 10}, w.Metrics())
+
+	w.Reset()
+	assert.Equal(t, Metrics{}, w.Metrics())
+}
+
+func TestWorkout_ForceCurve(t *testing.T) {
+	w := New()
+	curve, stroke := w.ForceCurve()
+	assert.Nil(t, curve)
+	assert.Equal(t, 0, stroke)
+
+	w.Update(func(m *Metrics) { m.StrokeCount = 3 })
+	w.SetForceCurve([]float64{10, 20, 10})
+	curve, stroke = w.ForceCurve()
+	assert.Equal(t, []float64{10, 20, 10}, curve)
+	assert.Equal(t, 3, stroke)
+
+	w.Reset()
+	curve, _ = w.ForceCurve()
+	assert.Nil(t, curve)
+}
+
+func TestGoal_Reached(t *testing.T) {
+	m := Metrics{ElapsedTime: 5 * time.Minute, Distance: 1500, Calories: 80}
+
+	tests := []struct {
+		name        string
+		goal        Goal
+		workoutType byte
+		want        bool
+	}{
+		{"Just Row", Goal{}, config.WORKOUTTYPE_JUSTROW_NOSPLITS, false},
+		{"Time Reached", Goal{Time: 5 * time.Minute}, config.WORKOUTTYPE_FIXEDTIME_NOSPLITS, true},
+		{"Time Not Reached", Goal{Time: 10 * time.Minute}, config.WORKOUTTYPE_FIXEDTIME_NOSPLITS, false},
+		{"Distance Reached", Goal{Distance: 1000}, config.WORKOUTTYPE_FIXEDDIST_NOSPLITS, true},
+		{"Distance Not Reached", Goal{Distance: 2000}, config.WORKOUTTYPE_FIXEDDIST_NOSPLITS, false},
+		{"Calories Reached", Goal{Calories: 80}, config.WORKOUTTYPE_FIXED_CALORIE, true},
+		{"Calories Not Reached", Goal{Calories: 100}, config.WORKOUTTYPE_FIXED_CALORIE, false},
+		{"Power Only", Goal{Power: 200}, config.WORKOUTTYPE_JUSTROW_NOSPLITS, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.goal.Reached(m))
+			assert.Equal(t, tt.workoutType, tt.goal.WorkoutType())
+		})
+	}
+}
+
+func TestWorkout_Start(t *testing.T) {
+	w := New()
+	w.SetGoal(func(g *Goal) { g.Distance = 2000 })
+	w.Update(func(m *Metrics) { m.Distance = 100 })
+
+	w.Start()
+	assert.Equal(t, Metrics{}, w.Metrics())
+	assert.Equal(t, Goal{Distance: 2000}, w.Goal())
+
+	w.Reset()
+	assert.Equal(t, Goal{}, w.Goal())
+}
+
+func TestWorkout_Splits(t *testing.T) {
+	tests := []struct {
+		name string
+		goal Goal
+		want []Split
+	}{
+		{"Just Row", Goal{}, []Split{
+			{Time: 2 * time.Minute, Distance: 500, Calories: 20, StrokeCount: 50},
+		}},
+		{"Distance", Goal{Distance: 1000}, []Split{
+			{Time: 48 * time.Second, Distance: 200, Calories: 8, StrokeCount: 20},
+			{Time: 48 * time.Second, Distance: 200, Calories: 8, StrokeCount: 20},
+			{Time: 48 * time.Second, Distance: 200, Calories: 8, StrokeCount: 20},
+		}},
+		{"Time", Goal{Time: 10 * time.Minute}, []Split{
+			{Time: 2 * time.Minute, Distance: 500, Calories: 20, StrokeCount: 50},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := New()
+			w.SetGoal(func(g *Goal) { *g = tt.goal })
+			w.Start()
+
+			// 4.8s per 20m steps, up to 720m
+			for i := 1; i <= 36; i++ {
+				w.Update(func(m *Metrics) {
+					m.ElapsedTime = time.Duration(i) * 4800 * time.Millisecond
+					m.Distance = float64(i) * 20
+					m.Calories = int(m.Distance / 25)
+					m.StrokeCount = int(m.Distance / 10)
+				})
+			}
+			assert.Equal(t, tt.want, w.Splits())
+			current := w.CurrentSplit()
+			for _, s := range tt.want {
+				current.Distance += s.Distance
+				current.Time += s.Time
+			}
+			assert.Equal(t, 720.0, current.Distance)
+			assert.Equal(t, 36*4800*time.Millisecond, current.Time)
+
+			w.Start()
+			assert.Empty(t, w.Splits())
+		})
+	}
+}
+
+func TestWorkout_ManualSplits(t *testing.T) {
+	w := New()
+	w.SetManualSplits(true)
+	w.Start()
+
+	w.Update(func(m *Metrics) {
+		m.ElapsedTime = 3 * time.Minute
+		m.Distance = 700
+	})
+	assert.Empty(t, w.Splits())
+
+	w.EndSplit()
+	w.Update(func(m *Metrics) {
+		m.ElapsedTime = 4 * time.Minute
+		m.Distance = 950
+	})
+	w.EndSplit()
+	assert.Equal(t, []Split{
+		{Time: 3 * time.Minute, Distance: 700},
+		{Time: time.Minute, Distance: 250},
+	}, w.Splits())
+
+	// splits follow the goal again once reset
+	w.Reset()
+	w.Update(func(m *Metrics) { m.Distance = 500 })
+	assert.Len(t, w.Splits(), 1)
+}